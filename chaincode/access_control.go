@@ -0,0 +1,158 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// RoleAttribute is the name of the certificate attribute holding the roles of an identity.
+// Like the Fabric CA hf.Registrar.Roles attribute, it is a comma separated list of roles.
+const RoleAttribute = "substra.role"
+
+// AccessControlSetting is the global setting enabling the role checks. Networks whose identities
+// don't have the role attribute keep working until it is enabled through a governance proposal.
+const AccessControlSetting = "access_control.enabled"
+
+// List of the roles an identity can have
+const (
+	RoleAdmin  = "admin"
+	RoleWorker = "worker"
+	RoleUser   = "user"
+)
+
+// contractPolicies lists the roles allowed to call each contract.
+// The read-only contracts are not listed, they can be called by any identity.
+// The other contracts which are not listed are denied.
+var contractPolicies = map[string][]string{
+	"registerNode":           {RoleAdmin},
+	"createProposal":         {RoleAdmin},
+	"approveProposal":        {RoleAdmin},
+	"executeProposal":        {RoleAdmin},
+	"migrateSchedulingIndex": {RoleAdmin},
+
+	"logFailTest":              {RoleWorker},
+	"logFailTrain":             {RoleWorker},
+	"logFailCompositeTrain":    {RoleWorker},
+	"logFailAggregate":         {RoleWorker},
+	"logStartTest":             {RoleWorker},
+	"logStartTrain":            {RoleWorker},
+	"logStartCompositeTrain":   {RoleWorker},
+	"logStartAggregate":        {RoleWorker},
+	"logSuccessTest":           {RoleWorker},
+	"logSuccessTrain":          {RoleWorker},
+	"logSuccessCompositeTrain": {RoleWorker},
+	"logSuccessAggregate":      {RoleWorker},
+
+	"compactComputePlan": {RoleWorker, RoleUser},
+
+	"cancelComputePlan":           {RoleUser},
+	"createAggregatetuple":        {RoleUser},
+	"createCompositeTraintuple":   {RoleUser},
+	"createComputePlan":           {RoleUser},
+	"createTesttuple":             {RoleUser},
+	"createTraintuple":            {RoleUser},
+	"deprecateAlgo":               {RoleUser},
+	"instantiateComputePlan":      {RoleUser},
+	"registerAggregateAlgo":       {RoleUser},
+	"registerAlgo":                {RoleUser},
+	"registerCompositeAlgo":       {RoleUser},
+	"registerComputePlanTemplate": {RoleUser},
+	"registerDataManager":         {RoleUser},
+	"registerDataSample":          {RoleUser},
+	"registerObjective":           {RoleUser},
+	"revokeDataSamples":           {RoleUser},
+	"updateComputePlan":           {RoleUser},
+	"updateComputePlanPriority":   {RoleUser},
+	"updateDataManager":           {RoleUser},
+	"updateDataSample":            {RoleUser},
+	"updateObjectiveTestDataset":  {RoleUser},
+}
+
+// GetTxCreatorRoles returns the roles of the transaction creator read from its certificate attributes
+func GetTxCreatorRoles(stub shim.ChaincodeStubInterface) ([]string, error) {
+	value, found, err := cid.GetAttributeValue(stub, RoleAttribute)
+	if err != nil {
+		return nil, errors.Forbidden(err, "cannot read the %s attribute of the transaction creator", RoleAttribute)
+	}
+	if !found {
+		return nil, errors.Forbidden("the transaction creator has no %s attribute", RoleAttribute)
+	}
+	roles := []string{}
+	for _, role := range strings.Split(value, ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			roles = append(roles, role)
+		}
+	}
+	if len(roles) == 0 {
+		return nil, errors.Forbidden("the transaction creator has an empty %s attribute", RoleAttribute)
+	}
+	return roles, nil
+}
+
+// validateAccessControlSetting checks that the value of the access control setting is a boolean
+func validateAccessControlSetting(name string, value string) error {
+	if name != AccessControlSetting {
+		return nil
+	}
+	if _, err := strconv.ParseBool(value); err != nil {
+		return errors.BadRequest("setting %s must be a boolean, received: %s", name, value)
+	}
+	return nil
+}
+
+// isAccessControlEnabled returns whether the roles of the transaction creators are checked
+func isAccessControlEnabled(db *LedgerDB) (bool, error) {
+	value, ok, err := getSetting(db, AccessControlSetting)
+	if err != nil || !ok {
+		return false, err
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.Internal("invalid value %s for setting %s", value, AccessControlSetting)
+	}
+	return enabled, nil
+}
+
+// checkContractPolicy checks that the transaction creator has one of the roles allowed to call the contract.
+// The read-only contracts are not checked, so that they don't read the governance settings.
+func checkContractPolicy(db *LedgerDB, c contract) error {
+	if c.ReadOnly {
+		return nil
+	}
+	enabled, err := isAccessControlEnabled(db)
+	if err != nil || !enabled {
+		return err
+	}
+	allowedRoles, ok := contractPolicies[c.Name]
+	if !ok {
+		return errors.Forbidden("%s has no access policy", c.Name)
+	}
+	roles, err := GetTxCreatorRoles(db.cc)
+	if err != nil {
+		return err
+	}
+	for _, role := range roles {
+		if stringInSlice(role, allowedRoles) {
+			return nil
+		}
+	}
+	return errors.Forbidden("%s requires one of the roles %v, the transaction creator has %v", c.Name, allowedRoles, roles)
+}
@@ -0,0 +1,90 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContractPolicy(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStub("substra", scc)
+
+	// Roles are not checked until access control is enabled
+	mockStub.CreatorRole = ""
+	resp := mockStub.MockInvoke([][]byte{[]byte("registerNode")})
+	assert.EqualValuesf(t, 200, resp.Status, "when registering a node without role, status %d and message %s", resp.Status, resp.Message)
+	mockStub.CreatorRole = strings.Join([]string{RoleAdmin, RoleWorker, RoleUser}, ",")
+	initGovernanceWithSettings(t, mockStub, map[string]string{AccessControlSetting: "true"})
+
+	// Only admin identities can register a node
+	mockStub.CreatorRole = RoleUser
+	resp = mockStub.MockInvoke([][]byte{[]byte("registerNode")})
+	assert.EqualValuesf(t, 403, resp.Status, "when registering a node as user, status %d and message %s", resp.Status, resp.Message)
+
+	mockStub.CreatorRole = RoleAdmin
+	resp = mockStub.MockInvoke([][]byte{[]byte("registerNode")})
+	assert.EqualValuesf(t, 200, resp.Status, "when registering a node as admin, status %d and message %s", resp.Status, resp.Message)
+
+	// Only worker identities can log the progress of a tuple
+	for _, role := range []string{RoleAdmin, RoleUser, "admin, user"} {
+		mockStub.CreatorRole = role
+		resp = mockStub.MockInvoke([][]byte{[]byte("logStartTrain"), keyToJSON(traintupleKey)})
+		assert.EqualValuesf(t, 403, resp.Status, "when starting a traintuple as %s, status %d and message %s", role, resp.Status, resp.Message)
+	}
+	mockStub.CreatorRole = "user, worker"
+	resp = mockStub.MockInvoke([][]byte{[]byte("logStartTrain"), keyToJSON(traintupleKey)})
+	assert.EqualValuesf(t, 404, resp.Status, "when starting an unknown traintuple as worker, status %d and message %s", resp.Status, resp.Message)
+
+	// Queries are allowed for any role
+	mockStub.CreatorRole = RoleWorker
+	resp = mockStub.MockInvoke([][]byte{[]byte("queryNodes")})
+	assert.EqualValuesf(t, 200, resp.Status, "when querying nodes as worker, status %d and message %s", resp.Status, resp.Message)
+
+	// Identities without role can only call queries
+	mockStub.CreatorRole = ""
+	resp = mockStub.MockInvoke([][]byte{[]byte("queryNodes")})
+	assert.EqualValuesf(t, 200, resp.Status, "when querying nodes without role, status %d and message %s", resp.Status, resp.Message)
+	resp = mockStub.MockInvoke([][]byte{[]byte("registerNode")})
+	assert.EqualValuesf(t, 403, resp.Status, "when registering a node without role, status %d and message %s", resp.Status, resp.Message)
+
+	// Write contracts without policy are denied
+	mockStub.CreatorRole = strings.Join([]string{RoleAdmin, RoleWorker, RoleUser}, ",")
+	mockStub.MockTransactionStart("42")
+	err := checkContractPolicy(NewLedgerDB(mockStub), contract{Name: "unlisted"})
+	assert.Error(t, err)
+	assert.Equal(t, 403, errors.Wrap(err).HTTPStatusCode())
+	mockStub.MockTransactionEnd("42")
+}
+
+func TestAccessControlSetting(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStub("substra", scc)
+	inpGovernance := inputGovernance{Admins: []string{workerA}, Threshold: 1, Settings: map[string]string{AccessControlSetting: "maybe"}}
+	resp := mockStub.MockInit("42", [][]byte{[]byte("init"), assetToJSON(inpGovernance)})
+	assert.EqualValues(t, 400, resp.Status, resp.Message)
+
+	// Access control can be enabled from the instantiation
+	inpGovernance.Settings[AccessControlSetting] = "true"
+	resp = mockStub.MockInit("42", [][]byte{[]byte("init"), assetToJSON(inpGovernance)})
+	assert.EqualValues(t, 200, resp.Status, resp.Message)
+	mockStub.CreatorRole = RoleUser
+	resp = mockStub.MockInvoke([][]byte{[]byte("registerNode")})
+	assert.EqualValues(t, 403, resp.Status, resp.Message)
+}
@@ -44,9 +44,50 @@ func (algo *Algo) Set(db *LedgerDB, inp inputAlgo) (err error) {
 	algo.Owner = owner
 	algo.Permissions = permissions
 	algo.Metadata = inp.Metadata
+	algo.Resources = NewResources(inp.Resources)
+	err = algo.setVersion(db, inp.ParentKey, inp.Version)
 	return
 }
 
+// setVersion checks and sets the parent algo and the version of a new algo.
+// A new version must have the same type and owner as its parent, and a greater
+// version than its parent when both are set.
+func (algo *Algo) setVersion(db *LedgerDB, parentKey string, version string) error {
+	algo.Version = version
+	if parentKey == "" {
+		return nil
+	}
+	parent, err := db.GetGenericAlgo(parentKey)
+	if err != nil {
+		return errors.BadRequest(err, "could not retrieve parent algo with key %s", parentKey)
+	}
+	if parent.AssetType != algo.AssetType {
+		return errors.BadRequest("parent algo %s is a %s, expecting a %s", parentKey, parent.AssetType, algo.AssetType)
+	}
+	if parent.Owner != algo.Owner {
+		return errors.Forbidden("only the owner of algo %s can register a new version of it", parentKey)
+	}
+	if parent.Version != "" && version != "" {
+		comparison, err := compareSemver(version, parent.Version)
+		if err != nil {
+			return err
+		}
+		if comparison <= 0 {
+			return errors.BadRequest("version %s should be greater than the version %s of parent algo %s", version, parent.Version, parentKey)
+		}
+	}
+	algo.ParentKey = parentKey
+	return nil
+}
+
+// createAlgoParentIndex creates the composite key used to find the new versions of an algo
+func createAlgoParentIndex(db *LedgerDB, algoKey string, parentKey string) error {
+	if parentKey == "" {
+		return nil
+	}
+	return db.CreateIndex("algo~parent~key", []string{"algo", parentKey, algoKey})
+}
+
 // -------------------------------------------------------------------------------------------
 // Smart contracts related to an algo
 // -------------------------------------------------------------------------------------------
@@ -74,6 +115,10 @@ func registerAlgo(db *LedgerDB, args []string) (resp outputKey, err error) {
 	if err != nil {
 		return
 	}
+	err = createAlgoParentIndex(db, algo.Key, algo.ParentKey)
+	if err != nil {
+		return
+	}
 	return outputKey{Key: algo.Key}, nil
 }
 
@@ -126,3 +171,77 @@ func queryAlgos(db *LedgerDB, args []string) (outAlgos []outputAlgo, bookmark st
 	}
 	return
 }
+
+// deprecateAlgo marks an algo, of any type, as deprecated.
+// No new tuple can be created with a deprecated algo but the existing ones can still be processed.
+func deprecateAlgo(db *LedgerDB, args []string) (resp outputKey, err error) {
+	inp := inputKey{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	algo, err := db.GetGenericAlgo(inp.Key)
+	if err != nil {
+		return
+	}
+	txCreator, err := GetTxCreator(db.cc)
+	if err != nil {
+		return
+	}
+	if algo.Owner != txCreator {
+		err = errors.Forbidden("%s is not the owner of algo %s", txCreator, inp.Key)
+		return
+	}
+	if algo.Deprecated {
+		err = errors.BadRequest("algo %s is already deprecated", inp.Key)
+		return
+	}
+	algo.Deprecated = true
+	err = db.Put(inp.Key, algo)
+	if err != nil {
+		return
+	}
+	return outputKey{Key: inp.Key}, nil
+}
+
+// queryAlgoVersions returns all the versions of an algo, of any type, starting from
+// the first version and followed by its descendants, level by level
+func queryAlgoVersions(db *LedgerDB, args []string) (outAlgos []outputAlgo, err error) {
+	inp := inputKey{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	algo, err := db.GetGenericAlgo(inp.Key)
+	if err != nil {
+		return
+	}
+	// go back to the first version
+	for algo.ParentKey != "" {
+		algo, err = db.GetGenericAlgo(algo.ParentKey)
+		if err != nil {
+			return
+		}
+	}
+	outAlgos = []outputAlgo{}
+	versions := []Algo{algo}
+	for len(versions) > 0 {
+		algo, versions = versions[0], versions[1:]
+		var out outputAlgo
+		out.Fill(algo)
+		outAlgos = append(outAlgos, out)
+
+		childKeys, err := db.GetIndexKeys("algo~parent~key", []string{"algo", algo.Key})
+		if err != nil {
+			return outAlgos, err
+		}
+		for _, key := range childKeys {
+			child, err := db.GetGenericAlgo(key)
+			if err != nil {
+				return outAlgos, err
+			}
+			versions = append(versions, child)
+		}
+	}
+	return
+}
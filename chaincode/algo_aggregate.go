@@ -44,6 +44,8 @@ func (algo *AggregateAlgo) Set(db *LedgerDB, inp inputAggregateAlgo) (err error)
 	algo.Owner = owner
 	algo.Permissions = permissions
 	algo.Metadata = inp.Metadata
+	algo.Resources = NewResources(inp.Resources)
+	err = algo.setVersion(db, inp.ParentKey, inp.Version)
 	return
 }
 
@@ -74,6 +76,10 @@ func registerAggregateAlgo(db *LedgerDB, args []string) (resp outputKey, err err
 	if err != nil {
 		return
 	}
+	err = createAlgoParentIndex(db, inp.Key, algo.ParentKey)
+	if err != nil {
+		return
+	}
 	return outputKey{Key: inp.Key}, nil
 }
 
@@ -19,6 +19,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type AlgoResponse struct {
@@ -82,3 +83,119 @@ func TestAlgo(t *testing.T) {
 	assert.Len(t, algos.Results, 1)
 	assert.Exactly(t, expectedAlgo, algos.Results[0], "return algo different from registered one")
 }
+
+func TestCompareSemver(t *testing.T) {
+	for _, tc := range []struct {
+		a, b     string
+		expected int
+	}{
+		{a: "1.0.0", b: "1.0.0", expected: 0},
+		{a: "1.0.0", b: "1.0.1", expected: -1},
+		{a: "1.10.0", b: "1.9.0", expected: 1},
+		{a: "1.0.0+build.2", b: "1.0.0+build.1", expected: 0},
+		{a: "1.0.0-rc.1", b: "1.0.0", expected: -1},
+		{a: "1.0.0-rc.9", b: "1.0.0-rc.10", expected: -1},
+		{a: "1.0.0-rc.10", b: "1.0.0-rc.9", expected: 1},
+		{a: "1.0.0-1", b: "1.0.0-alpha", expected: -1},
+		{a: "1.0.0-alpha", b: "1.0.0-1", expected: 1},
+		{a: "1.0.0-alpha", b: "1.0.0-alpha.1", expected: -1},
+		{a: "1.0.0-alpha.beta", b: "1.0.0-alpha.1", expected: 1},
+		{a: "1.0.0-beta", b: "1.0.0-alpha", expected: 1},
+		{a: "1.0.0-rc.99999999999999999999", b: "1.0.0-rc.2", expected: 1},
+	} {
+		t.Run(tc.a+"_"+tc.b, func(t *testing.T) {
+			comparison, err := compareSemver(tc.a, tc.b)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, comparison)
+		})
+	}
+	_, err := compareSemver("99999999999999999999.0.0", "1.0.0")
+	assert.Error(t, err)
+	_, err = compareSemver("v1", "1.0.0")
+	assert.Error(t, err)
+}
+
+func TestAlgoVersions(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	algoKeyV2 := "a2000000-1f62-244c-0f3a-761cc1688042"
+	algoKeyV3 := "a3000000-1f62-244c-0f3a-761cc1688042"
+
+	inpAlgo := inputAlgo{Version: "1.0.0"}
+	resp := mockStub.MockInvoke(inpAlgo.createDefault())
+	assert.EqualValuesf(t, 200, resp.Status, "when adding algo v1, status %d and message %s", resp.Status, resp.Message)
+
+	inpAlgo = inputAlgo{Key: algoKeyV2, ParentKey: algoKey, Version: "v2"}
+	resp = mockStub.MockInvoke(inpAlgo.createDefault())
+	assert.EqualValuesf(t, 400, resp.Status, "when adding algo with an invalid version, status %d and message %s", resp.Status, resp.Message)
+
+	inpAlgo = inputAlgo{Key: algoKeyV2, ParentKey: algoKey, Version: "99999999999999999999.0.0"}
+	resp = mockStub.MockInvoke(inpAlgo.createDefault())
+	assert.EqualValuesf(t, 400, resp.Status, "when adding algo with a version too large to compare, status %d and message %s", resp.Status, resp.Message)
+
+	inpAlgo = inputAlgo{Key: algoKeyV2, ParentKey: algoKey, Version: "1.0.0-rc1"}
+	resp = mockStub.MockInvoke(inpAlgo.createDefault())
+	assert.EqualValuesf(t, 400, resp.Status, "when adding algo with a lower version than its parent, status %d and message %s", resp.Status, resp.Message)
+
+	inpAlgo = inputAlgo{Key: algoKeyV2, ParentKey: algoKey, Version: "1.1.0"}
+	resp = mockStub.MockInvoke(inpAlgo.createDefault())
+	assert.EqualValuesf(t, 200, resp.Status, "when adding algo v2, status %d and message %s", resp.Status, resp.Message)
+
+	mockStub.Creator = workerB
+	inpAlgo = inputAlgo{Key: algoKeyV3, ParentKey: algoKeyV2, Version: "2.0.0"}
+	resp = mockStub.MockInvoke(inpAlgo.createDefault())
+	assert.EqualValuesf(t, 403, resp.Status, "when adding a new version of an algo owned by another node, status %d and message %s", resp.Status, resp.Message)
+	mockStub.Creator = workerA
+
+	inpCompositeAlgo := inputCompositeAlgo{inputAlgo{Key: algoKeyV3, ParentKey: algoKeyV2}}
+	resp = mockStub.MockInvoke(inpCompositeAlgo.createDefault())
+	assert.EqualValuesf(t, 400, resp.Status, "when adding a composite algo with an algo as parent, status %d and message %s", resp.Status, resp.Message)
+
+	inpAlgo = inputAlgo{Key: algoKeyV3, ParentKey: algoKeyV2, Version: "2.0.0"}
+	resp = mockStub.MockInvoke(inpAlgo.createDefault())
+	assert.EqualValuesf(t, 200, resp.Status, "when adding algo v3, status %d and message %s", resp.Status, resp.Message)
+
+	for _, key := range []string{algoKey, algoKeyV2, algoKeyV3} {
+		resp = mockStub.MockInvoke([][]byte{[]byte("queryAlgoVersions"), keyToJSON(key)})
+		assert.EqualValuesf(t, 200, resp.Status, "when querying algo versions, status %d and message %s", resp.Status, resp.Message)
+		var versions []outputAlgo
+		err := json.Unmarshal(resp.Payload, &versions)
+		assert.NoError(t, err, "while unmarshalling algo versions")
+		require.Len(t, versions, 3)
+		assert.Equal(t, algoKey, versions[0].Key)
+		assert.Equal(t, "", versions[0].ParentKey)
+		assert.Equal(t, algoKeyV2, versions[1].Key)
+		assert.Equal(t, algoKey, versions[1].ParentKey)
+		assert.Equal(t, "1.1.0", versions[1].Version)
+		assert.Equal(t, algoKeyV3, versions[2].Key)
+		assert.Equal(t, algoKeyV2, versions[2].ParentKey)
+	}
+}
+
+func TestDeprecateAlgo(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "traintuple")
+
+	mockStub.Creator = workerB
+	resp := mockStub.MockInvoke([][]byte{[]byte("deprecateAlgo"), keyToJSON(algoKey)})
+	assert.EqualValuesf(t, 403, resp.Status, "when deprecating an algo owned by another node, status %d and message %s", resp.Status, resp.Message)
+	mockStub.Creator = workerA
+
+	resp = mockStub.MockInvoke([][]byte{[]byte("deprecateAlgo"), keyToJSON(algoKey)})
+	assert.EqualValuesf(t, 200, resp.Status, "when deprecating an algo, status %d and message %s", resp.Status, resp.Message)
+
+	resp = mockStub.MockInvoke([][]byte{[]byte("queryAlgo"), keyToJSON(algoKey)})
+	algo := outputAlgo{}
+	err := json.Unmarshal(resp.Payload, &algo)
+	assert.NoError(t, err)
+	assert.True(t, algo.Deprecated)
+
+	inpTraintuple := inputTraintuple{Key: traintupleKey2}
+	resp = mockStub.MockInvoke(inpTraintuple.createDefault())
+	assert.EqualValuesf(t, 400, resp.Status, "when adding a traintuple with a deprecated algo, status %d and message %s", resp.Status, resp.Message)
+
+	// Existing tuples can still be processed
+	resp = mockStub.MockInvoke([][]byte{[]byte("logStartTrain"), keyToJSON(traintupleKey)})
+	assert.EqualValuesf(t, 200, resp.Status, "when starting a traintuple with a deprecated algo, status %d and message %s", resp.Status, resp.Message)
+}
@@ -16,6 +16,7 @@ package main
 
 import (
 	"chaincode/errors"
+	"fmt"
 	"strconv"
 )
 
@@ -26,6 +27,20 @@ func (inpTraintuple *inputTraintuple) Fill(inpCP inputComputePlanTraintuple, IDT
 	inpTraintuple.AlgoKey = inpCP.AlgoKey
 	inpTraintuple.Tag = inpCP.Tag
 	inpTraintuple.Metadata = inpCP.Metadata
+	inpTraintuple.Epsilon = inpCP.Epsilon
+	inpTraintuple.Delta = inpCP.Delta
+	inpTraintuple.Resources = inpCP.Resources
+	if inpCP.Condition != nil {
+		task, ok := IDToTrainTask[inpCP.Condition.TesttupleID]
+		if !ok {
+			return errors.BadRequest("condition testtuple ID %s not found", inpCP.Condition.TesttupleID)
+		}
+		inpTraintuple.Condition = &inputCondition{
+			TesttupleKey: task.Key,
+			Operator:     inpCP.Condition.Operator,
+			Value:        inpCP.Condition.Value,
+		}
+	}
 
 	// Set the inModels by matching the id to tuples key previously
 	// encontered in this compute plan
@@ -46,6 +61,7 @@ func (inpAggregatetuple *inputAggregatetuple) Fill(inpCP inputComputePlanAggrega
 	inpAggregatetuple.Tag = inpCP.Tag
 	inpAggregatetuple.Metadata = inpCP.Metadata
 	inpAggregatetuple.Worker = inpCP.Worker
+	inpAggregatetuple.Resources = inpCP.Resources
 
 	// Set the inModels by matching the id to tuples key previously
 	// encontered in this compute plan
@@ -68,6 +84,9 @@ func (inpCompositeTraintuple *inputCompositeTraintuple) Fill(inpCP inputComputeP
 	inpCompositeTraintuple.Tag = inpCP.Tag
 	inpCompositeTraintuple.Metadata = inpCP.Metadata
 	inpCompositeTraintuple.OutTrunkModelPermissions = inpCP.OutTrunkModelPermissions
+	inpCompositeTraintuple.Epsilon = inpCP.Epsilon
+	inpCompositeTraintuple.Delta = inpCP.Delta
+	inpCompositeTraintuple.Resources = inpCP.Resources
 
 	// Set the inModels by matching the id to traintuples key previously
 	// encontered in this compute plan
@@ -102,10 +121,54 @@ func (inpTesttuple *inputTesttuple) Fill(inpCP inputComputePlanTesttuple, IDToTr
 	inpTesttuple.Tag = inpCP.Tag
 	inpTesttuple.Metadata = inpCP.Metadata
 	inpTesttuple.ObjectiveKey = inpCP.ObjectiveKey
+	inpTesttuple.Epsilon = inpCP.Epsilon
+	inpTesttuple.Delta = inpCP.Delta
+	inpTesttuple.Resources = inpCP.Resources
 
 	return nil
 }
 
+// generateKeys sets the keys of the tuples which have none, deriving them from the transaction ID
+// and their task ID. The testtuples without ID get their key, or a generated one, as ID.
+func (inp *inputComputePlan) generateKeys(txID string) {
+	// the tuples are copied so that the input of the caller is left untouched
+	inp.Traintuples = append([]inputComputePlanTraintuple{}, inp.Traintuples...)
+	for i := range inp.Traintuples {
+		setTaskKey(txID, &inp.Traintuples[i].Key, inp.Traintuples[i].ID)
+	}
+	inp.Aggregatetuples = append([]inputComputePlanAggregatetuple{}, inp.Aggregatetuples...)
+	for i := range inp.Aggregatetuples {
+		setTaskKey(txID, &inp.Aggregatetuples[i].Key, inp.Aggregatetuples[i].ID)
+	}
+	inp.CompositeTraintuples = append([]inputComputePlanCompositeTraintuple{}, inp.CompositeTraintuples...)
+	for i := range inp.CompositeTraintuples {
+		setTaskKey(txID, &inp.CompositeTraintuples[i].Key, inp.CompositeTraintuples[i].ID)
+	}
+	inp.Testtuples = append([]inputComputePlanTesttuple{}, inp.Testtuples...)
+	for i := range inp.Testtuples {
+		tuple := &inp.Testtuples[i]
+		// testtuples were created without ID before they could be referenced by conditions
+		if tuple.ID == "" && tuple.Key != "" {
+			tuple.ID = tuple.Key
+		} else if tuple.ID == "" {
+			tuple.ID = GenerateKey(txID, fmt.Sprintf("testtuple/%d", i))
+		}
+		setTaskKey(txID, &tuple.Key, tuple.ID)
+	}
+}
+
+// setTaskKey derives the key of a task from its ID when it has none
+func setTaskKey(txID string, key *string, ID string) {
+	if *key == "" {
+		*key = computePlanTaskKey(txID, ID)
+	}
+}
+
+// computePlanTaskKey returns the key generated for the tuple of a compute plan task
+func computePlanTaskKey(txID string, ID string) string {
+	return GenerateKey(txID, "task/"+ID)
+}
+
 // createComputePlan is the wrapper for the substra smartcontract CreateComputePlan
 func createComputePlan(db *LedgerDB, args []string) (resp outputComputePlan, err error) {
 	inp := inputNewComputePlan{}
@@ -113,7 +176,7 @@ func createComputePlan(db *LedgerDB, args []string) (resp outputComputePlan, err
 	if err != nil {
 		return
 	}
-	return createComputePlanInternal(db, inp.inputComputePlan, inp.Tag, inp.Metadata, inp.CleanModels)
+	return createComputePlanInternal(db, inp.inputComputePlan, inp.Tag, inp.Metadata, inp.CleanModels, inp.Priority)
 }
 
 func updateComputePlan(db *LedgerDB, args []string) (resp outputComputePlan, err error) {
@@ -127,26 +190,46 @@ func updateComputePlan(db *LedgerDB, args []string) (resp outputComputePlan, err
 		len(inp.Aggregatetuples) +
 		len(inp.CompositeTraintuples) +
 		len(inp.Testtuples)
+	if inp.Key == "" {
+		return resp, errors.BadRequest("the key of the compute plan to update is required")
+	}
 	if count == 0 {
 		return resp, errors.BadRequest("empty update for compute plan %s", inp.Key)
 	}
 	return updateComputePlanInternal(db, inp)
 }
 
-func createComputePlanInternal(db *LedgerDB, inp inputComputePlan, tag string, metadata map[string]string, cleanModels bool) (resp outputComputePlan, err error) {
+func createComputePlanInternal(db *LedgerDB, inp inputComputePlan, tag string, metadata map[string]string, cleanModels bool, priority int) (resp outputComputePlan, err error) {
+	if inp.Key == "" {
+		inp.Key = GenerateKey(db.cc.GetTxID(), ComputePlanType.String())
+	}
 	var computePlan ComputePlan
+	computePlan.Creator, err = GetTxCreator(db.cc)
+	if err != nil {
+		return resp, err
+	}
+	count := len(inp.Traintuples) +
+		len(inp.Aggregatetuples) +
+		len(inp.CompositeTraintuples) +
+		len(inp.Testtuples)
+	if err = checkComputePlanQuota(db, computePlan.Creator); err != nil {
+		return resp, err
+	}
+	if err = computePlan.checkTuplesPerComputePlanQuota(db, count); err != nil {
+		return resp, err
+	}
 	computePlan.State.Status = StatusWaiting
 	computePlan.Tag = tag
 	computePlan.Metadata = metadata
 	computePlan.CleanModels = cleanModels
+	computePlan.Priority = priority
 	err = computePlan.Create(db, inp.Key)
 	if err != nil {
 		return resp, err
 	}
-	count := len(inp.Traintuples) +
-		len(inp.Aggregatetuples) +
-		len(inp.CompositeTraintuples) +
-		len(inp.Testtuples)
+	if err = reserveQuota(db, computePlan.Creator, quotaComputePlan, computePlan.Key); err != nil {
+		return resp, err
+	}
 	if count == 0 {
 		resp.Fill(inp.Key, computePlan, []string{}, 0, 0)
 		return resp, nil
@@ -156,10 +239,21 @@ func createComputePlanInternal(db *LedgerDB, inp inputComputePlan, tag string, m
 
 func updateComputePlanInternal(db *LedgerDB, inp inputComputePlan) (resp outputComputePlan, err error) {
 	var tupleKey string
+	inp.generateKeys(db.cc.GetTxID())
 	computePlan, err := db.GetComputePlan(inp.Key)
 	if err != nil {
 		return resp, err
 	}
+	if err = computePlan.checkCreator(db, inp.Key); err != nil {
+		return resp, err
+	}
+	count := len(inp.Traintuples) +
+		len(inp.Aggregatetuples) +
+		len(inp.CompositeTraintuples) +
+		len(inp.Testtuples)
+	if err = computePlan.checkTuplesPerComputePlanQuota(db, count); err != nil {
+		return resp, err
+	}
 	IDToTrainTask := map[string]TrainTask{}
 	for ID, trainTask := range computePlan.IDToTrainTask {
 		IDToTrainTask[ID] = trainTask
@@ -170,73 +264,12 @@ func updateComputePlanInternal(db *LedgerDB, inp inputComputePlan) (resp outputC
 		return resp, errors.BadRequest(err)
 	}
 	for _, task := range DAG.OrderTasks {
-		switch task.TaskType {
-		case TraintupleType:
-			computeTraintuple := inp.Traintuples[task.InputIndex]
-			inpTraintuple := inputTraintuple{
-				Rank: strconv.Itoa(task.Depth),
-			}
-			inpTraintuple.ComputePlanKey = inp.Key
-			err = inpTraintuple.Fill(computeTraintuple, IDToTrainTask)
-			if err != nil {
-				return resp, errors.BadRequest("traintuple ID %s: "+err.Error(), computeTraintuple.ID)
-			}
-
-			// Intentionally skip the compute plan availability check: since the transaction hasn't been
-			// committed yet, the index changes haven't been commited, so the check would always fail.
-			tupleKey, err = createTraintupleInternal(db, inpTraintuple, false)
-			if err != nil {
-				return resp, errors.BadRequest("traintuple ID %s: "+err.Error(), computeTraintuple.ID)
-			}
-		case CompositeTraintupleType:
-			computeCompositeTraintuple := inp.CompositeTraintuples[task.InputIndex]
-			inpCompositeTraintuple := inputCompositeTraintuple{
-				Rank: strconv.Itoa(task.Depth),
-			}
-			inpCompositeTraintuple.ComputePlanKey = inp.Key
-			err = inpCompositeTraintuple.Fill(computeCompositeTraintuple, IDToTrainTask)
-			if err != nil {
-				return resp, errors.BadRequest("traintuple ID %s: "+err.Error(), computeCompositeTraintuple.ID)
-			}
-			// Intentionally skip the compute plan availability check: since the transaction hasn't been
-			// committed yet, the index changes haven't been commited, so the check would always fail.
-			tupleKey, err = createCompositeTraintupleInternal(db, inpCompositeTraintuple, false)
-			if err != nil {
-				return resp, errors.BadRequest("traintuple ID %s: "+err.Error(), computeCompositeTraintuple.ID)
-			}
-		case AggregatetupleType:
-			computeAggregatetuple := inp.Aggregatetuples[task.InputIndex]
-			inpAggregatetuple := inputAggregatetuple{
-				Rank: strconv.Itoa(task.Depth),
-			}
-			inpAggregatetuple.ComputePlanKey = inp.Key
-			err = inpAggregatetuple.Fill(computeAggregatetuple, IDToTrainTask)
-			if err != nil {
-				return resp, errors.BadRequest("traintuple ID %s: "+err.Error(), computeAggregatetuple.ID)
-			}
-			// Intentionally skip the compute plan availability check: since the transaction hasn't been
-			// committed yet, the index changes haven't been commited, so the check would always fail.
-			tupleKey, err = createAggregatetupleInternal(db, inpAggregatetuple, false)
-			if err != nil {
-				return resp, errors.BadRequest("traintuple ID %s: "+err.Error(), computeAggregatetuple.ID)
-			}
-		}
-		IDToTrainTask[task.ID] = TrainTask{Depth: task.Depth, Key: tupleKey}
-		NewIDs = append(NewIDs, task.ID)
-	}
-
-	for index, computeTesttuple := range inp.Testtuples {
-		inpTesttuple := inputTesttuple{}
-		err = inpTesttuple.Fill(computeTesttuple, IDToTrainTask)
-		if err != nil {
-			return resp, errors.BadRequest("testtuple at index %s: "+err.Error(), index)
-		}
-
-		_, err := createTesttupleInternal(db, inpTesttuple)
+		tupleKey, err = createComputePlanTask(db, inp, task, IDToTrainTask)
 		if err != nil {
-			return resp, errors.BadRequest("testtuple at index %s: "+err.Error(), index)
+			return resp, err
 		}
-
+		IDToTrainTask[task.ID] = TrainTask{Depth: task.Depth, Key: tupleKey, Testtuple: task.TaskType == TesttupleType}
+		NewIDs = append(NewIDs, task.ID)
 	}
 
 	computePlan, err = db.GetComputePlan(inp.Key)
@@ -257,6 +290,69 @@ func updateComputePlanInternal(db *LedgerDB, inp inputComputePlan) (resp outputC
 	return resp, err
 }
 
+// createComputePlanTask creates the tuple of a task of the compute plan DAG and returns its key.
+// The keys of the tasks it depends on must be in IDToTrainTask.
+// The worker/rank uniqueness check of the tuples is skipped: the rank is the depth of the task
+// in the compute plan, several tasks of the compute plan can share a worker and a depth.
+func createComputePlanTask(db *LedgerDB, inp inputComputePlan, task TrainingTask, IDToTrainTask map[string]TrainTask) (tupleKey string, err error) {
+	switch task.TaskType {
+	case TraintupleType:
+		computeTraintuple := inp.Traintuples[task.InputIndex]
+		inpTraintuple := inputTraintuple{
+			Rank: strconv.Itoa(task.Depth),
+		}
+		inpTraintuple.ComputePlanKey = inp.Key
+		err = inpTraintuple.Fill(computeTraintuple, IDToTrainTask)
+		if err != nil {
+			return tupleKey, errors.BadRequest("traintuple ID %s: "+err.Error(), computeTraintuple.ID)
+		}
+		tupleKey, err = createTraintupleInternal(db, inpTraintuple, false)
+		if err != nil {
+			return tupleKey, errors.BadRequest("traintuple ID %s: "+err.Error(), computeTraintuple.ID)
+		}
+	case CompositeTraintupleType:
+		computeCompositeTraintuple := inp.CompositeTraintuples[task.InputIndex]
+		inpCompositeTraintuple := inputCompositeTraintuple{
+			Rank: strconv.Itoa(task.Depth),
+		}
+		inpCompositeTraintuple.ComputePlanKey = inp.Key
+		err = inpCompositeTraintuple.Fill(computeCompositeTraintuple, IDToTrainTask)
+		if err != nil {
+			return tupleKey, errors.BadRequest("traintuple ID %s: "+err.Error(), computeCompositeTraintuple.ID)
+		}
+		tupleKey, err = createCompositeTraintupleInternal(db, inpCompositeTraintuple, false)
+		if err != nil {
+			return tupleKey, errors.BadRequest("traintuple ID %s: "+err.Error(), computeCompositeTraintuple.ID)
+		}
+	case AggregatetupleType:
+		computeAggregatetuple := inp.Aggregatetuples[task.InputIndex]
+		inpAggregatetuple := inputAggregatetuple{
+			Rank: strconv.Itoa(task.Depth),
+		}
+		inpAggregatetuple.ComputePlanKey = inp.Key
+		err = inpAggregatetuple.Fill(computeAggregatetuple, IDToTrainTask)
+		if err != nil {
+			return tupleKey, errors.BadRequest("traintuple ID %s: "+err.Error(), computeAggregatetuple.ID)
+		}
+		tupleKey, err = createAggregatetupleInternal(db, inpAggregatetuple, false)
+		if err != nil {
+			return tupleKey, errors.BadRequest("traintuple ID %s: "+err.Error(), computeAggregatetuple.ID)
+		}
+	case TesttupleType:
+		computeTesttuple := inp.Testtuples[task.InputIndex]
+		inpTesttuple := inputTesttuple{}
+		err = inpTesttuple.Fill(computeTesttuple, IDToTrainTask)
+		if err != nil {
+			return tupleKey, errors.BadRequest("testtuple ID %s: "+err.Error(), computeTesttuple.ID)
+		}
+		tupleKey, err = createTesttupleInternal(db, inpTesttuple)
+		if err != nil {
+			return tupleKey, errors.BadRequest("testtuple ID %s: "+err.Error(), computeTesttuple.ID)
+		}
+	}
+	return tupleKey, err
+}
+
 func queryComputePlan(db *LedgerDB, args []string) (resp outputComputePlan, err error) {
 	inp := inputKey{}
 	err = AssetFromJSON(args, &inp)
@@ -328,11 +424,15 @@ func cancelComputePlan(db *LedgerDB, args []string) (resp outputComputePlan, err
 		return outputComputePlan{}, err
 	}
 
+	oldStatus := computeplan.State.Status
 	computeplan.State.Status = StatusCanceled
 	err = computeplan.SaveState(db)
 	if err != nil {
 		return outputComputePlan{}, err
 	}
+	if err = computeplan.updateQuotaUsage(db, oldStatus); err != nil {
+		return outputComputePlan{}, err
+	}
 
 	models, err := computeplan.removeAllIntermediaryModels(db)
 	if err != nil {
@@ -352,6 +452,57 @@ func cancelComputePlan(db *LedgerDB, args []string) (resp outputComputePlan, err
 	return resp, nil
 }
 
+// updateComputePlanPriority changes the priority of a compute plan and of its tuples.
+// Only the creator of the compute plan can change it.
+func updateComputePlanPriority(db *LedgerDB, args []string) (resp outputComputePlan, err error) {
+	inp := inputComputePlanPriority{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	computeplan, err := db.GetComputePlan(inp.Key)
+	if err != nil {
+		return outputComputePlan{}, err
+	}
+	txCreator, err := GetTxCreator(db.cc)
+	if err != nil {
+		return outputComputePlan{}, err
+	}
+	if computeplan.Creator == "" {
+		// compute plans created before their creator was recorded keep the default priority
+		return outputComputePlan{}, errors.BadRequest("compute plan %s has no recorded creator, its priority cannot be changed", inp.Key)
+	}
+	if txCreator != computeplan.Creator {
+		return outputComputePlan{}, errors.Forbidden("only %s can change the priority of compute plan %s", computeplan.Creator, inp.Key)
+	}
+	if err = computeplan.updatePriority(db, inp.Key, inp.Priority); err != nil {
+		return outputComputePlan{}, err
+	}
+
+	doneCount, tupleCount, err := computeplan.getTupleCounts(db)
+	if err != nil {
+		return resp, err
+	}
+	resp.Fill(inp.Key, computeplan, []string{}, doneCount, tupleCount)
+	return resp, nil
+}
+
+// checkCreator checks that the transaction creator is the creator of the compute plan.
+// Compute plans created before their creator was recorded can be updated by any node.
+func (cp *ComputePlan) checkCreator(db *LedgerDB, key string) error {
+	if cp.Creator == "" {
+		return nil
+	}
+	txCreator, err := GetTxCreator(db.cc)
+	if err != nil {
+		return err
+	}
+	if txCreator != cp.Creator {
+		return errors.Forbidden("only %s can update compute plan %s", cp.Creator, key)
+	}
+	return nil
+}
+
 // Create adds a Compute Plan to the ledger and registers it in the compute plan index
 func (cp *ComputePlan) Create(db *LedgerDB, key string) error {
 	cp.Key = key
@@ -386,67 +537,29 @@ func (cp *ComputePlan) SaveState(db *LedgerDB) error {
 	return db.Put(cp.StateKey, cp.State)
 }
 
-// UpdateState check the tuple status (from an updated tuple or a new one)
-// and, if required, it updates the compute plan' status and/or its doneCount.
-// It returns true and the list of models to delete if there is any change to the compute plan, false and empty list otherwise.
-func (cp *ComputePlan) UpdateState(db *LedgerDB, tupleStatus string, worker string) (bool, []string, error) {
+// UpdateState check the status of a tuple added to the compute plan and, if required,
+// it updates the compute plan's stored status. The transitions of the tuples already
+// added are recorded as counter deltas instead, see addCounterDelta.
+// It returns true if there is any change to the compute plan, false otherwise.
+func (cp *ComputePlan) UpdateState(tupleStatus string) bool {
 	switch cp.State.Status {
 	case StatusFailed, StatusCanceled:
 	case StatusDone:
 		// We might add tuples to a done compute plan
 		if stringInSlice(tupleStatus, []string{StatusWaiting, StatusTodo}) {
 			cp.State.Status = tupleStatus
-			return true, []string{}, nil
-		}
-	case StatusDoing:
-		switch tupleStatus {
-		case StatusFailed:
-			cp.State.Status = tupleStatus
-			return true, []string{}, nil
-		case StatusDone:
-			// In order for the CP to transition to the "done" state, each worker must have all
-			// its tuples in the "done" state. Checking the state of all the workers is
-			// expensive and can lead to MVCC conflicts because workers each write to their
-			// respective states concurrently. To mitigate this issue, we first check if the
-			//  *current* worker has finished processing all of its tuples. Only if that's the
-			// case do we inspect the state of other workers.
-			cp.incrementWorkerDoneCount(db, worker)
-			wStateKey := cp.getCPWorkerStateKey(worker)
-			wState, err := db.GetCPWorkerState(wStateKey)
-			if err != nil {
-				return false, []string{}, err
-			}
-			if wState.DoneCount == wState.TupleCount {
-				doneCount, tupleCount, err := cp.getTupleCounts(db)
-				if err != nil {
-					return false, []string{}, err
-				}
-				if doneCount == tupleCount {
-					modelsToDelete, err := cp.removeAllIntermediaryModels(db)
-					if err != nil {
-						return false, []string{}, err
-					}
-					cp.State.Status = StatusDone
-					return true, modelsToDelete, nil
-				}
-			}
-			return false, []string{}, nil
-		}
-	case StatusTodo:
-		if tupleStatus == StatusDoing {
-			cp.State.Status = tupleStatus
-			return true, []string{}, nil
+			return true
 		}
 	case StatusWaiting:
 		if tupleStatus == StatusTodo {
 			cp.State.Status = tupleStatus
-			return true, []string{}, nil
+			return true
 		}
 	case "":
 		cp.State.Status = tupleStatus
-		return true, []string{}, nil
+		return true
 	}
-	return false, []string{}, nil
+	return false
 }
 
 // AddTuple add the tuple key to the compute plan and update it accordingly
@@ -461,13 +574,22 @@ func (cp *ComputePlan) AddTuple(db *LedgerDB, tupleType AssetType, key, status s
 	case TesttupleType:
 		cp.TesttupleKeys = append(cp.TesttupleKeys, key)
 	}
-	cp.incrementWorkerTupleCount(db, worker)
-	_, _, err := cp.UpdateState(db, status, worker)
-	return err
+	if err := cp.checkTuplesPerComputePlanQuota(db, 0); err != nil {
+		return err
+	}
+	if err := cp.incrementWorkerTupleCount(db, worker, status); err != nil {
+		return err
+	}
+	oldStatus := cp.State.Status
+	cp.UpdateState(status)
+	return cp.updateQuotaUsage(db, oldStatus)
 }
 
-// UpdateComputePlanState retreive the compute plan if the ID is not empty,
-// check if the updated status change anything and save it if it's the case
+// UpdateComputePlanState retreive the compute plan if the ID is not empty and records
+// the new status of one of its tuples.
+// Only the failure of a tuple updates the compute plan state. The other transitions are
+// appended as counter deltas so that concurrent tuple updates don't conflict on the counters,
+// the done status is stored by compactComputePlan.
 func UpdateComputePlanState(db *LedgerDB, ComputePlanKey, tupleStatus, tupleKey string, worker string) error {
 	if ComputePlanKey == "" {
 		return nil
@@ -476,20 +598,19 @@ func UpdateComputePlanState(db *LedgerDB, ComputePlanKey, tupleStatus, tupleKey
 	if err != nil {
 		return err
 	}
-	stateUpdated, modelsToDelete, err := cp.UpdateState(db, tupleStatus, worker)
-	if err != nil {
-		return err
+	if tupleStatus != StatusFailed {
+		return cp.addCounterDelta(db, tupleStatus, worker, tupleKey)
 	}
-
-	if err != nil {
+	if stringInSlice(cp.State.Status, []string{StatusFailed, StatusCanceled}) {
+		return nil
+	}
+	oldStatus := cp.State.Status
+	cp.State.Status = StatusFailed
+	if err = cp.updateQuotaUsage(db, oldStatus); err != nil {
 		return err
 	}
-	if stateUpdated || len(modelsToDelete) > 0 {
-		err = db.AddComputePlanEvent(ComputePlanKey, cp.State.Status, modelsToDelete)
-		if err != nil {
-			return err
-		}
-		return cp.SaveState(db)
+	if err = db.AddComputePlanEvent(ComputePlanKey, cp.State.Status, []string{}); err != nil {
+		return err
 	}
-	return nil
+	return cp.SaveState(db)
 }
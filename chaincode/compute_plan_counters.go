@@ -0,0 +1,212 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+// Tuple transitions are not counted by rewriting the worker states: each transition
+// appends its own key to the counter index without reading the others, so that tuples
+// updated concurrently neither write the same key nor read the keys written by each other. The counters are the sum of the worker states and of these deltas,
+// until a compaction folds the deltas into the worker states.
+const computePlanCounterIndex = "computePlan~key~counter~worker~tupleKey"
+
+// counterByStatus maps the tuple statuses to the counters they increment.
+// Tuples canceled because of their condition are settled like done ones.
+var counterByStatus = map[string]string{
+	StatusTodo:    StatusTodo,
+	StatusDoing:   StatusDoing,
+	StatusDone:    StatusDone,
+	StatusAborted: StatusDone,
+}
+
+// computePlanCounters are the tuple counts of a compute plan, or of one of its workers
+type computePlanCounters struct {
+	Tuple int
+	Todo  int
+	Doing int
+	Done  int
+}
+
+// addCount increases the count matching a tuple status, if any
+func (wState *ComputePlanWorkerState) addCount(status string, n int) {
+	switch counterByStatus[status] {
+	case StatusTodo:
+		wState.TodoCount += n
+	case StatusDoing:
+		wState.DoingCount += n
+	case StatusDone:
+		wState.DoneCount += n
+	}
+}
+
+// status derives the status of the compute plan from its counters.
+// Failed and canceled compute plans keep their stored status.
+func (c computePlanCounters) status(storedStatus string) string {
+	switch {
+	case stringInSlice(storedStatus, []string{StatusFailed, StatusCanceled}):
+		return storedStatus
+	case c.Tuple > 0 && c.Done == c.Tuple:
+		return StatusDone
+	case c.Doing > 0:
+		return StatusDoing
+	case c.Todo > 0:
+		return StatusTodo
+	}
+	return StatusWaiting
+}
+
+// addCounterDelta records the transition of a tuple to a new status.
+// The key of the delta only depends on the tuple and the counter, recording the
+// same transition twice is harmless.
+func (cp *ComputePlan) addCounterDelta(db *LedgerDB, status, worker, tupleKey string) error {
+	counter, ok := counterByStatus[status]
+	if !ok {
+		return nil
+	}
+	return db.CreateIndex(computePlanCounterIndex, []string{"computePlan", cp.Key, counter, worker, tupleKey})
+}
+
+// getCounterDeltas returns the keys of the tuples having a delta for a given worker, by counter
+func (cp *ComputePlan) getCounterDeltas(db *LedgerDB, worker string) (map[string][]string, error) {
+	deltas := map[string][]string{}
+	for _, counter := range []string{StatusTodo, StatusDoing, StatusDone} {
+		keys, err := db.GetIndexKeys(computePlanCounterIndex, []string{"computePlan", cp.Key, counter, worker})
+		if err != nil {
+			return nil, err
+		}
+		deltas[counter] = keys
+	}
+	return deltas, nil
+}
+
+// getWorkerState returns the state of a worker with its counter deltas added
+// to its counts, and the deltas themselves
+func (cp *ComputePlan) getWorkerState(db *LedgerDB, worker string) (*ComputePlanWorkerState, map[string][]string, error) {
+	wState, err := db.GetCPWorkerState(cp.getCPWorkerStateKey(worker))
+	if err != nil {
+		return nil, nil, err
+	}
+	deltas, err := cp.getCounterDeltas(db, worker)
+	if err != nil {
+		return nil, nil, err
+	}
+	for counter, keys := range deltas {
+		wState.addCount(counter, len(keys))
+	}
+	return wState, deltas, nil
+}
+
+// getCounters returns the tuple counters of the compute plan
+func (cp *ComputePlan) getCounters(db *LedgerDB) (computePlanCounters, error) {
+	counters := computePlanCounters{}
+	for _, worker := range cp.Workers {
+		wState, _, err := cp.getWorkerState(db, worker)
+		if err != nil {
+			return counters, err
+		}
+		counters.add(wState)
+	}
+	return counters, nil
+}
+
+func (c *computePlanCounters) add(wState *ComputePlanWorkerState) {
+	c.Tuple += wState.TupleCount
+	c.Todo += wState.TodoCount
+	c.Doing += wState.DoingCount
+	c.Done += wState.DoneCount
+}
+
+// getTupleCounts returns the number of tuples in the "done" state and the total number of tuples
+// for a given compute plan. It also sets the compute plan status to the one derived from its counters.
+func (cp *ComputePlan) getTupleCounts(db *LedgerDB) (doneCount int, tupleCount int, err error) {
+	counters, err := cp.getCounters(db)
+	if err != nil {
+		return 0, 0, err
+	}
+	cp.State.Status = counters.status(cp.State.Status)
+	return counters.Done, counters.Tuple, nil
+}
+
+// compact folds the counter deltas into the worker states, stores the derived status and
+// returns the counters. When the compute plan becomes done, its intermediary models are released.
+func (cp *ComputePlan) compact(db *LedgerDB) (computePlanCounters, error) {
+	counters := computePlanCounters{}
+	wStates := map[string]*ComputePlanWorkerState{}
+	wDeltas := map[string]map[string][]string{}
+	for _, worker := range cp.Workers {
+		wState, deltas, err := cp.getWorkerState(db, worker)
+		if err != nil {
+			return counters, err
+		}
+		counters.add(wState)
+		wStates[worker], wDeltas[worker] = wState, deltas
+	}
+	oldStatus := cp.State.Status
+	cp.State.Status = counters.status(oldStatus)
+
+	for _, worker := range cp.Workers {
+		wState := wStates[worker]
+		if cp.State.Status == StatusDone {
+			// tuples added later to the compute plan bring it back to the "waiting" or "todo" state
+			wState.TodoCount, wState.DoingCount = 0, 0
+		}
+		if err := db.Put(cp.getCPWorkerStateKey(worker), wState); err != nil {
+			return counters, err
+		}
+		for counter, keys := range wDeltas[worker] {
+			for _, key := range keys {
+				if err := db.DeleteIndex(computePlanCounterIndex, []string{"computePlan", cp.Key, counter, worker, key}); err != nil {
+					return counters, err
+				}
+			}
+		}
+	}
+
+	if cp.State.Status == oldStatus {
+		return counters, nil
+	}
+	modelsToDelete := []string{}
+	if cp.State.Status == StatusDone {
+		var err error
+		if modelsToDelete, err = cp.removeAllIntermediaryModels(db); err != nil {
+			return counters, err
+		}
+	}
+	if err := cp.updateQuotaUsage(db, oldStatus); err != nil {
+		return counters, err
+	}
+	if err := db.AddComputePlanEvent(cp.Key, cp.State.Status, modelsToDelete); err != nil {
+		return counters, err
+	}
+	return counters, cp.SaveState(db)
+}
+
+// compactComputePlan compacts the counters of a compute plan.
+// The tuple transitions never read the deltas, so a compute plan whose tuples are all done
+// is only stored as done, and its intermediary models released, once it is compacted.
+func compactComputePlan(db *LedgerDB, args []string) (resp outputComputePlan, err error) {
+	inp := inputKey{}
+	if err = AssetFromJSON(args, &inp); err != nil {
+		return
+	}
+	computePlan, err := db.GetComputePlan(inp.Key)
+	if err != nil {
+		return
+	}
+	counters, err := computePlan.compact(db)
+	if err != nil {
+		return
+	}
+	resp.Fill(inp.Key, computePlan, []string{}, counters.Done, counters.Tuple)
+	return resp, nil
+}
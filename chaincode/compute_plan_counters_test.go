@@ -0,0 +1,125 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputePlanCounterDeltas(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	initGovernanceWithSettings(t, mockStub, map[string]string{"quota.max_active_compute_plans": "1"})
+	registerItem(t, *mockStub, "aggregateAlgo")
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	out, err := createComputePlanInternal(db, defaultComputePlan, tag, map[string]string{}, false, 0)
+	require.NoError(t, err)
+	cp, err := db.GetComputePlan(out.Key)
+	require.NoError(t, err)
+	wStateKey := cp.getCPWorkerStateKey(workerA)
+
+	traintupleToDone(t, db, out.TraintupleKeys[0])
+	traintupleToDone(t, db, out.TraintupleKeys[1])
+
+	// The transitions are appended as deltas, the worker state and the stored status are untouched
+	done, err := db.GetIndexKeys(computePlanCounterIndex, []string{"computePlan", out.Key, StatusDone, workerA})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, out.TraintupleKeys, done)
+	wState, err := db.GetCPWorkerState(wStateKey)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, wState.DoneCount)
+	assert.Equal(t, 3, wState.TupleCount)
+	cp, err = db.GetComputePlan(out.Key)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusTodo, cp.State.Status)
+	assert.Len(t, db.event.ComputePlans, 0)
+
+	// The status and counts are derived from the deltas
+	outCP, err := queryComputePlan(db, keyToArgs(out.Key))
+	assert.NoError(t, err)
+	assert.Equal(t, StatusDoing, outCP.Status)
+	assert.Equal(t, 2, outCP.DoneCount)
+
+	// Compacting a running compute plan folds the deltas and stores the derived status
+	outCP, err = compactComputePlan(db, assetToArgs(inputKey{Key: out.Key}))
+	assert.NoError(t, err)
+	assert.Equal(t, StatusDoing, outCP.Status)
+	assert.Equal(t, 2, outCP.DoneCount)
+	assert.Equal(t, 3, outCP.TupleCount)
+	require.Len(t, db.event.ComputePlans, 1)
+	assert.Equal(t, StatusDoing, db.event.ComputePlans[0].Status)
+	wState, err = db.GetCPWorkerState(wStateKey)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, wState.DoneCount)
+
+	clearEvent(db)
+
+	// The last transition only appends its delta, the compute plan is done once compacted
+	testtupleToDone(t, db, out.TesttupleKeys[0])
+	assert.Len(t, db.event.ComputePlans, 0)
+	outCP, err = queryComputePlan(db, keyToArgs(out.Key))
+	assert.NoError(t, err)
+	assert.Equal(t, StatusDone, outCP.Status)
+	_, err = compactComputePlan(db, assetToArgs(inputKey{Key: out.Key}))
+	assert.NoError(t, err)
+	require.Len(t, db.event.ComputePlans, 1)
+	assert.Equal(t, StatusDone, db.event.ComputePlans[0].Status)
+
+	done, err = db.GetIndexKeys(computePlanCounterIndex, []string{"computePlan", out.Key})
+	assert.NoError(t, err)
+	assert.Empty(t, done)
+	wState, err = db.GetCPWorkerState(wStateKey)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, wState.DoneCount)
+	cp, err = db.GetComputePlan(out.Key)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusDone, cp.State.Status)
+	usage, err := queryQuotaUsage(db, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, usage.ActiveComputePlans)
+
+	// Compacting again changes nothing
+	clearEvent(db)
+	outCP, err = compactComputePlan(db, assetToArgs(inputKey{Key: out.Key}))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, outCP.DoneCount)
+	assert.Len(t, db.event.ComputePlans, 0)
+}
+
+func TestComputePlanCounterStatus(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		stored   string
+		counters computePlanCounters
+		status   string
+	}{
+		{name: "empty", stored: StatusWaiting, status: StatusWaiting},
+		{name: "waiting", stored: StatusWaiting, counters: computePlanCounters{Tuple: 2}, status: StatusWaiting},
+		{name: "todo", stored: StatusWaiting, counters: computePlanCounters{Tuple: 2, Todo: 1}, status: StatusTodo},
+		{name: "doing", stored: StatusTodo, counters: computePlanCounters{Tuple: 2, Todo: 2, Doing: 1, Done: 1}, status: StatusDoing},
+		{name: "done", stored: StatusTodo, counters: computePlanCounters{Tuple: 2, Todo: 2, Doing: 2, Done: 2}, status: StatusDone},
+		{name: "failed", stored: StatusFailed, counters: computePlanCounters{Tuple: 2, Done: 2}, status: StatusFailed},
+		{name: "canceled", stored: StatusCanceled, counters: computePlanCounters{Tuple: 2}, status: StatusCanceled},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.status, tc.counters.status(tc.stored))
+		})
+	}
+}
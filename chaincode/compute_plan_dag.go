@@ -3,7 +3,7 @@ package main
 import "chaincode/errors"
 
 // TrainingTask is a node of a ComputeDAG. It represents a training task
-// (i.e. a Traintuple, a CompositeTraintuple or an Aggregatetuple)
+// (i.e. a Traintuple, a CompositeTraintuple or an Aggregatetuple) or a Testtuple
 type TrainingTask struct {
 	ID          string
 	InModelsIDs []string
@@ -29,6 +29,10 @@ func createComputeDAG(cp inputComputePlan, IDToTrainTask map[string]TrainTask) (
 			InputIndex:  i,
 			TaskType:    TraintupleType,
 		}
+		// a conditional traintuple also depends on the testtuple of its condition
+		if traintuple.Condition != nil {
+			task.InModelsIDs = append(append([]string{}, traintuple.InModelsIDs...), traintuple.Condition.TesttupleID)
+		}
 		DAG.OrderTasks = append(DAG.OrderTasks, task)
 	}
 	for i, traintuple := range cp.CompositeTraintuples {
@@ -49,6 +53,18 @@ func createComputeDAG(cp inputComputePlan, IDToTrainTask map[string]TrainTask) (
 		}
 		DAG.OrderTasks = append(DAG.OrderTasks, task)
 	}
+	for i, testtuple := range cp.Testtuples {
+		task := TrainingTask{
+			ID:          testtuple.ID,
+			InModelsIDs: []string{testtuple.TraintupleID},
+			InputIndex:  i,
+			TaskType:    TesttupleType,
+		}
+		DAG.OrderTasks = append(DAG.OrderTasks, task)
+	}
+	if err := checkNoTesttupleInModels(cp, IDToTrainTask); err != nil {
+		return DAG, err
+	}
 	DAG.IDToTrainTask = IDToTrainTask
 	err := DAG.sort()
 	if err != nil {
@@ -57,6 +73,47 @@ func createComputeDAG(cp inputComputePlan, IDToTrainTask map[string]TrainTask) (
 	return DAG, nil
 }
 
+// checkNoTesttupleInModels checks that the tasks don't use a testtuple as input model.
+// Only the conditions of the traintuples can depend on a testtuple.
+func checkNoTesttupleInModels(cp inputComputePlan, IDToTrainTask map[string]TrainTask) error {
+	testtupleIDs := map[string]bool{}
+	for ID, task := range IDToTrainTask {
+		testtupleIDs[ID] = task.Testtuple
+	}
+	for _, testtuple := range cp.Testtuples {
+		testtupleIDs[testtuple.ID] = true
+	}
+	check := func(ID string, inModelsIDs ...string) error {
+		for _, inModelID := range inModelsIDs {
+			if testtupleIDs[inModelID] {
+				return errors.BadRequest("compute plan error: task %s uses the testtuple %s as input model", ID, inModelID)
+			}
+		}
+		return nil
+	}
+	for _, tuple := range cp.Traintuples {
+		if err := check(tuple.ID, tuple.InModelsIDs...); err != nil {
+			return err
+		}
+	}
+	for _, tuple := range cp.CompositeTraintuples {
+		if err := check(tuple.ID, tuple.InHeadModelID, tuple.InTrunkModelID); err != nil {
+			return err
+		}
+	}
+	for _, tuple := range cp.Aggregatetuples {
+		if err := check(tuple.ID, tuple.InModelsIDs...); err != nil {
+			return err
+		}
+	}
+	for _, tuple := range cp.Testtuples {
+		if err := check(tuple.ID, tuple.TraintupleID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Sort the DAG's task list, or return an error if there is a cyclic dependency in inModelIDs
 func (dag *ComputeDAG) sort() error {
 	current := dag.OrderTasks
@@ -76,7 +133,12 @@ func (dag *ComputeDAG) sort() error {
 			if !ok {
 				break
 			}
-			depth = max(depth, parent.Depth+1)
+			// testtuples have the depth of the tuple they test
+			if current[i].TaskType == TesttupleType {
+				depth = max(depth, parent.Depth)
+			} else {
+				depth = max(depth, parent.Depth+1)
+			}
 		}
 		if ready {
 			current[i].Depth = depth
@@ -83,6 +83,15 @@ func TestDAGSort(t *testing.T) {
 			},
 			depths:      []int{1, 2, 5, 6},
 			expectError: false},
+		{name: "testtuples",
+			list: []TrainingTask{
+				{ID: "three", InModelsIDs: []string{"two"}},
+				{ID: "two", InModelsIDs: []string{"one"}, TaskType: TesttupleType},
+				{ID: "one"},
+				{ID: "four", InModelsIDs: []string{"three"}},
+			},
+			depths:      []int{0, 0, 1, 2},
+			expectError: false},
 	}
 	for _, tc := range ts {
 		t.Run(tc.name, func(t *testing.T) {
@@ -0,0 +1,238 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+)
+
+// registerComputePlanTemplate stores a new compute plan template
+func registerComputePlanTemplate(db *LedgerDB, args []string) (out outputComputePlanTemplate, err error) {
+	inp := inputComputePlanTemplate{}
+	if err = AssetFromJSON(args, &inp); err != nil {
+		return
+	}
+	owner, err := GetTxCreator(db.cc)
+	if err != nil {
+		return
+	}
+	template := ComputePlanTemplate{
+		Key:             inp.Key,
+		AssetType:       ComputePlanTemplateType,
+		Name:            inp.Name,
+		Owner:           owner,
+		Rounds:          inp.Rounds,
+		TestEvery:       inp.TestEvery,
+		AggregateWorker: inp.AggregateWorker,
+		CompositeAlgo:   inp.CompositeAlgo,
+		AggregateAlgo:   inp.AggregateAlgo,
+		Metadata:        inp.Metadata,
+	}
+	// parties are identified by their data manager in the names of the generated tasks
+	dataManagers := []string{}
+	for _, party := range inp.Parties {
+		if stringInSlice(party.DataManager, dataManagers) {
+			err = errors.BadRequest("data manager %s is used by several parties", party.DataManager)
+			return
+		}
+		dataManagers = append(dataManagers, party.DataManager)
+		template.Parties = append(template.Parties, TemplateParty(party))
+	}
+	if err = db.Add(template.Key, template); err != nil {
+		return
+	}
+	if err = db.CreateIndex("computePlanTemplate~key", []string{"computePlanTemplate", template.Key}); err != nil {
+		return
+	}
+	out.Fill(template)
+	return
+}
+
+func queryComputePlanTemplate(db *LedgerDB, args []string) (out outputComputePlanTemplate, err error) {
+	inp := inputKey{}
+	if err = AssetFromJSON(args, &inp); err != nil {
+		return
+	}
+	template, err := db.GetComputePlanTemplate(inp.Key)
+	if err != nil {
+		return
+	}
+	out.Fill(template)
+	return
+}
+
+func queryComputePlanTemplates(db *LedgerDB, args []string) (outTemplates []outputComputePlanTemplate, err error) {
+	outTemplates = []outputComputePlanTemplate{}
+	if len(args) != 0 {
+		err = errors.BadRequest("incorrect number of arguments, expecting nothing")
+		return
+	}
+	elementsKeys, err := db.GetIndexKeys("computePlanTemplate~key", []string{"computePlanTemplate"})
+	if err != nil {
+		return
+	}
+	for _, key := range elementsKeys {
+		var template ComputePlanTemplate
+		template, err = db.GetComputePlanTemplate(key)
+		if err != nil {
+			return
+		}
+		var out outputComputePlanTemplate
+		out.Fill(template)
+		outTemplates = append(outTemplates, out)
+	}
+	return
+}
+
+// instantiateComputePlan creates a compute plan from a template, the tuples being generated
+// by the chaincode instead of being listed by the client
+func instantiateComputePlan(db *LedgerDB, args []string) (resp outputComputePlan, err error) {
+	inp := inputInstantiateComputePlan{}
+	if err = AssetFromJSON(args, &inp); err != nil {
+		return
+	}
+	template, err := db.GetComputePlanTemplate(inp.TemplateKey)
+	if err != nil {
+		return resp, errors.BadRequest(err, "could not retrieve compute plan template with key %s", inp.TemplateKey)
+	}
+	inpCP, err := template.expand(db, inp)
+	if err != nil {
+		return
+	}
+	if err = newInputValidator().Struct(inpCP); err != nil {
+		return resp, errors.BadRequest(err, "invalid compute plan generated from template %s", inp.TemplateKey)
+	}
+	return createComputePlanInternal(db, inpCP.inputComputePlan, inpCP.Tag, inpCP.Metadata, inpCP.CleanModels, inpCP.Priority)
+}
+
+// expand generates the compute plan described by the template, once its symbols are bound.
+// The tuple keys are generated from the transaction ID and the task IDs, which are derived from the names
+// of the tasks, such as "composite/<data manager>/<round>", "aggregate/<round>" and "test/<data manager>/<round>".
+func (template ComputePlanTemplate) expand(db *LedgerDB, inp inputInstantiateComputePlan) (inpCP inputNewComputePlan, err error) {
+	rounds := template.Rounds
+	if inp.Rounds > 0 {
+		rounds = inp.Rounds
+	}
+	aggregateWorker, ok := inp.Workers[template.AggregateWorker]
+	if !ok {
+		return inpCP, errors.BadRequest("worker %s of the template is not bound", template.AggregateWorker)
+	}
+	compositeAlgoKey, ok := inp.Algos[template.CompositeAlgo]
+	if !ok {
+		return inpCP, errors.BadRequest("algo %s of the template is not bound", template.CompositeAlgo)
+	}
+	aggregateAlgoKey, ok := inp.Algos[template.AggregateAlgo]
+	if !ok {
+		return inpCP, errors.BadRequest("algo %s of the template is not bound", template.AggregateAlgo)
+	}
+
+	// the trunk models are shared between all the workers of the compute plan
+	workers := []string{aggregateWorker}
+	dataManagers := []inputTemplateDataManager{}
+	for _, party := range template.Parties {
+		worker, ok := inp.Workers[party.Worker]
+		if !ok {
+			return inpCP, errors.BadRequest("worker %s of the template is not bound", party.Worker)
+		}
+		dataManager, ok := inp.DataManagers[party.DataManager]
+		if !ok {
+			return inpCP, errors.BadRequest("data manager %s of the template is not bound", party.DataManager)
+		}
+		owner, err := getDataManagerOwner(db, dataManager.Key)
+		if err != nil {
+			return inpCP, errors.BadRequest(err, "could not retrieve data manager %s bound to %s", dataManager.Key, party.DataManager)
+		}
+		if owner != worker {
+			return inpCP, errors.BadRequest("data manager %s bound to %s is owned by %s instead of worker %s", dataManager.Key, party.DataManager, owner, worker)
+		}
+		if len(dataManager.TestDataSampleKeys) > 0 && inp.ObjectiveKey == "" {
+			return inpCP, errors.BadRequest("an objective is required to test on data manager %s", party.DataManager)
+		}
+		if !stringInSlice(worker, workers) {
+			workers = append(workers, worker)
+		}
+		dataManagers = append(dataManagers, dataManager)
+	}
+	trunkPermissions := inputPermissions{Process: inputPermission{Public: false, AuthorizedIDs: workers}}
+
+	inpCP = inputNewComputePlan{
+		CleanModels: inp.CleanModels,
+		Tag:         inp.Tag,
+		Metadata:    inp.Metadata,
+		Priority:    inp.Priority,
+	}
+	inpCP.Key = inp.Key
+	for round := 0; round < rounds; round++ {
+		tested := round == rounds-1 || (template.TestEvery > 0 && (round+1)%template.TestEvery == 0)
+		aggregate := inputComputePlanAggregatetuple{
+			AlgoKey:  aggregateAlgoKey,
+			Worker:   aggregateWorker,
+			Metadata: templateTaskMetadata(template, fmt.Sprintf("aggregate/%d", round), round),
+		}
+		aggregate.ID = templateTaskID(fmt.Sprintf("aggregate/%d", round))
+		for i, party := range template.Parties {
+			name := fmt.Sprintf("composite/%s/%d", party.DataManager, round)
+			composite := inputComputePlanCompositeTraintuple{
+				DataManagerKey:           dataManagers[i].Key,
+				DataSampleKeys:           dataManagers[i].TrainDataSampleKeys,
+				AlgoKey:                  compositeAlgoKey,
+				OutTrunkModelPermissions: trunkPermissions,
+				Metadata:                 templateTaskMetadata(template, name, round),
+			}
+			composite.ID = templateTaskID(name)
+			if round > 0 {
+				composite.InHeadModelID = templateTaskID(fmt.Sprintf("composite/%s/%d", party.DataManager, round-1))
+				composite.InTrunkModelID = templateTaskID(fmt.Sprintf("aggregate/%d", round-1))
+			}
+			inpCP.CompositeTraintuples = append(inpCP.CompositeTraintuples, composite)
+			aggregate.InModelsIDs = append(aggregate.InModelsIDs, composite.ID)
+
+			if !tested || len(dataManagers[i].TestDataSampleKeys) == 0 {
+				continue
+			}
+			name = fmt.Sprintf("test/%s/%d", party.DataManager, round)
+			testtuple := inputComputePlanTesttuple{
+				DataManagerKey: dataManagers[i].Key,
+				DataSampleKeys: dataManagers[i].TestDataSampleKeys,
+				ObjectiveKey:   inp.ObjectiveKey,
+				TraintupleID:   composite.ID,
+				Metadata:       templateTaskMetadata(template, name, round),
+			}
+			testtuple.ID = templateTaskID(name)
+			inpCP.Testtuples = append(inpCP.Testtuples, testtuple)
+		}
+		inpCP.Aggregatetuples = append(inpCP.Aggregatetuples, aggregate)
+	}
+	return inpCP, nil
+}
+
+// templateTaskID returns the ID of a task of an instantiated template, the hash of its name
+func templateTaskID(name string) string {
+	ID := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(ID[:])
+}
+
+// templateTaskMetadata records the template and the name of the task on its tuple
+func templateTaskMetadata(template ComputePlanTemplate, name string, round int) map[string]string {
+	return map[string]string{
+		"template_key":  template.Key,
+		"template_task": name,
+		"round":         strconv.Itoa(round),
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputePlanTemplate(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "aggregateAlgo")
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	inpTemplate := inputComputePlanTemplate{
+		Key:       RandomUUID(),
+		Name:      "federated averaging",
+		Rounds:    3,
+		TestEvery: 2,
+		Parties: []inputTemplateParty{
+			{Worker: "hospital", DataManager: "records1"},
+			{Worker: "hospital", DataManager: "records2"},
+		},
+		AggregateWorker: "aggregator",
+		CompositeAlgo:   "train",
+		AggregateAlgo:   "average",
+	}
+	template, err := registerComputePlanTemplate(db, assetToArgs(inpTemplate))
+	require.NoError(t, err)
+	assert.Equal(t, workerA, template.Owner)
+	templates, err := queryComputePlanTemplates(db, []string{})
+	require.NoError(t, err)
+	assert.Len(t, templates, 1)
+
+	// Parties are identified by their data manager
+	duplicate := inpTemplate
+	duplicate.Key = RandomUUID()
+	duplicate.Parties = []inputTemplateParty{inpTemplate.Parties[0], inpTemplate.Parties[0]}
+	_, err = registerComputePlanTemplate(db, assetToArgs(duplicate))
+	assert.Error(t, err)
+
+	inp := inputInstantiateComputePlan{
+		TemplateKey: inpTemplate.Key,
+		Key:         computePlanKey,
+		Workers:     map[string]string{"hospital": workerA, "aggregator": workerA},
+		DataManagers: map[string]inputTemplateDataManager{
+			"records1": {
+				Key:                 dataManagerKey,
+				TrainDataSampleKeys: []string{trainDataSampleKey1},
+				TestDataSampleKeys:  []string{testDataSampleKey1},
+			},
+			"records2": {
+				Key:                 dataManagerKey,
+				TrainDataSampleKeys: []string{trainDataSampleKey2},
+				TestDataSampleKeys:  []string{testDataSampleKey2},
+			},
+		},
+		Algos:        map[string]string{"train": compositeAlgoKey, "average": aggregateAlgoKey},
+		ObjectiveKey: objectiveKey,
+	}
+
+	// All the symbols must be bound to assets owned by their worker
+	unbound := inp
+	unbound.Algos = map[string]string{"train": compositeAlgoKey}
+	_, err = instantiateComputePlan(db, assetToArgs(unbound))
+	assert.Error(t, err)
+	wrongOwner := inp
+	wrongOwner.Workers = map[string]string{"hospital": "otherOrg", "aggregator": workerA}
+	_, err = instantiateComputePlan(db, assetToArgs(wrongOwner))
+	assert.Error(t, err)
+
+	out, err := instantiateComputePlan(db, assetToArgs(inp))
+	require.NoError(t, err)
+	assert.Len(t, out.CompositeTraintupleKeys, 6)
+	assert.Len(t, out.AggregatetupleKeys, 3)
+	// tested after the second and the last round
+	assert.Len(t, out.TesttupleKeys, 4)
+	assert.Equal(t, 13, out.TupleCount)
+
+	// The task IDs are derived from the task names, and the keys from the transaction ID
+	ID := templateTaskID("composite/records2/1")
+	key := computePlanTaskKey(mockStub.GetTxID(), ID)
+	assert.Equal(t, key, out.IDToKey[ID])
+	composite, err := db.GetCompositeTraintuple(key)
+	require.NoError(t, err)
+	assert.Equal(t, "composite/records2/1", composite.Metadata["template_task"])
+	assert.Equal(t, "1", composite.Metadata["round"])
+	// rounds alternate composite and aggregate ranks
+	assert.Equal(t, 2, composite.Rank)
+	assert.Equal(t, out.IDToKey[templateTaskID("aggregate/0")], composite.InTrunkModel)
+	assert.Equal(t, out.IDToKey[templateTaskID("composite/records2/0")], composite.InHeadModel)
+
+	assert.NotContains(t, out.IDToKey, templateTaskID("test/records1/0"))
+	assert.Contains(t, out.IDToKey, templateTaskID("test/records1/1"))
+}
@@ -15,6 +15,7 @@
 package main
 
 import (
+	"chaincode/errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -48,6 +49,7 @@ var (
 				DataSampleKeys: []string{testDataSampleKey1, testDataSampleKey2},
 				ObjectiveKey:   objectiveKey,
 				TraintupleID:   traintupleID2,
+				ID:             testtupleID,
 			},
 		},
 	}
@@ -151,6 +153,7 @@ var (
 				DataSampleKeys: []string{testDataSampleKey1, testDataSampleKey2},
 				ObjectiveKey:   objectiveKey,
 				TraintupleID:   "step_1_composite_A",
+				ID:             "step_1_testtuple_A",
 			},
 			{
 				Key:            computePlanTesttupleKey2,
@@ -158,6 +161,7 @@ var (
 				DataSampleKeys: []string{testDataSampleKey1, testDataSampleKey2},
 				ObjectiveKey:   objectiveKey,
 				TraintupleID:   "step_1_composite_B",
+				ID:             "step_1_testtuple_B",
 			},
 			{
 				Key:            computePlanTesttupleKey3,
@@ -165,6 +169,7 @@ var (
 				DataSampleKeys: []string{testDataSampleKey1, testDataSampleKey2},
 				ObjectiveKey:   objectiveKey,
 				TraintupleID:   "step_2_aggregate",
+				ID:             "step_2_testtuple",
 			},
 			{
 				Key:            computePlanTesttupleKey4,
@@ -172,6 +177,7 @@ var (
 				DataSampleKeys: []string{testDataSampleKey1, testDataSampleKey2},
 				ObjectiveKey:   objectiveKey,
 				TraintupleID:   "step_3_composite_A",
+				ID:             "step_3_testtuple_A",
 			},
 			{
 				Key:            computePlanTesttupleKey5,
@@ -179,6 +185,7 @@ var (
 				DataSampleKeys: []string{testDataSampleKey1, testDataSampleKey2},
 				ObjectiveKey:   objectiveKey,
 				TraintupleID:   "step_3_composite_B",
+				ID:             "step_3_testtuple_B",
 			},
 			{
 				Key:            computePlanTesttupleKey6,
@@ -186,6 +193,7 @@ var (
 				DataSampleKeys: []string{testDataSampleKey1, testDataSampleKey2},
 				ObjectiveKey:   objectiveKey,
 				TraintupleID:   "step_4_aggregate",
+				ID:             "step_4_testtuple",
 			},
 		},
 	}
@@ -210,7 +218,7 @@ func TestModelCompositionComputePlanWorkflow(t *testing.T) {
 	db := NewLedgerDB(mockStub)
 
 	// Create CP
-	out, err := createComputePlanInternal(db, modelCompositionComputePlan, tag, map[string]string{}, true)
+	out, err := createComputePlanInternal(db, modelCompositionComputePlan, tag, map[string]string{}, true, 0)
 	assert.NoError(t, err)
 	assert.NotNil(t, db.event)
 	assert.Len(t, db.event.CompositeTraintuples, 2)
@@ -281,8 +289,13 @@ func TestModelCompositionComputePlanWorkflow(t *testing.T) {
 	assert.Len(t, db.event.ComputePlans[0].ModelsToDelete, 1)
 	assert.Contains(t, db.event.ComputePlans[0].ModelsToDelete, step[2].Aggregate)
 
+	// The compute plan is done once compacted
 	testtupleToDone(t, db, out.TesttupleKeys[5])
-	assert.Len(t, db.event.ComputePlans, 1)
+	assert.Len(t, db.event.ComputePlans, 0)
+	_, err = compactComputePlan(db, assetToArgs(inputKey{Key: out.Key}))
+	assert.NoError(t, err)
+	require.Len(t, db.event.ComputePlans, 1)
+	assert.Equal(t, StatusDone, db.event.ComputePlans[0].Status)
 	assert.Len(t, db.event.ComputePlans[0].ModelsToDelete, 4)
 	assert.Contains(t, db.event.ComputePlans[0].ModelsToDelete, step[3].composite[0].Head)
 	assert.Contains(t, db.event.ComputePlans[0].ModelsToDelete, step[3].composite[0].Trunk)
@@ -355,7 +368,7 @@ func TestCreateComputePlanCompositeAggregate(t *testing.T) {
 		},
 	}
 
-	outCP, err := createComputePlanInternal(db, inCP, tag, map[string]string{}, false)
+	outCP, err := createComputePlanInternal(db, inCP, tag, map[string]string{}, false, 0)
 	assert.NoError(t, err)
 
 	// Check the composite traintuples
@@ -397,7 +410,7 @@ func TestCreateComputePlan(t *testing.T) {
 
 	// Simply test method and return values
 	inCP := defaultComputePlan
-	outCP, err := createComputePlanInternal(db, inCP, tag, map[string]string{}, false)
+	outCP, err := createComputePlanInternal(db, inCP, tag, map[string]string{}, false, 0)
 	assert.NoError(t, err)
 	validateDefaultComputePlan(t, outCP)
 
@@ -456,7 +469,7 @@ func TestQueryComputePlan(t *testing.T) {
 
 	// Simply test method and return values
 	inCP := defaultComputePlan
-	outCP, err := createComputePlanInternal(db, inCP, tag, map[string]string{}, false)
+	outCP, err := createComputePlanInternal(db, inCP, tag, map[string]string{}, false, 0)
 	assert.NoError(t, err)
 	assert.NotNil(t, outCP)
 
@@ -476,7 +489,7 @@ func TestQueryComputePlans(t *testing.T) {
 
 	// Simply test method and return values
 	inCP := defaultComputePlan
-	outCP, err := createComputePlanInternal(db, inCP, tag, map[string]string{}, false)
+	outCP, err := createComputePlanInternal(db, inCP, tag, map[string]string{}, false, 0)
 	assert.NoError(t, err)
 	assert.NotNil(t, outCP)
 
@@ -527,7 +540,7 @@ func TestComputePlanEmptyTesttuples(t *testing.T) {
 		Testtuples: []inputComputePlanTesttuple{},
 	}
 
-	outCP, err := createComputePlanInternal(db, inCP, tag, map[string]string{}, false)
+	outCP, err := createComputePlanInternal(db, inCP, tag, map[string]string{}, false, 0)
 	assert.NoError(t, err)
 	assert.NotNil(t, outCP)
 	assert.Len(t, outCP.TesttupleKeys, 0)
@@ -564,7 +577,7 @@ func TestCancelComputePlan(t *testing.T) {
 	mockStub.MockTransactionStart("42")
 	db := NewLedgerDB(mockStub)
 
-	out, err := createComputePlanInternal(db, defaultComputePlan, tag, map[string]string{}, false)
+	out, err := createComputePlanInternal(db, defaultComputePlan, tag, map[string]string{}, false, 0)
 	assert.NoError(t, err)
 
 	_, err = cancelComputePlan(db, assetToArgs(inputKey{Key: out.Key}))
@@ -603,7 +616,7 @@ func TestStartedTuplesOfCanceledComputePlan(t *testing.T) {
 	mockStub.MockTransactionStart("42")
 	db := NewLedgerDB(mockStub)
 
-	out, err := createComputePlanInternal(db, modelCompositionComputePlan, tag, map[string]string{}, false)
+	out, err := createComputePlanInternal(db, modelCompositionComputePlan, tag, map[string]string{}, false, 0)
 	assert.NoError(t, err)
 
 	logStartCompositeTrain(db, assetToArgs(inputKey{out.CompositeTraintupleKeys[0]}))
@@ -634,7 +647,7 @@ func TestLogSuccessAfterCancel(t *testing.T) {
 	mockStub.MockTransactionStart("42")
 	db := NewLedgerDB(mockStub)
 
-	out, err := createComputePlanInternal(db, modelCompositionComputePlan, tag, map[string]string{}, false)
+	out, err := createComputePlanInternal(db, modelCompositionComputePlan, tag, map[string]string{}, false, 0)
 	assert.NoError(t, err)
 
 	logStartCompositeTrain(db, assetToArgs(inputKey{out.CompositeTraintupleKeys[0]}))
@@ -690,7 +703,7 @@ func TestComputePlanMetrics(t *testing.T) {
 	mockStub.MockTransactionStart("42")
 	db := NewLedgerDB(mockStub)
 
-	out, err := createComputePlanInternal(db, defaultComputePlan, tag, map[string]string{}, false)
+	out, err := createComputePlanInternal(db, defaultComputePlan, tag, map[string]string{}, false, 0)
 	assert.NoError(t, err)
 	checkComputePlanMetrics(t, db, out.Key, 0, 3)
 
@@ -744,7 +757,7 @@ func TestUpdateComputePlan(t *testing.T) {
 	registerItem(t, *mockStub, "aggregateAlgo")
 	db := NewLedgerDB(mockStub)
 
-	out, err := createComputePlanInternal(db, inputComputePlan{Key: computePlanKey}, tag, map[string]string{}, false)
+	out, err := createComputePlanInternal(db, inputComputePlan{Key: computePlanKey}, tag, map[string]string{}, false, 0)
 	assert.NoError(t, err)
 	assert.Equal(t, tag, out.Tag)
 
@@ -798,7 +811,7 @@ func TestCreateSameComputePlanTwice(t *testing.T) {
 	registerItem(t, *mockStub, "aggregateAlgo")
 	db := NewLedgerDB(mockStub)
 
-	out, err := createComputePlanInternal(db, inputComputePlan{Key: computePlanKey}, tag, map[string]string{}, false)
+	out, err := createComputePlanInternal(db, inputComputePlan{Key: computePlanKey}, tag, map[string]string{}, false, 0)
 	assert.NoError(t, err)
 	assert.Equal(t, tag, out.Tag)
 
@@ -837,6 +850,7 @@ func TestCreateSameComputePlanTwice(t *testing.T) {
 				DataSampleKeys: []string{testDataSampleKey1},
 				ObjectiveKey:   objectiveKey,
 				TraintupleID:   "traintuple",
+				ID:             "testtuple",
 			},
 		},
 	}
@@ -844,7 +858,7 @@ func TestCreateSameComputePlanTwice(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Upload the same tuples inside another compute plan
-	out, err = createComputePlanInternal(db, inputComputePlan{Key: computePlanKey2}, tag, map[string]string{}, false)
+	out, err = createComputePlanInternal(db, inputComputePlan{Key: computePlanKey2}, tag, map[string]string{}, false, 0)
 	assert.NoError(t, err)
 	assert.Equal(t, tag, out.Tag)
 
@@ -934,3 +948,135 @@ func aggregateToDone(t *testing.T, mockStub *MockStub, worker string, db *Ledger
 
 	mockStub.Creator = workerA // reset worker to default
 }
+
+func TestComputePlanGeneratedKeys(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "aggregateAlgo")
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	inp := inputComputePlan{
+		Traintuples: []inputComputePlanTraintuple{defaultComputePlan.Traintuples[0], defaultComputePlan.Traintuples[1]},
+		Testtuples:  []inputComputePlanTesttuple{defaultComputePlan.Testtuples[0]},
+	}
+	inp.Traintuples[0].Key = ""
+	inp.Traintuples[1].Key = ""
+	inp.Testtuples[0].Key = ""
+	out, err := createComputePlanInternal(db, inp, tag, map[string]string{}, false, 0)
+	require.NoError(t, err)
+
+	// keys are derived from the transaction ID and the task IDs
+	assert.Equal(t, GenerateKey("42", "compute_plan"), out.Key)
+	assert.Equal(t, map[string]string{
+		traintupleID1: computePlanTaskKey("42", traintupleID1),
+		traintupleID2: computePlanTaskKey("42", traintupleID2),
+		testtupleID:   computePlanTaskKey("42", testtupleID),
+	}, out.IDToKey)
+	traintuple, err := db.GetTraintuple(out.IDToKey[traintupleID2])
+	require.NoError(t, err)
+	assert.Equal(t, []string{out.IDToKey[traintupleID1]}, traintuple.InModelKeys)
+	// the input keys are left untouched
+	assert.Equal(t, computePlanTraintupleKey1, defaultComputePlan.Traintuples[0].Key)
+	// the same task IDs get different keys in different transactions
+	mockStub.MockTransactionStart("43")
+	db = NewLedgerDB(mockStub)
+	other, err := createComputePlanInternal(db, inp, tag, map[string]string{}, false, 0)
+	require.NoError(t, err)
+	assert.NotEqual(t, out.Key, other.Key)
+	for ID, key := range out.IDToKey {
+		assert.NotEqual(t, key, other.IDToKey[ID])
+	}
+
+	_, err = updateComputePlan(db, assetToArgs(inputComputePlan{Testtuples: inp.Testtuples}))
+	assert.Error(t, err, "the compute plan to update must be given")
+}
+
+func TestComputePlanTesttupleIDs(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "aggregateAlgo")
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	out, err := createComputePlanInternal(db, defaultComputePlan, tag, map[string]string{}, false, 0)
+	require.NoError(t, err)
+	assert.Equal(t, computePlanTesttupleKey1, out.IDToKey[testtupleID])
+
+	// Errors are reported with the ID of the testtuple
+	up := inputComputePlan{
+		Key: computePlanKey,
+		Testtuples: []inputComputePlanTesttuple{{
+			Key:          RandomUUID(),
+			ID:           "unknownObjective",
+			ObjectiveKey: RandomUUID(),
+			TraintupleID: traintupleID1,
+		}},
+	}
+	_, err = updateComputePlanInternal(db, up)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "testtuple ID unknownObjective")
+
+	// Testtuple IDs must be unique among the tasks of the compute plan
+	up.Testtuples[0].ID = traintupleID2
+	up.Testtuples[0].ObjectiveKey = objectiveKey
+	_, err = updateComputePlanInternal(db, up)
+	assert.Error(t, err)
+	assert.Equal(t, 400, errors.Wrap(err).HTTPStatusCode())
+}
+
+func TestComputePlanTesttupleWithoutID(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "aggregateAlgo")
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	// The payloads sent before testtuples had IDs are still accepted
+	inp := defaultComputePlan
+	inp.Testtuples = []inputComputePlanTesttuple{defaultComputePlan.Testtuples[0]}
+	inp.Testtuples[0].ID = ""
+	inp.Testtuples = append(inp.Testtuples, inputComputePlanTesttuple{
+		ObjectiveKey: objectiveKey,
+		TraintupleID: traintupleID1,
+	})
+	out, err := createComputePlan(db, assetToArgs(inputNewComputePlan{inputComputePlan: inp}))
+	require.NoError(t, err)
+	assert.Equal(t, computePlanTesttupleKey1, out.IDToKey[computePlanTesttupleKey1])
+	generatedID := GenerateKey("42", "testtuple/1")
+	assert.Equal(t, computePlanTaskKey("42", generatedID), out.IDToKey[generatedID])
+	assert.Len(t, out.TesttupleKeys, 2)
+}
+
+func TestComputePlanTesttupleAsInModel(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "aggregateAlgo")
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	_, err := createComputePlanInternal(db, defaultComputePlan, tag, map[string]string{}, false, 0)
+	require.NoError(t, err)
+
+	// A testtuple of the compute plan, or one of the same update, can't be used as input model
+	for _, testtuples := range [][]inputComputePlanTesttuple{nil, {{ID: "newTest", ObjectiveKey: objectiveKey, TraintupleID: traintupleID1}}} {
+		testtupleIDs := []string{testtupleID}
+		if testtuples != nil {
+			testtupleIDs = []string{"newTest"}
+		}
+		_, err = updateComputePlanInternal(db, inputComputePlan{
+			Key: computePlanKey,
+			Traintuples: []inputComputePlanTraintuple{{
+				DataManagerKey: dataManagerKey,
+				DataSampleKeys: []string{trainDataSampleKey1},
+				AlgoKey:        algoKey,
+				ID:             "afterTest",
+				InModelsIDs:    testtupleIDs,
+			}},
+			Testtuples: testtuples,
+		})
+		assert.Error(t, err)
+		assert.Equal(t, 400, errors.Wrap(err).HTTPStatusCode())
+		assert.Contains(t, err.Error(), "uses the testtuple")
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// taskInput identifies a tuple in the input of a compute plan
+type taskInput struct {
+	taskType AssetType
+	index    int
+}
+
+// validateComputePlan runs the checks of createComputePlan, or of updateComputePlan when the compute
+// plan already exists, without writing anything in the ledger. Instead of failing on the first error,
+// it returns all the problems found for each task ID, along with the rank and worker of the valid ones.
+func validateComputePlan(db *LedgerDB, args []string) (resp outputComputePlanValidation, err error) {
+	inp := inputNewComputePlan{}
+	// The tuples are validated one by one so that a malformed one doesn't hide the others
+	v := &computePlanValidator{validator: newInputValidator(), inputErrors: map[taskInput][]string{}}
+	if err = assetFromJSONWithValidator(args, &inp, v); err != nil {
+		return resp, err
+	}
+	resp = outputComputePlanValidation{
+		Key:               inp.Key,
+		Errors:            v.errors,
+		Tasks:             []outputValidatedTask{},
+		WorkerTupleCounts: map[string]int{},
+	}
+	inputErrors := v.inputErrors
+	if len(resp.Errors) > 0 {
+		return resp, nil
+	}
+
+	if inp.Key == "" {
+		inp.Key = GenerateKey(db.cc.GetTxID(), ComputePlanType.String())
+		resp.Key = inp.Key
+	}
+	inp.generateKeys(db.cc.GetTxID())
+	// The tuples created in the dry run db are visible to the checks of the next tasks,
+	// including their indexes which LedgerDB keeps in the transaction state
+	dryRun := NewDryRunLedgerDB(db.cc)
+	exists, err := dryRun.KeyExists(inp.Key)
+	if err != nil {
+		return resp, err
+	}
+	if !exists {
+		var computePlan ComputePlan
+		computePlan.Creator, err = GetTxCreator(db.cc)
+		if err != nil {
+			return resp, err
+		}
+		if err = checkComputePlanQuota(dryRun, computePlan.Creator); err != nil {
+			resp.Errors = append(resp.Errors, err.Error())
+		}
+		computePlan.State.Status = StatusWaiting
+		computePlan.Priority = inp.Priority
+		if err = computePlan.Create(dryRun, inp.Key); err != nil {
+			resp.Errors = append(resp.Errors, err.Error())
+			return resp, nil
+		}
+	}
+	computePlan, err := dryRun.GetComputePlan(inp.Key)
+	if err != nil {
+		return resp, err
+	}
+	if exists {
+		if err = computePlan.checkCreator(dryRun, inp.Key); err != nil {
+			resp.Errors = append(resp.Errors, err.Error())
+		}
+	}
+	count := len(inp.Traintuples) +
+		len(inp.Aggregatetuples) +
+		len(inp.CompositeTraintuples) +
+		len(inp.Testtuples)
+	if err = computePlan.checkTuplesPerComputePlanQuota(dryRun, count); err != nil {
+		resp.Errors = append(resp.Errors, err.Error())
+	}
+	DAG, err := createComputeDAG(inp.inputComputePlan, computePlan.IDToTrainTask)
+	if err != nil {
+		resp.Errors = append(resp.Errors, err.Error())
+		return resp, nil
+	}
+
+	IDToTrainTask := map[string]TrainTask{}
+	for ID, trainTask := range computePlan.IDToTrainTask {
+		IDToTrainTask[ID] = trainTask
+	}
+	invalidIDs := map[string]bool{}
+	for _, task := range DAG.OrderTasks {
+		out := outputValidatedTask{
+			ID:     task.ID,
+			Type:   task.TaskType.String(),
+			Rank:   task.Depth,
+			Errors: inputErrors[taskInput{task.TaskType, task.InputIndex}],
+		}
+		for _, parentID := range task.InModelsIDs {
+			if invalidIDs[parentID] {
+				out.Errors = append(out.Errors, fmt.Sprintf("depends on invalid task ID %s", parentID))
+			}
+		}
+		if len(out.Errors) == 0 {
+			out.Worker, err = validateComputePlanTask(dryRun, inp.inputComputePlan, task, IDToTrainTask)
+			if err != nil {
+				out.Errors = append(out.Errors, err.Error())
+			}
+		}
+		if len(out.Errors) > 0 {
+			invalidIDs[task.ID] = true
+		} else {
+			resp.WorkerTupleCounts[out.Worker]++
+		}
+		resp.Tasks = append(resp.Tasks, out)
+	}
+	resp.Valid = len(resp.Errors) == 0 && len(invalidIDs) == 0
+	return resp, nil
+}
+
+// computePlanValidator checks the header and each tuple of a compute plan input separately.
+// It collects the problems instead of failing on the first one and never returns an error.
+type computePlanValidator struct {
+	validator   *validator.Validate
+	errors      []string
+	inputErrors map[taskInput][]string
+}
+
+// Struct validates an *inputNewComputePlan
+func (v *computePlanValidator) Struct(s interface{}) error {
+	inp := *s.(*inputNewComputePlan)
+	header := inp
+	header.Traintuples = nil
+	header.Aggregatetuples = nil
+	header.CompositeTraintuples = nil
+	header.Testtuples = nil
+	v.errors = validationErrors(v.validator.Struct(header))
+	for i, t := range inp.Traintuples {
+		v.inputErrors[taskInput{TraintupleType, i}] = validationErrors(v.validator.Struct(t))
+	}
+	for i, t := range inp.CompositeTraintuples {
+		v.inputErrors[taskInput{CompositeTraintupleType, i}] = validationErrors(v.validator.Struct(t))
+	}
+	for i, t := range inp.Aggregatetuples {
+		v.inputErrors[taskInput{AggregatetupleType, i}] = validationErrors(v.validator.Struct(t))
+	}
+	for i, t := range inp.Testtuples {
+		v.inputErrors[taskInput{TesttupleType, i}] = validationErrors(v.validator.Struct(t))
+	}
+	return nil
+}
+
+// validateComputePlanTask creates the tuple of the task in the dry run db and returns its worker
+func validateComputePlanTask(dryRun *LedgerDB, inp inputComputePlan, task TrainingTask, IDToTrainTask map[string]TrainTask) (string, error) {
+	tupleKey, err := createComputePlanTask(dryRun, inp, task, IDToTrainTask)
+	if err != nil {
+		return "", err
+	}
+	IDToTrainTask[task.ID] = TrainTask{Depth: task.Depth, Key: tupleKey, Testtuple: task.TaskType == TesttupleType}
+	_, worker, _, err := getSchedulingTask(dryRun, tupleKey)
+	return worker, err
+}
+
+// validationErrors lists the fields of an input which failed its validation
+func validationErrors(err error) []string {
+	problems := []string{}
+	if err == nil {
+		return problems
+	}
+	fieldErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return append(problems, err.Error())
+	}
+	for _, fieldError := range fieldErrors {
+		problems = append(problems, fmt.Sprintf("invalid field %s: failed on the '%s' validation", fieldError.Namespace(), fieldError.Tag()))
+	}
+	return problems
+}
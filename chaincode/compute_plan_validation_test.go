@@ -0,0 +1,116 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateComputePlan(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "aggregateAlgo")
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	inp := inputNewComputePlan{inputComputePlan: defaultComputePlan}
+	out, err := validateComputePlan(db, assetToArgs(inp))
+	require.NoError(t, err)
+	assert.True(t, out.Valid)
+	assert.Empty(t, out.Errors)
+	require.Len(t, out.Tasks, 3)
+	ranks := map[string]int{}
+	for _, task := range out.Tasks {
+		assert.Empty(t, task.Errors)
+		assert.Equal(t, workerA, task.Worker)
+		ranks[task.ID] = task.Rank
+	}
+	assert.Equal(t, map[string]int{traintupleID1: 0, traintupleID2: 1, testtupleID: 1}, ranks)
+	assert.Equal(t, map[string]int{workerA: 3}, out.WorkerTupleCounts)
+
+	// Nothing has been written
+	_, err = NewLedgerDB(mockStub).GetComputePlan(computePlanKey)
+	assert.Error(t, err)
+	_, err = NewLedgerDB(mockStub).GetTraintuple(computePlanTraintupleKey1)
+	assert.Error(t, err)
+
+	// All the problems are reported, per task ID
+	invalid := defaultComputePlan
+	invalid.Traintuples = []inputComputePlanTraintuple{
+		defaultComputePlan.Traintuples[0],
+		defaultComputePlan.Traintuples[1],
+	}
+	invalid.Traintuples[0].AlgoKey = RandomUUID()
+	invalid.Traintuples = append(invalid.Traintuples, inputComputePlanTraintuple{
+		Key:            RandomUUID(),
+		DataManagerKey: dataManagerKey,
+		AlgoKey:        algoKey,
+		ID:             "noDataSample",
+	})
+	inp = inputNewComputePlan{inputComputePlan: invalid}
+	out, err = validateComputePlan(db, assetToArgs(inp))
+	require.NoError(t, err)
+	assert.False(t, out.Valid)
+	require.Len(t, out.Tasks, 4)
+	problems := map[string][]string{}
+	for _, task := range out.Tasks {
+		problems[task.ID] = task.Errors
+	}
+	require.Len(t, problems[traintupleID1], 1)
+	assert.Contains(t, problems[traintupleID1][0], "traintuple ID "+traintupleID1)
+	assert.Equal(t, []string{"depends on invalid task ID " + traintupleID1}, problems[traintupleID2])
+	assert.Equal(t, []string{"depends on invalid task ID " + traintupleID2}, problems[testtupleID])
+	require.Len(t, problems["noDataSample"], 1)
+	assert.Contains(t, problems["noDataSample"][0], "DataSampleKeys")
+	assert.Empty(t, out.WorkerTupleCounts)
+
+	// An existing compute plan is validated as an update
+	_, err = createComputePlanInternal(db, defaultComputePlan, tag, map[string]string{}, false, 0)
+	require.NoError(t, err)
+	up := inputNewComputePlan{inputComputePlan: inputComputePlan{
+		Key: computePlanKey,
+		Traintuples: []inputComputePlanTraintuple{{
+			Key:            RandomUUID(),
+			DataManagerKey: dataManagerKey,
+			DataSampleKeys: []string{trainDataSampleKey1},
+			AlgoKey:        algoKey,
+			ID:             "child",
+			InModelsIDs:    []string{traintupleID2},
+		}},
+	}}
+	out, err = validateComputePlan(db, assetToArgs(up))
+	require.NoError(t, err)
+	assert.True(t, out.Valid, out)
+	require.Len(t, out.Tasks, 1)
+	assert.Equal(t, 2, out.Tasks[0].Rank)
+	cp, err := db.GetComputePlan(computePlanKey)
+	require.NoError(t, err)
+	assert.Len(t, cp.TraintupleKeys, 2)
+
+	// Only the creator of the compute plan can update it
+	mockStub.Creator = workerB
+	out, err = validateComputePlan(db, assetToArgs(up))
+	require.NoError(t, err)
+	assert.False(t, out.Valid)
+	require.Len(t, out.Errors, 1)
+	assert.Contains(t, out.Errors[0], "only "+workerA+" can update")
+	_, err = updateComputePlanInternal(db, up.inputComputePlan)
+	assert.Error(t, err)
+	assert.Equal(t, 403, errors.Wrap(err).HTTPStatusCode())
+}
@@ -48,8 +48,8 @@ func TryAddIntermediaryModel(db *LedgerDB, ComputePlanKey, worker, tupleKey, mod
 }
 
 // incrementWorkerTupleCount increases the total number of tuples for
-// a given compute plan and worker
-func (cp *ComputePlan) incrementWorkerTupleCount(db *LedgerDB, worker string) error {
+// a given compute plan and worker, and the count matching the status of the added tuple
+func (cp *ComputePlan) incrementWorkerTupleCount(db *LedgerDB, worker string, status string) error {
 
 	// Add the worker to the list of workers, if missing
 	found := false
@@ -63,44 +63,20 @@ func (cp *ComputePlan) incrementWorkerTupleCount(db *LedgerDB, worker string) er
 		cp.Workers = append(cp.Workers, worker)
 	}
 
-	// Create or update the done count
+	// Create or update the tuple count
 	wStateKey := cp.getCPWorkerStateKey(worker)
 	wState, err := db.GetCPWorkerState(wStateKey)
 	if err != nil {
-		return db.Add(wStateKey, ComputePlanWorkerState{TupleCount: 1})
+		wState = &ComputePlanWorkerState{TupleCount: 1}
+		wState.addCount(status, 1)
+		return db.Add(wStateKey, wState)
 	}
 
+	wState.addCount(status, 1)
 	wState.TupleCount++
 	return db.Put(wStateKey, wState)
 }
 
-// incrementWorkerDoneCount increases the count of tuples in the "done" state for
-// a given compute plan and worker
-func (cp *ComputePlan) incrementWorkerDoneCount(db *LedgerDB, worker string) error {
-	wStateKey := cp.getCPWorkerStateKey(worker)
-	wState, err := db.GetCPWorkerState(wStateKey)
-	if err != nil {
-		return nil
-	}
-	wState.DoneCount++
-	return db.Put(wStateKey, wState)
-}
-
-// getTupleCounts returns the number of tuples in the "done" state and the total number of tuples
-// for a given compute plan
-func (cp *ComputePlan) getTupleCounts(db *LedgerDB) (doneCount int, tupleCount int, err error) {
-	for _, worker := range cp.Workers {
-		wStateKey := cp.getCPWorkerStateKey(worker)
-		wState, err := db.GetCPWorkerState(wStateKey)
-		if err != nil {
-			return doneCount, tupleCount, err
-		}
-		doneCount += wState.DoneCount
-		tupleCount += wState.TupleCount
-	}
-	return doneCount, tupleCount, nil
-}
-
 // getCPWorkerStateKey returns the worker state key for a given compute plan and worker
 func (cp *ComputePlan) getCPWorkerStateKey(worker string) string {
 	return fmt.Sprintf("computePlan~%v~stateByWorker~%v", cp.Key, worker)
@@ -0,0 +1,133 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+)
+
+// List of the possible condition operators, comparing the perf of the testtuple to the condition value
+const (
+	ConditionLower          = "lt"
+	ConditionLowerOrEqual   = "lte"
+	ConditionGreater        = "gt"
+	ConditionGreaterOrEqual = "gte"
+)
+
+// isSatisfied returns whether the perf of the testtuple satisfies the condition
+func (condition *Condition) isSatisfied(perf float32) bool {
+	switch condition.Operator {
+	case ConditionLower:
+		return perf < condition.Value
+	case ConditionLowerOrEqual:
+		return perf <= condition.Value
+	case ConditionGreater:
+		return perf > condition.Value
+	case ConditionGreaterOrEqual:
+		return perf >= condition.Value
+	}
+	return false
+}
+
+// SetCondition makes the traintuple wait for the perf of a testtuple of its compute plan.
+// It must be called once the status of the traintuple is set from its parents.
+func (traintuple *Traintuple) SetCondition(db *LedgerDB, inp *inputCondition) error {
+	if inp == nil {
+		return nil
+	}
+	testtuple, err := db.GetTesttuple(inp.TesttupleKey)
+	if err != nil {
+		return errors.BadRequest(err, "could not retrieve testtuple with key %s", inp.TesttupleKey)
+	}
+	if testtuple.ComputePlanKey != traintuple.ComputePlanKey {
+		return errors.BadRequest("testtuple %s of the condition does not belong to the compute plan of the traintuple", inp.TesttupleKey)
+	}
+	condition := Condition{
+		TesttupleKey: inp.TesttupleKey,
+		Operator:     inp.Operator,
+		Value:        inp.Value,
+	}
+	switch testtuple.Status {
+	case StatusDone:
+		if !condition.isSatisfied(testtuple.Dataset.Perf) {
+			return errors.BadRequest("could not register this traintuple, the perf %v of testtuple %s does not satisfy its condition", testtuple.Dataset.Perf, inp.TesttupleKey)
+		}
+	case StatusFailed, StatusAborted:
+		return errors.BadRequest("could not register this traintuple, the testtuple %s of its condition has a status %s", inp.TesttupleKey, testtuple.Status)
+	default:
+		if traintuple.Status == StatusTodo {
+			traintuple.Status = StatusWaiting
+		}
+	}
+	traintuple.Condition = &condition
+	return nil
+}
+
+// UpdateConditionalChildren updates the traintuples waiting for the perf of a done or failed testtuple.
+// A traintuple whose condition is satisfied becomes todo once its parents are done,
+// otherwise it is canceled along with the tuples depending on it.
+func UpdateConditionalChildren(db *LedgerDB, testtupleKey string, testtupleStatus string, perf float32) error {
+	if !stringInSlice(testtupleStatus, []string{StatusDone, StatusFailed}) {
+		return nil
+	}
+	childKeys, err := db.GetIndexKeys("tuple~condition~key", []string{"tuple", testtupleKey})
+	if err != nil {
+		return err
+	}
+	for _, childKey := range childKeys {
+		child, err := db.GetTraintuple(childKey)
+		if err != nil {
+			return err
+		}
+		if child.Status != StatusWaiting {
+			continue
+		}
+
+		var newStatus string
+		switch {
+		case testtupleStatus == StatusFailed:
+			newStatus = StatusFailed
+		case !child.Condition.isSatisfied(perf):
+			newStatus = StatusAborted
+		default:
+			ready, err := child.isReady(db, "")
+			if err != nil {
+				return err
+			}
+			if ready {
+				newStatus = StatusTodo
+			}
+		}
+		if newStatus == "" {
+			continue
+		}
+		if err = child.commitStatusUpdate(db, childKey, newStatus); err != nil {
+			return err
+		}
+		if err = db.AddTupleEvent(childKey); err != nil {
+			return err
+		}
+		if newStatus == StatusTodo {
+			continue
+		}
+		if err = UpdateTesttupleChildren(db, childKey, newStatus); err != nil {
+			return err
+		}
+		if err = UpdateTraintupleChildren(db, childKey, newStatus, []string{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
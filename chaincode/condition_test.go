@@ -0,0 +1,181 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConditionalTraintuples(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "aggregateAlgo")
+
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	_, err := createComputePlanInternal(db, defaultComputePlan, tag, map[string]string{}, false, 0)
+	require.NoError(t, err)
+
+	// The testtuple of the default compute plan will have a perf of 0.9
+	skippedKey, skippedChildKey, conditionalKey := RandomUUID(), RandomUUID(), RandomUUID()
+	_, err = updateComputePlanInternal(db, inputComputePlan{
+		Key: computePlanKey,
+		Traintuples: []inputComputePlanTraintuple{
+			{
+				Key:            skippedKey,
+				DataManagerKey: dataManagerKey,
+				DataSampleKeys: []string{trainDataSampleKey1},
+				AlgoKey:        algoKey,
+				ID:             "skipped",
+				InModelsIDs:    []string{traintupleID2},
+				Condition:      &inputComputePlanCondition{TesttupleID: testtupleID, Operator: ConditionLower, Value: 0.5},
+			},
+			{
+				Key:            skippedChildKey,
+				DataManagerKey: dataManagerKey,
+				DataSampleKeys: []string{trainDataSampleKey1},
+				AlgoKey:        algoKey,
+				ID:             "skippedChild",
+				InModelsIDs:    []string{"skipped"},
+			},
+			{
+				Key:            conditionalKey,
+				DataManagerKey: dataManagerKey,
+				DataSampleKeys: []string{trainDataSampleKey2},
+				AlgoKey:        algoKey,
+				ID:             "conditional",
+				InModelsIDs:    []string{traintupleID2},
+				Condition:      &inputComputePlanCondition{TesttupleID: testtupleID, Operator: ConditionGreaterOrEqual, Value: 0.5},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	traintupleToDone(t, db, computePlanTraintupleKey1)
+	traintupleToDone(t, db, computePlanTraintupleKey2)
+	traintuple, err := queryTraintuple(db, keyToArgs(conditionalKey))
+	assert.NoError(t, err)
+	assert.Equal(t, StatusWaiting, traintuple.Status, "a conditional traintuple should wait for its testtuple")
+
+	testtupleToDone(t, db, computePlanTesttupleKey1)
+	for key, status := range map[string]string{skippedKey: StatusAborted, skippedChildKey: StatusAborted, conditionalKey: StatusTodo} {
+		traintuple, err = queryTraintuple(db, keyToArgs(key))
+		assert.NoError(t, err)
+		assert.Equal(t, status, traintuple.Status)
+	}
+
+	// Canceled tuples are settled, the compute plan is done once the conditional traintuple is
+	traintupleToDone(t, db, conditionalKey)
+	checkComputePlanMetrics(t, db, computePlanKey, 6, 6)
+	cp, err := queryComputePlan(db, keyToArgs(computePlanKey))
+	assert.NoError(t, err)
+	assert.Equal(t, StatusDone, cp.Status)
+
+	// Tuples added to the done compute plan after a canceled one are settled
+	_, err = updateComputePlanInternal(db, inputComputePlan{
+		Key: computePlanKey,
+		Traintuples: []inputComputePlanTraintuple{{
+			Key:            RandomUUID(),
+			DataManagerKey: dataManagerKey,
+			DataSampleKeys: []string{trainDataSampleKey1},
+			AlgoKey:        algoKey,
+			ID:             "afterSkipped",
+			InModelsIDs:    []string{"skipped"},
+		}},
+	})
+	assert.NoError(t, err)
+	checkComputePlanMetrics(t, db, computePlanKey, 7, 7)
+
+	// A condition which is already not satisfied is rejected
+	_, err = updateComputePlanInternal(db, inputComputePlan{
+		Key: computePlanKey,
+		Traintuples: []inputComputePlanTraintuple{{
+			Key:            RandomUUID(),
+			DataManagerKey: dataManagerKey,
+			DataSampleKeys: []string{trainDataSampleKey1},
+			AlgoKey:        algoKey,
+			ID:             "late",
+			Condition:      &inputComputePlanCondition{TesttupleID: testtupleID, Operator: ConditionGreater, Value: 0.95},
+		}},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 400, errors.Wrap(err).HTTPStatusCode())
+}
+
+func TestConditionalTraintuplesFailedTesttuple(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "aggregateAlgo")
+
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	_, err := createComputePlanInternal(db, defaultComputePlan, tag, map[string]string{}, false, 0)
+	require.NoError(t, err)
+
+	conditionalKey := RandomUUID()
+	_, err = updateComputePlanInternal(db, inputComputePlan{
+		Key: computePlanKey,
+		Traintuples: []inputComputePlanTraintuple{{
+			Key:            conditionalKey,
+			DataManagerKey: dataManagerKey,
+			DataSampleKeys: []string{trainDataSampleKey2},
+			AlgoKey:        algoKey,
+			ID:             "conditional",
+			InModelsIDs:    []string{traintupleID2},
+			Condition:      &inputComputePlanCondition{TesttupleID: testtupleID, Operator: ConditionGreaterOrEqual, Value: 0.5},
+		}},
+	})
+	require.NoError(t, err)
+
+	traintupleToDone(t, db, computePlanTraintupleKey1)
+	traintupleToDone(t, db, computePlanTraintupleKey2)
+	_, err = logStartTest(db, assetToArgs(inputKey{Key: computePlanTesttupleKey1}))
+	require.NoError(t, err)
+	_, err = logFailTest(db, assetToArgs(inputLogFailTest{inputLog{Key: computePlanTesttupleKey1}}))
+	require.NoError(t, err)
+
+	// The conditional traintuple does not wait forever, it is canceled with its compute plan
+	traintuple, err := queryTraintuple(db, keyToArgs(conditionalKey))
+	assert.NoError(t, err)
+	assert.Equal(t, StatusAborted, traintuple.Status)
+}
+
+func TestConditionOnlyOnTraintuples(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "aggregateAlgo")
+
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	inp := modelCompositionComputePlan
+	inp.CompositeTraintuples = append([]inputComputePlanCompositeTraintuple{}, inp.CompositeTraintuples...)
+	inp.CompositeTraintuples[0].Condition = &inputComputePlanCondition{TesttupleID: testtupleID, Operator: ConditionGreater, Value: 0.5}
+	_, err := createComputePlan(db, assetToArgs(inp))
+	assert.Error(t, err)
+	assert.Equal(t, 400, errors.Wrap(err).HTTPStatusCode())
+
+	inpAggregatetuple := inputAggregatetuple{Condition: &inputCondition{TesttupleKey: testtupleKey, Operator: ConditionGreater}}
+	inpAggregatetuple.createDefault()
+	_, err = createAggregatetuple(db, assetToArgs(inpAggregatetuple))
+	assert.Error(t, err)
+	assert.Equal(t, 400, errors.Wrap(err).HTTPStatusCode())
+}
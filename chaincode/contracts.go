@@ -0,0 +1,196 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"reflect"
+	"sort"
+)
+
+// contract describes a function of the chaincode
+type contract struct {
+	Name string
+	// Handler is the function called with the LedgerDB and the args of the transaction.
+	// Its signature is func(*LedgerDB, []string) (output, error), or (output, bookmark, error)
+	// for the paginated contracts, the type of the output being the one of the response.
+	Handler interface{}
+	// Input is the struct decoded from the JSON argument, nil for the contracts without argument
+	Input interface{}
+	// ReadOnly contracts never write in the ledger
+	ReadOnly bool
+	// Paginated contracts return their results along with a bookmark
+	Paginated bool
+}
+
+// contracts is the registry of the functions of the chaincode, by name
+var contracts = map[string]contract{}
+
+func init() {
+	for _, c := range []contract{
+		{Name: "approveProposal", Handler: approveProposal, Input: inputKey{}},
+		{Name: "cancelComputePlan", Handler: cancelComputePlan, Input: inputKey{}},
+		{Name: "compactComputePlan", Handler: compactComputePlan, Input: inputKey{}},
+		{Name: "createAggregatetuple", Handler: createAggregatetuple, Input: inputAggregatetuple{}},
+		{Name: "createCompositeTraintuple", Handler: createCompositeTraintuple, Input: inputCompositeTraintuple{}},
+		{Name: "createComputePlan", Handler: createComputePlan, Input: inputNewComputePlan{}},
+		{Name: "createProposal", Handler: createProposal, Input: inputProposal{}},
+		{Name: "createTesttuple", Handler: createTesttuple, Input: inputTesttuple{}},
+		{Name: "createTraintuple", Handler: createTraintuple, Input: inputTraintuple{}},
+		{Name: "deprecateAlgo", Handler: deprecateAlgo, Input: inputKey{}},
+		{Name: "executeProposal", Handler: executeProposal, Input: inputKey{}},
+		{Name: "instantiateComputePlan", Handler: instantiateComputePlan, Input: inputInstantiateComputePlan{}},
+		{Name: "logFailAggregate", Handler: logFailAggregate, Input: inputLogFailTrain{}},
+		{Name: "logFailCompositeTrain", Handler: logFailCompositeTrain, Input: inputLogFailTrain{}},
+		{Name: "logFailTest", Handler: logFailTest, Input: inputLogFailTest{}},
+		{Name: "logFailTrain", Handler: logFailTrain, Input: inputLogFailTrain{}},
+		{Name: "logStartAggregate", Handler: logStartAggregate, Input: inputKey{}},
+		{Name: "logStartCompositeTrain", Handler: logStartCompositeTrain, Input: inputKey{}},
+		{Name: "logStartTest", Handler: logStartTest, Input: inputKey{}},
+		{Name: "logStartTrain", Handler: logStartTrain, Input: inputKey{}},
+		{Name: "logSuccessAggregate", Handler: logSuccessAggregate, Input: inputLogSuccessTrain{}},
+		{Name: "logSuccessCompositeTrain", Handler: logSuccessCompositeTrain, Input: inputLogSuccessCompositeTrain{}},
+		{Name: "logSuccessTest", Handler: logSuccessTest, Input: inputLogSuccessTest{}},
+		{Name: "logSuccessTrain", Handler: logSuccessTrain, Input: inputLogSuccessTrain{}},
+		{Name: "migrateSchedulingIndex", Handler: migrateSchedulingIndex, Input: inputWorker{}},
+		{Name: "queryAggregateAlgo", Handler: queryAggregateAlgo, Input: inputKey{}, ReadOnly: true},
+		{Name: "queryAggregateAlgos", Handler: queryAggregateAlgos, Input: inputBookmark{}, ReadOnly: true, Paginated: true},
+		{Name: "queryAggregatetuple", Handler: queryAggregatetuple, Input: inputKey{}, ReadOnly: true},
+		{Name: "queryAggregatetuples", Handler: queryAggregatetuples, Input: inputBookmark{}, ReadOnly: true, Paginated: true},
+		{Name: "queryAlgo", Handler: queryAlgo, Input: inputKey{}, ReadOnly: true},
+		{Name: "queryAlgoVersions", Handler: queryAlgoVersions, Input: inputKey{}, ReadOnly: true},
+		{Name: "queryAlgos", Handler: queryAlgos, Input: inputBookmark{}, ReadOnly: true, Paginated: true},
+		{Name: "queryCompositeAlgo", Handler: queryCompositeAlgo, Input: inputKey{}, ReadOnly: true},
+		{Name: "queryCompositeAlgos", Handler: queryCompositeAlgos, Input: inputBookmark{}, ReadOnly: true, Paginated: true},
+		{Name: "queryCompositeTraintuple", Handler: queryCompositeTraintuple, Input: inputKey{}, ReadOnly: true},
+		{Name: "queryCompositeTraintuples", Handler: queryCompositeTraintuples, Input: inputBookmark{}, ReadOnly: true, Paginated: true},
+		{Name: "queryComputePlan", Handler: queryComputePlan, Input: inputKey{}, ReadOnly: true},
+		{Name: "queryComputePlanTemplate", Handler: queryComputePlanTemplate, Input: inputKey{}, ReadOnly: true},
+		{Name: "queryComputePlanTemplates", Handler: queryComputePlanTemplates, ReadOnly: true},
+		{Name: "queryComputePlans", Handler: queryComputePlans, Input: inputBookmark{}, ReadOnly: true, Paginated: true},
+		{Name: "queryContractMetadata", Handler: queryContractMetadata, ReadOnly: true},
+		{Name: "queryDataManager", Handler: queryDataManager, Input: inputKey{}, ReadOnly: true},
+		{Name: "queryDataManagers", Handler: queryDataManagers, Input: inputBookmark{}, ReadOnly: true, Paginated: true},
+		{Name: "queryDataSamples", Handler: queryDataSamples, Input: inputBookmark{}, ReadOnly: true, Paginated: true},
+		{Name: "queryDataset", Handler: queryDataset, Input: inputKey{}, ReadOnly: true},
+		{Name: "queryFilter", Handler: queryFilter, Input: inputQueryFilter{}, ReadOnly: true},
+		{Name: "queryModel", Handler: queryModel, Input: inputKey{}, ReadOnly: true},
+		{Name: "queryModelDetails", Handler: queryModelDetails, Input: inputKey{}, ReadOnly: true},
+		{Name: "queryModels", Handler: queryModels, Input: inputQueryModelsBookmarks{}, ReadOnly: true, Paginated: true},
+		{Name: "queryNextTasks", Handler: queryNextTasks, Input: inputNextTasks{}, ReadOnly: true},
+		{Name: "queryNodes", Handler: queryNodes, ReadOnly: true},
+		{Name: "queryObjective", Handler: queryObjective, Input: inputKey{}, ReadOnly: true},
+		{Name: "queryObjectiveLeaderboard", Handler: queryObjectiveLeaderboard, Input: inputLeaderboard{}, ReadOnly: true},
+		{Name: "queryObjectives", Handler: queryObjectives, Input: inputBookmark{}, ReadOnly: true, Paginated: true},
+		{Name: "queryPrivacyBudget", Handler: queryPrivacyBudget, Input: inputKey{}, ReadOnly: true},
+		{Name: "queryProposals", Handler: queryProposals, ReadOnly: true},
+		{Name: "queryQuotaUsage", Handler: queryQuotaUsage, Input: inputQuotaUsage{}, ReadOnly: true},
+		{Name: "queryTesttuple", Handler: queryTesttuple, Input: inputKey{}, ReadOnly: true},
+		{Name: "queryTesttuples", Handler: queryTesttuples, Input: inputBookmark{}, ReadOnly: true, Paginated: true},
+		{Name: "queryTraintuple", Handler: queryTraintuple, Input: inputKey{}, ReadOnly: true},
+		{Name: "queryTraintuples", Handler: queryTraintuples, Input: inputBookmark{}, ReadOnly: true, Paginated: true},
+		{Name: "registerAggregateAlgo", Handler: registerAggregateAlgo, Input: inputAggregateAlgo{}},
+		{Name: "registerAlgo", Handler: registerAlgo, Input: inputAlgo{}},
+		{Name: "registerCompositeAlgo", Handler: registerCompositeAlgo, Input: inputCompositeAlgo{}},
+		{Name: "registerComputePlanTemplate", Handler: registerComputePlanTemplate, Input: inputComputePlanTemplate{}},
+		{Name: "registerDataManager", Handler: registerDataManager, Input: inputDataManager{}},
+		{Name: "registerDataSample", Handler: registerDataSample, Input: inputDataSample{}},
+		{Name: "registerNode", Handler: registerNode, Input: inputNode{}},
+		{Name: "registerObjective", Handler: registerObjective, Input: inputObjective{}},
+		{Name: "revokeDataSamples", Handler: revokeDataSamples, Input: inputRevokeDataSamples{}},
+		{Name: "updateComputePlan", Handler: updateComputePlan, Input: inputComputePlan{}},
+		{Name: "updateComputePlanPriority", Handler: updateComputePlanPriority, Input: inputComputePlanPriority{}},
+		{Name: "updateDataManager", Handler: updateDataManager, Input: inputUpdateDataManager{}},
+		{Name: "updateDataSample", Handler: updateDataSample, Input: inputUpdateDataSample{}},
+		{Name: "updateObjectiveTestDataset", Handler: updateObjectiveTestDataset, Input: inputUpdateObjectiveTestDataset{}},
+		{Name: "validateComputePlan", Handler: validateComputePlan, Input: inputNewComputePlan{}, ReadOnly: true},
+	} {
+		contracts[c.Name] = c
+	}
+}
+
+// invoke calls the handler of the contract
+func (c contract) invoke(db *LedgerDB, args []string) (result interface{}, bookmark string, err error) {
+	out := reflect.ValueOf(c.Handler).Call([]reflect.Value{reflect.ValueOf(db), reflect.ValueOf(args)})
+	result = out[0].Interface()
+	if c.Paginated {
+		bookmark = out[1].String()
+	}
+	if e := out[len(out)-1].Interface(); e != nil {
+		err = e.(error)
+	}
+	return
+}
+
+// outputType returns the type of the response of the contract, before its pagination
+func (c contract) outputType() reflect.Type {
+	return reflect.TypeOf(c.Handler).Out(0)
+}
+
+type outputContractMetadata struct {
+	Name      string                 `json:"name"`
+	ReadOnly  bool                   `json:"read_only"`
+	Paginated bool                   `json:"paginated"`
+	Roles     []string               `json:"roles"`
+	Input     map[string]interface{} `json:"input"`
+	Output    map[string]interface{} `json:"output"`
+}
+
+// Fill returns the metadata of a contract, with the JSON Schemas of its input and output
+func (out *outputContractMetadata) Fill(c contract) {
+	out.Name = c.Name
+	out.ReadOnly = c.ReadOnly
+	out.Paginated = c.Paginated
+	out.Roles = contractPolicies[c.Name]
+	if out.Roles == nil {
+		out.Roles = []string{}
+	}
+	if c.Input != nil {
+		out.Input = newJSONSchema(reflect.TypeOf(c.Input))
+	}
+	output := typeSchema(c.outputType(), nil, map[reflect.Type]bool{})
+	if c.Paginated {
+		output = map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"results":  output,
+				"bookmark": map[string]interface{}{"type": "string"},
+			},
+		}
+	}
+	output["$schema"] = jsonSchemaVersion
+	out.Output = output
+}
+
+// queryContractMetadata returns the metadata of all the contracts of the chaincode, sorted by name,
+// so that the SDKs and the documentation can be generated from the chaincode itself
+func queryContractMetadata(db *LedgerDB, args []string) (out []outputContractMetadata, err error) {
+	out = []outputContractMetadata{}
+	if len(args) != 0 {
+		err = errors.BadRequest("incorrect number of arguments, expecting nothing")
+		return
+	}
+	names := []string{}
+	for name := range contracts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		metadata := outputContractMetadata{}
+		metadata.Fill(contracts[name])
+		out = append(out, metadata)
+	}
+	return out, nil
+}
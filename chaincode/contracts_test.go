@@ -0,0 +1,211 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContractRegistry(t *testing.T) {
+	dbType, argsType := reflect.TypeOf(&LedgerDB{}), reflect.TypeOf([]string{})
+	errorType := reflect.TypeOf((*error)(nil)).Elem()
+	for name, c := range contracts {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, name, c.Name)
+			handler := reflect.TypeOf(c.Handler)
+			require.Equal(t, reflect.Func, handler.Kind())
+			require.Equal(t, 2, handler.NumIn())
+			assert.Equal(t, dbType, handler.In(0))
+			assert.Equal(t, argsType, handler.In(1))
+			if c.Paginated {
+				require.Equal(t, 3, handler.NumOut())
+				assert.Equal(t, reflect.String, handler.Out(1).Kind())
+			} else {
+				require.Equal(t, 2, handler.NumOut())
+			}
+			assert.Equal(t, errorType, handler.Out(handler.NumOut()-1))
+			if c.Input != nil {
+				assert.Equal(t, reflect.Struct, reflect.TypeOf(c.Input).Kind())
+			}
+			if strings.HasPrefix(name, "query") {
+				assert.True(t, c.ReadOnly, "queries must be read-only")
+			}
+			_, ok := contractPolicies[name]
+			assert.NotEqual(t, c.ReadOnly, ok, "the write contracts, and only them, must have a policy")
+		})
+	}
+	for name := range contractPolicies {
+		assert.Contains(t, contracts, name)
+	}
+}
+
+func TestReadmeListsContracts(t *testing.T) {
+	readme, err := ioutil.ReadFile("../README.md")
+	require.NoError(t, err)
+	section := strings.SplitN(string(readme), "### Implemented smart contracts", 2)[1]
+	section = strings.SplitN(section, "###", 2)[0]
+	listed := []string{}
+	for _, line := range strings.Split(section, "\n") {
+		if strings.HasPrefix(line, "- `") {
+			listed = append(listed, strings.Trim(strings.TrimPrefix(line, "- "), "`"))
+		}
+	}
+	names := []string{}
+	for name := range contracts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	assert.Equal(t, names, listed)
+}
+
+func TestQueryContractMetadata(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	resp := mockStub.MockInvoke([][]byte{[]byte("queryContractMetadata")})
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	var metadata []outputContractMetadata
+	require.NoError(t, json.Unmarshal(resp.Payload, &metadata))
+	require.Len(t, metadata, len(contracts))
+
+	byName := map[string]outputContractMetadata{}
+	for _, m := range metadata {
+		byName[m.Name] = m
+	}
+
+	cancel := byName["cancelComputePlan"]
+	assert.False(t, cancel.ReadOnly)
+	assert.Equal(t, contractPolicies["cancelComputePlan"], cancel.Roles)
+	assert.JSONEq(t, `{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"type": "object",
+		"properties": {"key": {"type": "string", "minLength": 36, "maxLength": 36}},
+		"required": ["key"]
+	}`, toJSONString(t, cancel.Input))
+
+	algos := byName["queryAlgos"]
+	assert.True(t, algos.ReadOnly)
+	assert.True(t, algos.Paginated)
+	assert.Equal(t, []string{}, algos.Roles)
+	properties := algos.Output["properties"].(map[string]interface{})
+	assert.Equal(t, "array", properties["results"].(map[string]interface{})["type"])
+	assert.Contains(t, properties, "bookmark")
+
+	assert.Nil(t, byName["queryNodes"].Input)
+}
+
+func TestJSONSchema(t *testing.T) {
+	type embedded struct {
+		Rank int `validate:"gte=0,lt=10" json:"rank"`
+	}
+	type input struct {
+		embedded
+		Checksum  string            `validate:"required,len=64,hexadecimal" json:"checksum"`
+		Keys      []string          `validate:"required,unique,gt=0,dive,len=36" json:"keys"`
+		Metadata  map[string]string `validate:"lte=100,dive,keys,lte=50,endkeys,lte=100" json:"metadata"`
+		Type      string            `validate:"required,oneof=addNode removeNode" json:"type"`
+		Worker    string            `validate:"required_with=Rank" json:"worker"`
+		Address   string            `validate:"omitempty,url" json:"address"`
+		Child     *input            `json:"child"`
+		unexposed string
+	}
+
+	assert.JSONEq(t, `{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"type": "object",
+		"properties": {
+			"rank": {"type": "integer", "minimum": 0, "exclusiveMaximum": 10},
+			"checksum": {"type": "string", "minLength": 64, "maxLength": 64, "pattern": "^(0[xX])?[0-9a-fA-F]+$"},
+			"keys": {"type": "array", "minItems": 1, "uniqueItems": true, "items": {"type": "string", "minLength": 36, "maxLength": 36}},
+			"metadata": {
+				"type": "object",
+				"maxProperties": 100,
+				"propertyNames": {"type": "string", "maxLength": 50},
+				"additionalProperties": {"type": "string", "maxLength": 100}
+			},
+			"type": {"type": "string", "enum": ["addNode", "removeNode"]},
+			"worker": {"type": "string"},
+			"address": {"type": "string", "format": "uri"},
+			"child": {"type": "object"}
+		},
+		"required": ["checksum", "keys", "type"],
+		"dependencies": {"rank": ["worker"]}
+	}`, toJSONString(t, newJSONSchema(reflect.TypeOf(input{}))))
+}
+
+func toJSONString(t *testing.T, value interface{}) string {
+	buff, err := json.Marshal(value)
+	require.NoError(t, err)
+	return string(buff)
+}
+
+func TestReadOnlyContracts(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "aggregatetuple")
+	inpCP := inputNewComputePlan{inputComputePlan: defaultComputePlan}
+	resp := mockStub.MockInvoke(methodAndAssetToByte("createComputePlan", inpCP))
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	var cp outputComputePlan
+	require.NoError(t, json.Unmarshal(resp.Payload, &cp))
+
+	args := map[string][]string{
+		"queryAggregateAlgo":        keyToArgs(aggregateAlgoKey),
+		"queryAggregatetuple":       keyToArgs(aggregatetupleKey),
+		"queryAlgo":                 keyToArgs(algoKey),
+		"queryAlgoVersions":         keyToArgs(algoKey),
+		"queryCompositeAlgo":        keyToArgs(compositeAlgoKey),
+		"queryCompositeTraintuple":  keyToArgs(compositeTraintupleKey),
+		"queryComputePlan":          keyToArgs(cp.Key),
+		"queryComputePlanTemplate":  keyToArgs(cp.Key),
+		"queryDataManager":          keyToArgs(dataManagerKey),
+		"queryDataset":              keyToArgs(dataManagerKey),
+		"queryFilter":               assetToArgs(inputQueryFilter{IndexName: "traintuple~worker~status", Attributes: workerA + ", todo"}),
+		"queryModel":                keyToArgs(traintupleKey),
+		"queryModelDetails":         keyToArgs(traintupleKey),
+		"queryNextTasks":            assetToArgs(inputNextTasks{Worker: workerA, N: 10}),
+		"queryObjective":            keyToArgs(objectiveKey),
+		"queryObjectiveLeaderboard": assetToArgs(inputLeaderboard{ObjectiveKey: objectiveKey, AscendingOrder: true}),
+		"queryPrivacyBudget":        keyToArgs(dataManagerKey),
+		"queryTesttuple":            keyToArgs(cp.TesttupleKeys[0]),
+		"queryTraintuple":           keyToArgs(traintupleKey),
+		"validateComputePlan":       assetToArgs(inpCP),
+	}
+	for name, c := range contracts {
+		if !c.ReadOnly {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			mockStub.MockTransactionStart("42")
+			defer mockStub.MockTransactionEnd("42")
+			db := NewReadOnlyLedgerDB(mockStub)
+			_, _, err := c.invoke(db, args[name])
+			if err != nil {
+				// the contract may not find what it looks for, but it must not try to write
+				assert.NotEqual(t, 500, errors.Wrap(err).HTTPStatusCode(), err.Error())
+			}
+			assert.Equal(t, 0, db.Stats().Writes)
+			assert.Nil(t, db.event)
+		})
+	}
+}
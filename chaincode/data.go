@@ -33,15 +33,21 @@ func (dataManager *DataManager) Set(db *LedgerDB, inp inputDataManager) (string,
 	}
 	dataManager.Type = inp.Type
 	dataManager.Metadata = inp.Metadata
+	// the storage address of the description is only readable by the owner
 	dataManager.Description = &ChecksumAddress{
-		Checksum:       inp.DescriptionChecksum,
-		StorageAddress: inp.DescriptionStorageAddress,
+		Checksum: inp.DescriptionChecksum,
 	}
 	owner, err := GetTxCreator(db.cc)
 	if err != nil {
 		return "", err
 	}
 	dataManager.Owner = owner
+	dataManager.PrivateHash, err = db.PutPrivate(owner, inp.Key, DataManagerPrivateData{
+		DescriptionStorageAddress: inp.DescriptionStorageAddress,
+	})
+	if err != nil {
+		return "", err
+	}
 
 	permissions, err := NewPermissions(db, inp.Permissions)
 	if err != nil {
@@ -49,6 +55,12 @@ func (dataManager *DataManager) Set(db *LedgerDB, inp inputDataManager) (string,
 	}
 
 	dataManager.Permissions = permissions
+	if inp.PrivacyBudget.Epsilon > 0 {
+		dataManager.PrivacyBudget = &PrivacyBudget{
+			Epsilon: inp.PrivacyBudget.Epsilon,
+			Delta:   inp.PrivacyBudget.Delta,
+		}
+	}
 	return dataManager.ObjectiveKey, nil
 }
 
@@ -126,6 +138,10 @@ func registerDataManager(db *LedgerDB, args []string) (resp outputKey, err error
 	if err != nil {
 		return
 	}
+	err = createPrivacyBudgetState(db, dataManager)
+	if err != nil {
+		return
+	}
 	// create composite keys (one for each associated objective) to find dataSample associated with a objective
 	indexName := "dataManager~objective~key"
 	err = db.CreateIndex(indexName, []string{"dataManager", objectiveKey, dataManager.Key})
@@ -156,9 +172,19 @@ func registerDataSample(db *LedgerDB, args []string) (addedDataSampleKeys map[st
 
 	// store dataSample in the ledger
 	for _, dataSampleKey := range dataSampleKeys {
+		// the metadata is only readable by the owner
+		if len(inp.Metadata) > 0 {
+			dataSample.PrivateHash, err = db.PutPrivate(dataSample.Owner, dataSampleKey, DataSamplePrivateData{Metadata: inp.Metadata})
+			if err != nil {
+				return
+			}
+		}
 		if err = db.Add(dataSampleKey, dataSample); err != nil {
 			return
 		}
+		if err = setDataSampleEndorsers(db, dataSampleKey, dataSample); err != nil {
+			return
+		}
 		for _, dataManagerKey := range dataSample.DataManagerKeys {
 			// create composite keys to find all dataSample associated with a dataManager and both test and train dataSample
 			if err = db.CreateIndex("dataSample~dataManager~key", []string{"dataSample", dataManagerKey, dataSampleKey}); err != nil {
@@ -200,6 +226,10 @@ func updateDataSample(db *LedgerDB, args []string) (resp outputKey, err error) {
 		if err = checkDataSampleOwner(db, dataSample); err != nil {
 			return
 		}
+		if dataSample.Revoked {
+			err = errors.BadRequest("dataSample %s is revoked", dataSampleKey)
+			return
+		}
 		for _, dataManagerKey := range dataManagerKeys {
 			if !stringInSlice(dataManagerKey, dataSample.DataManagerKeys) {
 				// check data manager is not already associated with this data
@@ -217,7 +247,9 @@ func updateDataSample(db *LedgerDB, args []string) (resp outputKey, err error) {
 		if err = db.Put(dataSampleKey, dataSample); err != nil {
 			return
 		}
-
+		if err = setDataSampleEndorsers(db, dataSampleKey, dataSample); err != nil {
+			return
+		}
 	}
 	// return updated dataSample keys
 	// TODO return a json struct
@@ -225,6 +257,55 @@ func updateDataSample(db *LedgerDB, args []string) (resp outputKey, err error) {
 	return outputKey{Key: keysJSON}, nil
 }
 
+// revokeDataSamples marks one or more dataSample as revoked so that they can no longer be used.
+// Revoked dataSample are removed from the datasets of their dataManagers and the tuples
+// using them which have not started yet are set to failed.
+func revokeDataSamples(db *LedgerDB, args []string) (resp map[string][]string, err error) {
+	inp := inputRevokeDataSamples{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	var workers []string
+	for _, dataSampleKey := range inp.Keys {
+		var dataSample DataSample
+		dataSample, err = db.GetDataSample(dataSampleKey)
+		if err != nil {
+			return
+		}
+		if err = checkDataSampleOwner(db, dataSample); err != nil {
+			return
+		}
+		if dataSample.Revoked {
+			err = errors.BadRequest("dataSample %s is already revoked", dataSampleKey)
+			return
+		}
+		dataSample.Revoked = true
+		if err = db.Put(dataSampleKey, dataSample); err != nil {
+			return
+		}
+		// the dataSample stays listed by queryDataSamples but is no longer part of any dataset
+		for _, dataManagerKey := range dataSample.DataManagerKeys {
+			// the tuples using the dataSample run on the owner of its dataManager
+			var worker string
+			worker, err = getDataManagerOwner(db, dataManagerKey)
+			if err != nil {
+				return
+			}
+			if !stringInSlice(worker, workers) {
+				workers = append(workers, worker)
+			}
+			if err = db.DeleteIndex("dataSample~dataManager~testOnly~key", []string{"dataSample", dataManagerKey, strconv.FormatBool(dataSample.TestOnly), dataSampleKey}); err != nil {
+				return
+			}
+		}
+	}
+	if err = failTuplesUsingDataSamples(db, workers, inp.Keys); err != nil {
+		return
+	}
+	return map[string][]string{"keys": inp.Keys}, nil
+}
+
 // updateDataManager associates a objectiveKey to an existing dataManager
 func updateDataManager(db *LedgerDB, args []string) (resp outputKey, err error) {
 	inp := inputUpdateDataManager{}
@@ -256,6 +337,7 @@ func queryDataManager(db *LedgerDB, args []string) (out outputDataManager, err e
 		return
 	}
 	out.Fill(dataManager)
+	out.fillPrivateData(db, dataManager)
 	return
 }
 
@@ -289,6 +371,7 @@ func queryDataManagers(db *LedgerDB, args []string) (outDataManagers []outputDat
 		}
 		var out outputDataManager
 		out.Fill(dataManager)
+		out.fillPrivateData(db, dataManager)
 		outDataManagers = append(outDataManagers, out)
 	}
 	return
@@ -321,6 +404,7 @@ func queryDataset(db *LedgerDB, args []string) (outputDataset, error) {
 	}
 
 	out.Fill(dataManager, trainDataSampleKeys, testDataSampleKeys)
+	out.outputDataManager.fillPrivateData(db, dataManager)
 	return out, nil
 }
 
@@ -353,6 +437,7 @@ func queryDataSamples(db *LedgerDB, args []string) (outDataSamples []outputDataS
 		}
 		var out outputDataSample
 		out.Fill(key, dataSample)
+		out.fillPrivateData(db, dataSample)
 		outDataSamples = append(outDataSamples, out)
 	}
 	return
@@ -411,12 +496,31 @@ func checkSameDataManager(db *LedgerDB, dataManagerKey string, dataSampleKeys []
 			err = errors.BadRequest("dataSample do not belong to the same dataManager")
 			return testOnly, trainOnly, err
 		}
+		if dataSample.Revoked {
+			err = errors.BadRequest("dataSample %s is revoked", dataSampleKey)
+			return testOnly, trainOnly, err
+		}
 		testOnly = testOnly && dataSample.TestOnly
 		trainOnly = trainOnly && !dataSample.TestOnly
 	}
 	return testOnly, trainOnly, nil
 }
 
+// withoutRevokedDataSamples returns the dataSample keys which have not been revoked
+func withoutRevokedDataSamples(db *LedgerDB, dataSampleKeys []string) ([]string, error) {
+	keys := []string{}
+	for _, dataSampleKey := range dataSampleKeys {
+		dataSample, err := db.GetDataSample(dataSampleKey)
+		if err != nil {
+			return nil, err
+		}
+		if !dataSample.Revoked {
+			keys = append(keys, dataSampleKey)
+		}
+	}
+	return keys, nil
+}
+
 // getDataset returns all dataSample keys associated to a dataManager
 func getDataset(db *LedgerDB, dataManagerKey string, testOnly bool) ([]string, error) {
 	indexName := "dataSample~dataManager~testOnly~key"
@@ -447,3 +551,78 @@ func checkDataSamplesExist(db *LedgerDB, keys []string) (existingKeys []string)
 	}
 	return
 }
+
+// failTuplesUsingDataSamples sets to failed the tuples running on the workers which
+// have not started yet and use at least one of the dataSample.
+// Failures are propagated to the children of tuples which are not part of a compute plan.
+func failTuplesUsingDataSamples(db *LedgerDB, workers []string, dataSampleKeys []string) error {
+	for _, worker := range workers {
+		for _, tupleType := range []string{"traintuple", "compositeTraintuple", "testtuple"} {
+			for _, status := range []string{StatusWaiting, StatusTodo} {
+				if err := failTuplesOfWorker(db, tupleType, worker, status, dataSampleKeys); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// failTuplesOfWorker sets to failed the tuples of a type, worker and status which use at least one of the dataSample
+func failTuplesOfWorker(db *LedgerDB, tupleType string, worker string, status string, dataSampleKeys []string) error {
+	indexName := tupleType + "~worker~status~key"
+	keys, err := db.GetIndexKeys(indexName, []string{tupleType, worker, status})
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		tuple := struct {
+			GenericTuple
+			Dataset TtDataset `json:"dataset"`
+		}{}
+		if err := db.Get(key, &tuple); err != nil {
+			return err
+		}
+		// the tuple may already have been updated as the child of a revoked tuple
+		if tuple.Status != status || !usesDataSamples(tuple.Dataset.DataSampleKeys, dataSampleKeys) {
+			continue
+		}
+		updater, err := db.GetStatusUpdater(key)
+		if err != nil {
+			return err
+		}
+		if err := updater.commitStatusUpdate(db, key, StatusFailed); err != nil {
+			return err
+		}
+		if tuple.AssetType == TesttupleType {
+			continue
+		}
+		if err := updateChildrenOfRevokedTuple(db, key, tuple.ComputePlanKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// usesDataSamples returns whether one of the keys is a dataSample of dataSampleKeys
+func usesDataSamples(keys []string, dataSampleKeys []string) bool {
+	for _, key := range keys {
+		if stringInSlice(key, dataSampleKeys) {
+			return true
+		}
+	}
+	return false
+}
+
+// updateChildrenOfRevokedTuple propagates the failure of a train tuple
+// as logFailTrain does
+func updateChildrenOfRevokedTuple(db *LedgerDB, key string, computePlanKey string) error {
+	// Do not propagate failure if we are in a compute plan
+	if computePlanKey != "" {
+		return nil
+	}
+	if err := UpdateTesttupleChildren(db, key, StatusFailed); err != nil {
+		return err
+	}
+	return UpdateTraintupleChildren(db, key, StatusFailed, []string{})
+}
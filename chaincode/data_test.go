@@ -15,10 +15,12 @@
 package main
 
 import (
+	"chaincode/errors"
 	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestJsonInputsDataManager(t *testing.T) {
@@ -195,3 +197,62 @@ func TestDataset(t *testing.T) {
 	assert.ElementsMatch(t, out.TrainDataSampleKeys, inpDataSample.Keys, "when querying dataManager dataSample, unexpected train keys")
 
 }
+
+func TestRevokeDataSamples(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "traintuple")
+
+	// Add a child traintuple only using the dataSample which will not be revoked
+	inpTraintuple := inputTraintuple{
+		Key:            traintupleKey2,
+		InModels:       []string{traintupleKey},
+		DataSampleKeys: []string{trainDataSampleKey2},
+	}
+	resp := mockStub.MockInvoke(inpTraintuple.createDefault())
+	require.EqualValuesf(t, 200, resp.Status, "when adding traintuple with status %d and message %s", resp.Status, resp.Message)
+
+	inpRevoke := inputRevokeDataSamples{Keys: []string{trainDataSampleKey1}}
+	args := append([][]byte{[]byte("revokeDataSamples")}, assetToJSON(inpRevoke))
+
+	// Only the owner can revoke dataSample
+	mockStub.Creator = workerB
+	resp = mockStub.MockInvoke(args)
+	assert.EqualValuesf(t, 403, resp.Status, "when revoking dataSample of another node, status %d and message %s", resp.Status, resp.Message)
+	mockStub.Creator = workerA
+
+	resp = mockStub.MockInvoke(args)
+	require.EqualValuesf(t, 200, resp.Status, "when revoking dataSample, status %d and message %s", resp.Status, resp.Message)
+
+	resp = mockStub.MockInvoke(args)
+	assert.EqualValuesf(t, 400, resp.Status, "when revoking dataSample already revoked, status %d and message %s", resp.Status, resp.Message)
+
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	// The revoked dataSample is no longer part of the dataset
+	dataset, err := queryDataset(db, keyToArgs(dataManagerKey))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{trainDataSampleKey2}, dataset.TrainDataSampleKeys)
+
+	dataSamples, _, err := queryDataSamples(db, []string{})
+	assert.NoError(t, err)
+	for _, dataSample := range dataSamples {
+		assert.Equal(t, dataSample.Key == trainDataSampleKey1, dataSample.Revoked, dataSample.Key)
+	}
+
+	// The tuple using it and its child are failed
+	traintuple, err := queryTraintuple(db, keyToArgs(traintupleKey))
+	assert.NoError(t, err)
+	assert.Equal(t, StatusFailed, traintuple.Status)
+	traintuple, err = queryTraintuple(db, keyToArgs(traintupleKey2))
+	assert.NoError(t, err)
+	assert.Equal(t, StatusFailed, traintuple.Status)
+
+	// The revoked dataSample cannot be used by new tuples
+	inpTraintuple = inputTraintuple{Key: RandomUUID()}
+	inpTraintuple.createDefault()
+	_, err = createTraintuple(db, assetToArgs(inpTraintuple))
+	assert.Error(t, err)
+	assert.Equal(t, 400, errors.Wrap(err).HTTPStatusCode())
+}
@@ -0,0 +1,65 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"strconv"
+)
+
+// stateBasedEndorsementSetting is the setting enabling the key-level endorsement policies
+const stateBasedEndorsementSetting = "endorsement.state_based"
+
+// validateEndorsementSetting checks the value of the state based endorsement setting
+func validateEndorsementSetting(name string, value string) error {
+	if name != stateBasedEndorsementSetting {
+		return nil
+	}
+	if _, err := strconv.ParseBool(value); err != nil {
+		return errors.BadRequest("setting %s must be a boolean, received: %s", name, value)
+	}
+	return nil
+}
+
+// setKeyEndorsers requires the organizations owning a key to endorse its further changes,
+// when state based endorsement is enabled
+func setKeyEndorsers(db *LedgerDB, key string, orgs ...string) error {
+	value, ok, err := getSetting(db, stateBasedEndorsementSetting)
+	if err != nil {
+		return err
+	}
+	if enabled, _ := strconv.ParseBool(value); !ok || !enabled {
+		return nil
+	}
+	return db.SetEndorsers(key, orgs)
+}
+
+// setDataSampleEndorsers requires the owners of the data managers of a data sample to endorse its changes
+func setDataSampleEndorsers(db *LedgerDB, key string, dataSample DataSample) error {
+	orgs := []string{}
+	for _, dataManagerKey := range dataSample.DataManagerKeys {
+		owner, err := getDataManagerOwner(db, dataManagerKey)
+		if err != nil {
+			return err
+		}
+		if !stringInSlice(owner, orgs) {
+			orgs = append(orgs, owner)
+		}
+	}
+	if len(orgs) == 0 {
+		orgs = append(orgs, dataSample.Owner)
+	}
+	return setKeyEndorsers(db, key, orgs...)
+}
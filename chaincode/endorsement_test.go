@@ -0,0 +1,125 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/statebased"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// getKeyEndorsers returns the organizations required to endorse the changes of a key
+func getKeyEndorsers(t *testing.T, mockStub *MockStub, key string) []string {
+	policy, err := mockStub.GetStateValidationParameter(key)
+	require.NoError(t, err)
+	if policy == nil {
+		return nil
+	}
+	ep, err := statebased.NewStateEP(policy)
+	require.NoError(t, err)
+	return ep.ListOrgs()
+}
+
+func TestStateBasedEndorsement(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	initGovernanceWithSettings(t, mockStub, map[string]string{stateBasedEndorsementSetting: "true"})
+	registerItem(t, *mockStub, "aggregateAlgo")
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	// Data samples are endorsed by the owner of their data managers
+	assert.Equal(t, []string{workerA}, getKeyEndorsers(t, mockStub, trainDataSampleKey1))
+
+	// Tuples and worker states are endorsed by their worker
+	out, err := createComputePlanInternal(db, defaultComputePlan, tag, map[string]string{}, false, 0)
+	require.NoError(t, err)
+	for _, key := range out.IDToKey {
+		assert.Equal(t, []string{workerA}, getKeyEndorsers(t, mockStub, key))
+	}
+
+	// Worker states are also written by the creator of the compute plan and by the other workers
+	computePlan, err := db.GetComputePlan(out.Key)
+	require.NoError(t, err)
+	assert.Nil(t, getKeyEndorsers(t, mockStub, computePlan.getCPWorkerStateKey(workerA)))
+
+	// The setting must be a boolean
+	inp := inputProposal{Key: RandomUUID(), Type: ProposalSetSetting, SettingName: stateBasedEndorsementSetting, SettingValue: "yes"}
+	_, err = createProposal(db, assetToArgs(inp))
+	assert.Error(t, err)
+}
+
+func TestStateBasedEndorsementDisabled(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "traintuple")
+
+	assert.Nil(t, getKeyEndorsers(t, mockStub, trainDataSampleKey1))
+	assert.Nil(t, getKeyEndorsers(t, mockStub, traintupleKey))
+}
+
+// requiredEndorsers returns the organizations whose endorsement the key-level policies of the keys
+// changed by f require, on top of the chaincode endorsement policy
+func requiredEndorsers(t *testing.T, mockStub *MockStub, f func()) []string {
+	before := map[string][]byte{}
+	for key, value := range mockStub.State {
+		before[key] = value
+	}
+	f()
+	orgs := []string{}
+	for key, value := range before {
+		if newValue, ok := mockStub.State[key]; ok && bytes.Equal(newValue, value) {
+			continue
+		}
+		for _, org := range getKeyEndorsers(t, mockStub, key) {
+			if !stringInSlice(org, orgs) {
+				orgs = append(orgs, org)
+			}
+		}
+	}
+	sort.Strings(orgs)
+	return orgs
+}
+
+func TestStateBasedEndorsementCrossOrg(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := getMockStubForModelComposition(t, scc)
+	initGovernanceWithSettings(t, mockStub, map[string]string{stateBasedEndorsementSetting: "true"})
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	out, err := createComputePlanInternal(db, modelCompositionComputePlan, tag, map[string]string{}, true, 0)
+	require.NoError(t, err)
+
+	compositeToDone(t, mockStub, workerA, db, out.CompositeTraintupleKeys[0], RandomUUID(), RandomUUID())
+
+	// The children running on other workers, the aggregatetuple and a testtuple, become todo:
+	// their workers must endorse the transaction too
+	endorsers := requiredEndorsers(t, mockStub, func() {
+		compositeToDone(t, mockStub, workerB, db, out.CompositeTraintupleKeys[1], RandomUUID(), RandomUUID())
+	})
+	assert.Equal(t, []string{workerA, workerB, workerC}, endorsers)
+
+	// The creator cancels the compute plan without the endorsement of its workers
+	endorsers = requiredEndorsers(t, mockStub, func() {
+		_, err = cancelComputePlan(db, assetToArgs(inputKey{Key: out.Key}))
+		require.NoError(t, err)
+	})
+	assert.Empty(t, endorsers)
+}
@@ -95,6 +95,11 @@ func Wrap(err error) Error {
 	return Error{Err: err}
 }
 
+// IsNotFound returns whether the error is of the not found kind
+func IsNotFound(err error) bool {
+	return err != nil && Wrap(err).Kind == notFound
+}
+
 // Internal returns an Error of a this specific type
 func Internal(args ...interface{}) Error {
 	args = append([]interface{}{internal}, args...)
@@ -162,6 +167,21 @@ const (
 	forbidden              // Forbidden request
 )
 
+// String returns the name of an error kind
+func (k Kind) String() string {
+	switch k {
+	case notFound:
+		return "not_found"
+	case conflict:
+		return "conflict"
+	case badRequest:
+		return "bad_request"
+	case forbidden:
+		return "forbidden"
+	}
+	return "internal"
+}
+
 // HTTPStatusCode returns for an error kind the associated http status
 func (k Kind) HTTPStatusCode() int {
 	switch k {
@@ -124,3 +124,10 @@ func TestErrorCases(t *testing.T) {
 		})
 	}
 }
+
+func TestIsNotFound(t *testing.T) {
+	assert.True(t, IsNotFound(NotFound("missing")))
+	assert.False(t, IsNotFound(Internal("failure")))
+	assert.False(t, IsNotFound(fmt.Errorf("failure")))
+	assert.False(t, IsNotFound(nil))
+}
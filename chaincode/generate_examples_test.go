@@ -278,6 +278,7 @@ func TestPipeline(t *testing.T) {
 			DataSampleKeys: []string{testDataSampleKey1, testDataSampleKey2},
 			ObjectiveKey:   objectiveKey,
 			TraintupleID:   "thirdTraintupleID",
+			ID:             "thirdTesttupleID",
 		},
 	}
 	callAssertAndPrint("invoke", "updateComputePlan", upCP)
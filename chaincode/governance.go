@@ -0,0 +1,389 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+)
+
+// governanceKey is the key under which the governance of the network is stored
+const governanceKey = "governance"
+
+// List of the possible proposal's type
+const (
+	ProposalAddNode      = "addNode"
+	ProposalRemoveNode   = "removeNode"
+	ProposalSetSetting   = "setSetting"
+	ProposalAddAdmin     = "addAdmin"
+	ProposalRemoveAdmin  = "removeAdmin"
+	ProposalSetThreshold = "setThreshold"
+)
+
+// List of the possible proposal's status
+const (
+	ProposalPending  = "pending"
+	ProposalExecuted = "executed"
+)
+
+// initGovernance stores the admins of the network, the number of approvals required by proposals
+// and the initial global settings.
+// The governance can only be initialized once, it is then changed through proposals.
+func initGovernance(db *LedgerDB, args []string) error {
+	inp := inputGovernance{}
+	if err := AssetFromJSON(args, &inp); err != nil {
+		return err
+	}
+	if inp.Threshold > len(inp.Admins) {
+		return errors.BadRequest("threshold %d cannot be greater than the number of admins %d", inp.Threshold, len(inp.Admins))
+	}
+	governance := Governance{
+		Admins:    inp.Admins,
+		Threshold: inp.Threshold,
+		Settings:  map[string]string{},
+	}
+	for name, value := range inp.Settings {
+		if err := validateSetting(name, value); err != nil {
+			return err
+		}
+		governance.Settings[name] = value
+	}
+	if err := db.Add(governanceKey, governance); err != nil {
+		return errors.Conflict(err, "governance is already initialized")
+	}
+	return nil
+}
+
+// hasGovernance returns whether the governance of the network has been initialized
+func hasGovernance(db *LedgerDB) (bool, error) {
+	return db.KeyExists(governanceKey)
+}
+
+// checkGovernanceAdmin checks that the transaction requester is one of the admins of the network
+// and returns the governance
+func checkGovernanceAdmin(db *LedgerDB) (Governance, string, error) {
+	governance, err := db.GetGovernance()
+	if err != nil {
+		return governance, "", err
+	}
+	txCreator, err := GetTxCreator(db.cc)
+	if err != nil {
+		return governance, "", err
+	}
+	if !stringInSlice(txCreator, governance.Admins) {
+		return governance, txCreator, errors.Forbidden("%s is not an admin of the network", txCreator)
+	}
+	return governance, txCreator, nil
+}
+
+// getSetting returns the value of a global setting and whether it is set
+func getSetting(db *LedgerDB, name string) (string, bool, error) {
+	ok, err := hasGovernance(db)
+	if err != nil || !ok {
+		return "", false, err
+	}
+	governance, err := db.GetGovernance()
+	if err != nil {
+		return "", false, err
+	}
+	value, ok := governance.Settings[name]
+	return value, ok, nil
+}
+
+// validate checks that the proposal can be executed on the current state of the network
+func (proposal *Proposal) validate(db *LedgerDB) error {
+	switch proposal.Type {
+	case ProposalAddNode, ProposalRemoveNode:
+		if proposal.NodeID == "" {
+			return errors.BadRequest("a node id is required by %s proposals", proposal.Type)
+		}
+		if proposal.NodeID == governanceKey {
+			return errors.BadRequest("%s cannot be used as a node id", governanceKey)
+		}
+		registered, err := isNode(db, proposal.NodeID)
+		if err != nil {
+			return err
+		}
+		if proposal.Type == ProposalRemoveNode && !registered {
+			return errors.NotFound("node %s is not registered", proposal.NodeID)
+		}
+		if proposal.Type == ProposalAddNode && registered {
+			return errors.BadRequest("node %s is already registered", proposal.NodeID)
+		}
+		// the node is stored under its id, which must not be the key of another asset
+		exists, err := db.KeyExists(proposal.NodeID)
+		if err != nil {
+			return err
+		}
+		if proposal.Type == ProposalAddNode && exists {
+			return errors.BadRequest("%s is already the key of another asset", proposal.NodeID)
+		}
+	case ProposalSetSetting:
+		if proposal.SettingName == "" {
+			return errors.BadRequest("a setting name is required by %s proposals", proposal.Type)
+		}
+		return validateSetting(proposal.SettingName, proposal.SettingValue)
+	case ProposalAddAdmin, ProposalRemoveAdmin, ProposalSetThreshold:
+		governance, err := db.GetGovernance()
+		if err != nil {
+			return err
+		}
+		_, err = proposal.applyToAdmins(governance)
+		return err
+	}
+	return nil
+}
+
+// applyToAdmins returns the governance with the admins or the threshold changed by the proposal.
+// The threshold can never exceed the number of admins.
+func (proposal *Proposal) applyToAdmins(governance Governance) (Governance, error) {
+	admins := append([]string{}, governance.Admins...)
+	threshold := governance.Threshold
+	switch proposal.Type {
+	case ProposalAddAdmin, ProposalRemoveAdmin:
+		if proposal.Admin == "" {
+			return governance, errors.BadRequest("an admin is required by %s proposals", proposal.Type)
+		}
+		isAdmin := stringInSlice(proposal.Admin, admins)
+		if proposal.Type == ProposalAddAdmin && isAdmin {
+			return governance, errors.BadRequest("%s is already an admin of the network", proposal.Admin)
+		}
+		if proposal.Type == ProposalRemoveAdmin && !isAdmin {
+			return governance, errors.NotFound("%s is not an admin of the network", proposal.Admin)
+		}
+		if proposal.Type == ProposalAddAdmin {
+			admins = append(admins, proposal.Admin)
+			break
+		}
+		admins = []string{}
+		for _, admin := range governance.Admins {
+			if admin != proposal.Admin {
+				admins = append(admins, admin)
+			}
+		}
+	case ProposalSetThreshold:
+		if proposal.Threshold < 1 {
+			return governance, errors.BadRequest("a threshold of at least 1 is required by %s proposals", proposal.Type)
+		}
+		threshold = proposal.Threshold
+	}
+	if threshold > len(admins) {
+		return governance, errors.BadRequest("threshold %d cannot be greater than the number of admins %d", threshold, len(admins))
+	}
+	governance.Admins = admins
+	governance.Threshold = threshold
+	return governance, nil
+}
+
+// validateSetting checks the value of a global setting
+func validateSetting(name string, value string) error {
+	if err := validateQuotaSetting(name, value); err != nil {
+		return err
+	}
+	if err := validateEndorsementSetting(name, value); err != nil {
+		return err
+	}
+	if err := validateAccessControlSetting(name, value); err != nil {
+		return err
+	}
+	return validateLimitSetting(name, value)
+}
+
+// addNode stores a node and its index
+func addNode(db *LedgerDB, node Node) (Node, error) {
+	if err := db.Put(node.ID, node); err != nil {
+		return Node{}, err
+	}
+	if err := db.CreateIndex("node~key", []string{"node", node.ID}); err != nil {
+		return Node{}, err
+	}
+	return node, nil
+}
+
+// isNode returns whether a key is the id of a registered node, only nodes are stored in the node index
+func isNode(db *LedgerDB, key string) (bool, error) {
+	return db.IndexExists("node~key", []string{"node", key})
+}
+
+// removeNode deletes a node and its index.
+// The tuples and the compute plan worker states of the node are kept as the history of its compute plans,
+// the tuples it has not run yet are left pending until their compute plan is canceled.
+func removeNode(db *LedgerDB, nodeID string) error {
+	registered, err := isNode(db, nodeID)
+	if err != nil {
+		return err
+	}
+	if !registered || nodeID == governanceKey {
+		return errors.NotFound("node %s is not registered", nodeID)
+	}
+	if err := db.Delete(nodeID); err != nil {
+		return err
+	}
+	return db.DeleteIndex("node~key", []string{"node", nodeID})
+}
+
+// -----------------------------------------------------------------
+// ----------------------- Smart Contracts  ------------------------
+// -----------------------------------------------------------------
+
+// createProposal stores a new proposal approved by the admin proposing it
+func createProposal(db *LedgerDB, args []string) (out outputProposal, err error) {
+	inp := inputProposal{}
+	if err = AssetFromJSON(args, &inp); err != nil {
+		return
+	}
+	_, txCreator, err := checkGovernanceAdmin(db)
+	if err != nil {
+		return
+	}
+	proposal := Proposal{
+		Key:          inp.Key,
+		AssetType:    ProposalType,
+		Type:         inp.Type,
+		NodeID:       inp.NodeID,
+		SettingName:  inp.SettingName,
+		SettingValue: inp.SettingValue,
+		Admin:        inp.Admin,
+		Threshold:    inp.Threshold,
+		Proposer:     txCreator,
+		Approvals:    []string{txCreator},
+		Status:       ProposalPending,
+	}
+	if err = proposal.validate(db); err != nil {
+		return
+	}
+	if err = db.Add(proposal.Key, proposal); err != nil {
+		return
+	}
+	if err = db.CreateIndex("proposal~key", []string{"proposal", proposal.Key}); err != nil {
+		return
+	}
+	out.Fill(proposal)
+	return
+}
+
+// approveProposal records the approval of a pending proposal by an admin
+func approveProposal(db *LedgerDB, args []string) (out outputProposal, err error) {
+	inp := inputKey{}
+	if err = AssetFromJSON(args, &inp); err != nil {
+		return
+	}
+	_, txCreator, err := checkGovernanceAdmin(db)
+	if err != nil {
+		return
+	}
+	proposal, err := db.GetProposal(inp.Key)
+	if err != nil {
+		return
+	}
+	if proposal.Status != ProposalPending {
+		err = errors.BadRequest("proposal %s is already %s", inp.Key, proposal.Status)
+		return
+	}
+	if stringInSlice(txCreator, proposal.Approvals) {
+		err = errors.BadRequest("proposal %s is already approved by %s", inp.Key, txCreator)
+		return
+	}
+	proposal.Approvals = append(proposal.Approvals, txCreator)
+	if err = db.Put(proposal.Key, proposal); err != nil {
+		return
+	}
+	out.Fill(proposal)
+	return
+}
+
+// executeProposal applies a proposal approved by enough admins
+func executeProposal(db *LedgerDB, args []string) (out outputProposal, err error) {
+	inp := inputKey{}
+	if err = AssetFromJSON(args, &inp); err != nil {
+		return
+	}
+	governance, _, err := checkGovernanceAdmin(db)
+	if err != nil {
+		return
+	}
+	proposal, err := db.GetProposal(inp.Key)
+	if err != nil {
+		return
+	}
+	if proposal.Status != ProposalPending {
+		err = errors.BadRequest("proposal %s is already %s", inp.Key, proposal.Status)
+		return
+	}
+	// admins removed since their approval are not taken into account
+	approvals := 0
+	for _, approval := range proposal.Approvals {
+		if stringInSlice(approval, governance.Admins) {
+			approvals++
+		}
+	}
+	if approvals < governance.Threshold {
+		err = errors.BadRequest("proposal %s has %d approvals, %d are required", inp.Key, approvals, governance.Threshold)
+		return
+	}
+	if err = proposal.validate(db); err != nil {
+		return
+	}
+
+	switch proposal.Type {
+	case ProposalAddNode:
+		_, err = addNode(db, Node{ID: proposal.NodeID})
+	case ProposalRemoveNode:
+		err = removeNode(db, proposal.NodeID)
+	case ProposalSetSetting:
+		if governance.Settings == nil {
+			governance.Settings = map[string]string{}
+		}
+		governance.Settings[proposal.SettingName] = proposal.SettingValue
+		err = db.Put(governanceKey, governance)
+	case ProposalAddAdmin, ProposalRemoveAdmin, ProposalSetThreshold:
+		if governance, err = proposal.applyToAdmins(governance); err == nil {
+			err = db.Put(governanceKey, governance)
+		}
+	}
+	if err != nil {
+		return
+	}
+
+	proposal.Status = ProposalExecuted
+	if err = db.Put(proposal.Key, proposal); err != nil {
+		return
+	}
+	out.Fill(proposal)
+	return
+}
+
+// queryProposals returns all the proposals
+func queryProposals(db *LedgerDB, args []string) (outProposals []outputProposal, err error) {
+	outProposals = []outputProposal{}
+	if len(args) != 0 {
+		err = errors.BadRequest("incorrect number of arguments, expecting nothing")
+		return
+	}
+	elementsKeys, err := db.GetIndexKeys("proposal~key", []string{"proposal"})
+	if err != nil {
+		return
+	}
+	for _, key := range elementsKeys {
+		var proposal Proposal
+		proposal, err = db.GetProposal(key)
+		if err != nil {
+			return
+		}
+		var out outputProposal
+		out.Fill(proposal)
+		outProposals = append(outProposals, out)
+	}
+	return
+}
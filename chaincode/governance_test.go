@@ -0,0 +1,193 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGovernance(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStub("substra", scc)
+
+	inpGovernance := inputGovernance{Admins: []string{workerA, workerB}, Threshold: 3}
+	resp := mockStub.MockInit("42", [][]byte{[]byte("init"), assetToJSON(inpGovernance)})
+	assert.EqualValuesf(t, 400, resp.Status, "when initializing governance with a threshold too high, status %d and message %s", resp.Status, resp.Message)
+
+	inpGovernance.Threshold = 2
+	resp = mockStub.MockInit("42", [][]byte{[]byte("init"), assetToJSON(inpGovernance)})
+	require.EqualValuesf(t, 200, resp.Status, "when initializing governance, status %d and message %s", resp.Status, resp.Message)
+	resp = mockStub.MockInit("42", [][]byte{[]byte("init"), assetToJSON(inpGovernance)})
+	assert.EqualValuesf(t, 409, resp.Status, "when initializing governance twice, status %d and message %s", resp.Status, resp.Message)
+
+	// Only admins can register themselves
+	resp = mockStub.MockInvoke([][]byte{[]byte("registerNode")})
+	assert.EqualValuesf(t, 200, resp.Status, "when registering an admin node, status %d and message %s", resp.Status, resp.Message)
+	mockStub.Creator = workerC
+	resp = mockStub.MockInvoke([][]byte{[]byte("registerNode")})
+	assert.EqualValuesf(t, 403, resp.Status, "when registering a node which is not admin, status %d and message %s", resp.Status, resp.Message)
+
+	inpProposal := inputProposal{Key: RandomUUID(), Type: ProposalAddNode, NodeID: workerC}
+	resp = mockStub.MockInvoke(methodAndAssetToByte("createProposal", inpProposal))
+	assert.EqualValuesf(t, 403, resp.Status, "when creating a proposal as non admin, status %d and message %s", resp.Status, resp.Message)
+	mockStub.Creator = workerA
+	resp = mockStub.MockInvoke(methodAndAssetToByte("createProposal", inpProposal))
+	require.EqualValuesf(t, 200, resp.Status, "when creating a proposal, status %d and message %s", resp.Status, resp.Message)
+
+	args := methodAndAssetToByte("executeProposal", inputKey{Key: inpProposal.Key})
+	resp = mockStub.MockInvoke(args)
+	assert.EqualValuesf(t, 400, resp.Status, "when executing a proposal without enough approvals, status %d and message %s", resp.Status, resp.Message)
+
+	approveArgs := methodAndAssetToByte("approveProposal", inputKey{Key: inpProposal.Key})
+	resp = mockStub.MockInvoke(approveArgs)
+	assert.EqualValuesf(t, 400, resp.Status, "when approving a proposal twice, status %d and message %s", resp.Status, resp.Message)
+	mockStub.Creator = workerB
+	resp = mockStub.MockInvoke(approveArgs)
+	require.EqualValuesf(t, 200, resp.Status, "when approving a proposal, status %d and message %s", resp.Status, resp.Message)
+
+	resp = mockStub.MockInvoke(args)
+	require.EqualValuesf(t, 200, resp.Status, "when executing a proposal, status %d and message %s", resp.Status, resp.Message)
+	proposal := outputProposal{}
+	assert.NoError(t, json.Unmarshal(resp.Payload, &proposal))
+	assert.Equal(t, ProposalExecuted, proposal.Status)
+	assert.Equal(t, []string{workerA, workerB}, proposal.Approvals)
+	resp = mockStub.MockInvoke(args)
+	assert.EqualValuesf(t, 400, resp.Status, "when executing a proposal twice, status %d and message %s", resp.Status, resp.Message)
+	mockStub.Creator = workerA
+
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+	nodes, err := queryNodes(db, []string{})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []Node{{ID: workerA}, {ID: workerC}}, nodes)
+
+	// Remove the node and change a setting
+	for _, inp := range []inputProposal{
+		{Key: RandomUUID(), Type: ProposalRemoveNode, NodeID: workerC},
+		{Key: RandomUUID(), Type: ProposalSetSetting, SettingName: "foo", SettingValue: "bar"},
+	} {
+		mockStub.Creator = workerA
+		resp = mockStub.MockInvoke(methodAndAssetToByte("createProposal", inp))
+		require.EqualValuesf(t, 200, resp.Status, "when creating a proposal, status %d and message %s", resp.Status, resp.Message)
+		mockStub.Creator = workerB
+		resp = mockStub.MockInvoke(methodAndAssetToByte("approveProposal", inputKey{Key: inp.Key}))
+		require.EqualValuesf(t, 200, resp.Status, "when approving a proposal, status %d and message %s", resp.Status, resp.Message)
+		resp = mockStub.MockInvoke(methodAndAssetToByte("executeProposal", inputKey{Key: inp.Key}))
+		require.EqualValuesf(t, 200, resp.Status, "when executing a proposal, status %d and message %s", resp.Status, resp.Message)
+	}
+	mockStub.Creator = workerA
+
+	mockStub.MockTransactionStart("42")
+	db = NewLedgerDB(mockStub)
+	nodes, err = queryNodes(db, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, []Node{{ID: workerA}}, nodes)
+
+	value, ok, err := getSetting(db, "foo")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "bar", value)
+
+	proposals, err := queryProposals(db, []string{})
+	assert.NoError(t, err)
+	assert.Len(t, proposals, 3)
+}
+
+func TestProposalValidation(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStub("substra", scc)
+
+	// Proposals require the governance to be initialized
+	inpProposal := inputProposal{Key: RandomUUID(), Type: ProposalSetSetting, SettingName: "foo"}
+	resp := mockStub.MockInvoke(methodAndAssetToByte("createProposal", inpProposal))
+	assert.EqualValuesf(t, 404, resp.Status, "when creating a proposal without governance, status %d and message %s", resp.Status, resp.Message)
+
+	inpGovernance := inputGovernance{Admins: []string{workerA}, Threshold: 1}
+	resp = mockStub.MockInit("42", [][]byte{[]byte("init"), assetToJSON(inpGovernance)})
+	require.EqualValuesf(t, 200, resp.Status, "when initializing governance, status %d and message %s", resp.Status, resp.Message)
+	resp = mockStub.MockInvoke([][]byte{[]byte("registerNode")})
+	require.EqualValuesf(t, 200, resp.Status, "when registering an admin node, status %d and message %s", resp.Status, resp.Message)
+
+	for _, inp := range []inputProposal{
+		{Key: RandomUUID(), Type: "unknown"},
+		{Key: RandomUUID(), Type: ProposalAddNode},
+		{Key: RandomUUID(), Type: ProposalAddNode, NodeID: workerA},
+		{Key: RandomUUID(), Type: ProposalSetSetting},
+	} {
+		resp = mockStub.MockInvoke(methodAndAssetToByte("createProposal", inp))
+		assert.EqualValuesf(t, 400, resp.Status, "when creating an invalid %s proposal, status %d and message %s", inp.Type, resp.Status, resp.Message)
+	}
+	resp = mockStub.MockInvoke(methodAndAssetToByte("createProposal", inputProposal{Key: RandomUUID(), Type: ProposalRemoveNode, NodeID: workerC}))
+	assert.EqualValuesf(t, 404, resp.Status, "when removing an unknown node, status %d and message %s", resp.Status, resp.Message)
+
+	// Only nodes can be removed, and nodes cannot take the key of another asset
+	settingProposalKey := RandomUUID()
+	resp = mockStub.MockInvoke(methodAndAssetToByte("createProposal", inputProposal{Key: settingProposalKey, Type: ProposalSetSetting, SettingName: "foo"}))
+	require.EqualValuesf(t, 200, resp.Status, "when creating a proposal, status %d and message %s", resp.Status, resp.Message)
+	resp = mockStub.MockInvoke(methodAndAssetToByte("createProposal", inputProposal{Key: RandomUUID(), Type: ProposalRemoveNode, NodeID: settingProposalKey}))
+	assert.EqualValuesf(t, 404, resp.Status, "when removing another asset as a node, status %d and message %s", resp.Status, resp.Message)
+	resp = mockStub.MockInvoke(methodAndAssetToByte("createProposal", inputProposal{Key: RandomUUID(), Type: ProposalAddNode, NodeID: settingProposalKey}))
+	assert.EqualValuesf(t, 400, resp.Status, "when adding a node with the key of another asset, status %d and message %s", resp.Status, resp.Message)
+	for _, proposalType := range []string{ProposalAddNode, ProposalRemoveNode} {
+		resp = mockStub.MockInvoke(methodAndAssetToByte("createProposal", inputProposal{Key: RandomUUID(), Type: proposalType, NodeID: governanceKey}))
+		assert.EqualValuesf(t, 400, resp.Status, "when using the governance key in a %s proposal, status %d and message %s", proposalType, resp.Status, resp.Message)
+	}
+}
+
+func TestGovernanceAdmins(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStub("substra", scc)
+	inpGovernance := inputGovernance{Admins: []string{workerA, workerB}, Threshold: 2}
+	resp := mockStub.MockInit("42", [][]byte{[]byte("init"), assetToJSON(inpGovernance)})
+	require.EqualValuesf(t, 200, resp.Status, "when initializing governance, status %d and message %s", resp.Status, resp.Message)
+
+	// Add an admin, then require the approval of all of them
+	for _, inp := range []inputProposal{
+		{Key: RandomUUID(), Type: ProposalAddAdmin, Admin: workerC},
+		{Key: RandomUUID(), Type: ProposalSetThreshold, Threshold: 3},
+	} {
+		mockStub.Creator = workerA
+		resp = mockStub.MockInvoke(methodAndAssetToByte("createProposal", inp))
+		require.EqualValuesf(t, 200, resp.Status, "when creating a proposal, status %d and message %s", resp.Status, resp.Message)
+		mockStub.Creator = workerB
+		resp = mockStub.MockInvoke(methodAndAssetToByte("approveProposal", inputKey{Key: inp.Key}))
+		require.EqualValuesf(t, 200, resp.Status, "when approving a proposal, status %d and message %s", resp.Status, resp.Message)
+		resp = mockStub.MockInvoke(methodAndAssetToByte("executeProposal", inputKey{Key: inp.Key}))
+		require.EqualValuesf(t, 200, resp.Status, "when executing a proposal, status %d and message %s", resp.Status, resp.Message)
+	}
+	mockStub.Creator = workerA
+
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+	governance, err := db.GetGovernance()
+	require.NoError(t, err)
+	assert.Equal(t, []string{workerA, workerB, workerC}, governance.Admins)
+	assert.Equal(t, 3, governance.Threshold)
+
+	// The threshold can never exceed the number of admins
+	for _, inp := range []inputProposal{
+		{Key: RandomUUID(), Type: ProposalRemoveAdmin, Admin: workerB},
+		{Key: RandomUUID(), Type: ProposalSetThreshold, Threshold: 4},
+		{Key: RandomUUID(), Type: ProposalSetThreshold},
+		{Key: RandomUUID(), Type: ProposalAddAdmin, Admin: workerB},
+	} {
+		resp = mockStub.MockInvoke(methodAndAssetToByte("createProposal", inp))
+		assert.EqualValuesf(t, 400, resp.Status, "when creating an invalid %s proposal, status %d and message %s", inp.Type, resp.Status, resp.Message)
+	}
+}
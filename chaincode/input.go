@@ -59,20 +59,40 @@ type inputAlgo struct {
 	DescriptionStorageAddress string            `validate:"required,url" json:"description_storage_address"`
 	Permissions               inputPermissions  `validate:"required" json:"permissions"`
 	Metadata                  map[string]string `validate:"lte=100,dive,keys,lte=50,endkeys,lte=100" json:"metadata"`
+	ParentKey                 string            `validate:"omitempty,len=36" json:"parent_key"`
+	Version                   string            `validate:"omitempty,semver" json:"version"`
+	Resources                 inputResources    `json:"resources"`
+}
+
+// inputResources is the representation of the resources required by an algo or a tuple,
+// or available on a node
+type inputResources struct {
+	CPU        int `validate:"gte=0" json:"cpu"`
+	Memory     int `validate:"gte=0" json:"memory"`
+	GPU        int `validate:"gte=0" json:"gpu"`
+	MaxRuntime int `validate:"gte=0" json:"max_runtime"`
 }
 
 // inputDataManager is the representation of input args to register a DataManager
 type inputDataManager struct {
-	Key                       string            `validate:"required,len=36" json:"key"`
-	Name                      string            `validate:"required,gte=1,lte=100" json:"name"`
-	OpenerChecksum            string            `validate:"required,len=64,hexadecimal" json:"opener_checksum"`
-	OpenerStorageAddress      string            `validate:"required,url" json:"opener_storage_address"`
-	Type                      string            `validate:"required,gte=1,lte=30" json:"type"`
-	DescriptionChecksum       string            `validate:"required,len=64,hexadecimal" json:"description_checksum"`
-	DescriptionStorageAddress string            `validate:"required,url" json:"description_storage_address"`
-	ObjectiveKey              string            `validate:"omitempty,len=36" json:"objective_key"` //`validate:"required"`
-	Permissions               inputPermissions  `validate:"required" json:"permissions"`
-	Metadata                  map[string]string `validate:"lte=100,dive,keys,lte=50,endkeys,lte=100" json:"metadata"`
+	Key                       string             `validate:"required,len=36" json:"key"`
+	Name                      string             `validate:"required,gte=1,lte=100" json:"name"`
+	OpenerChecksum            string             `validate:"required,len=64,hexadecimal" json:"opener_checksum"`
+	OpenerStorageAddress      string             `validate:"required,url" json:"opener_storage_address"`
+	Type                      string             `validate:"required,gte=1,lte=30" json:"type"`
+	DescriptionChecksum       string             `validate:"required,len=64,hexadecimal" json:"description_checksum"`
+	DescriptionStorageAddress string             `validate:"required,url" json:"description_storage_address"`
+	ObjectiveKey              string             `validate:"omitempty,len=36" json:"objective_key"` //`validate:"required"`
+	Permissions               inputPermissions   `validate:"required" json:"permissions"`
+	Metadata                  map[string]string  `validate:"lte=100,dive,keys,lte=50,endkeys,lte=100" json:"metadata"`
+	PrivacyBudget             inputPrivacyBudget `json:"privacy_budget"`
+}
+
+// inputPrivacyBudget is the representation of the optional differential privacy budget of a dataManager.
+// A zero epsilon means the dataManager has no budget.
+type inputPrivacyBudget struct {
+	Epsilon float64 `validate:"gte=0" json:"epsilon"`
+	Delta   float64 `validate:"gte=0,lt=1" json:"delta"`
 }
 
 // inputUpdateDataManager is the representation of input args to update a dataManager with a objective
@@ -81,11 +101,20 @@ type inputUpdateDataManager struct {
 	ObjectiveKey   string `validate:"required,len=36" json:"objective_key"`
 }
 
+// inputUpdateObjectiveTestDataset is the representation of input args to set, extend or replace the test dataset of an objective
+type inputUpdateObjectiveTestDataset struct {
+	ObjectiveKey   string   `validate:"required,len=36" json:"objective_key"`
+	DataManagerKey string   `validate:"required,len=36" json:"data_manager_key"`
+	DataSampleKeys []string `validate:"required,unique,gt=0,dive,len=36" json:"data_sample_keys"`
+	Replace        bool     `json:"replace"` // whether the dataSamples replace the test dataset instead of extending it
+}
+
 // inputDataSample is the representation of input args to register one or more dataSample
 type inputDataSample struct {
-	Keys            []string `validate:"required,dive,len=36" json:"keys"`
-	DataManagerKeys []string `validate:"omitempty,dive,len=36" json:"data_manager_keys"`
-	TestOnly        string   `validate:"required,oneof=true false" json:"testOnly"`
+	Keys            []string          `validate:"required,dive,len=36" json:"keys"`
+	DataManagerKeys []string          `validate:"omitempty,dive,len=36" json:"data_manager_keys"`
+	TestOnly        string            `validate:"required,oneof=true false" json:"testOnly"`
+	Metadata        map[string]string `validate:"lte=100,dive,keys,lte=50,endkeys,lte=100" json:"metadata"`
 }
 
 // inputUpdateDataSample is the representation of input args to update one or more dataSample
@@ -94,9 +123,14 @@ type inputUpdateDataSample struct {
 	DataManagerKeys []string `validate:"required,dive,len=36" json:"data_manager_keys"`
 }
 
+// inputRevokeDataSamples is the representation of input args to revoke one or more dataSample
+type inputRevokeDataSamples struct {
+	Keys []string `validate:"required,unique,gt=0,dive,len=36" json:"keys"`
+}
+
 // inputTraintuple is the representation of input args to register a Traintuple
 type inputTraintuple struct {
-	Key            string            `validate:"required,len=36" json:"key"`
+	Key            string            `validate:"omitempty,len=36" json:"key"`
 	AlgoKey        string            `validate:"required,len=36" json:"algo_key"`
 	InModels       []string          `validate:"omitempty,dive,len=36" json:"in_models"`
 	DataManagerKey string            `validate:"required,len=36" json:"data_manager_key"`
@@ -105,17 +139,31 @@ type inputTraintuple struct {
 	Rank           string            `json:"rank"`
 	Tag            string            `validate:"omitempty,lte=64" json:"tag"`
 	Metadata       map[string]string `validate:"lte=100,dive,keys,lte=50,endkeys,lte=100" json:"metadata"`
+	Delta          float64           `validate:"gte=0,lt=1" json:"delta"`
+	Epsilon        float64           `validate:"gte=0" json:"epsilon"`
+	Resources      inputResources    `json:"resources"`
+	Condition      *inputCondition   `json:"condition"`
+}
+
+// inputCondition is the representation of a condition on the perf of a testtuple
+type inputCondition struct {
+	TesttupleKey string  `validate:"required,len=36" json:"testtuple_key"`
+	Operator     string  `validate:"required,oneof=lt lte gt gte" json:"operator"`
+	Value        float32 `json:"value"`
 }
 
 // inputTestuple is the representation of input args to register a Testtuple
 type inputTesttuple struct {
-	Key            string            `validate:"required,len=36" json:"key"`
+	Key            string            `validate:"omitempty,len=36" json:"key"`
 	DataManagerKey string            `validate:"omitempty,len=36" json:"data_manager_key"`
 	DataSampleKeys []string          `validate:"omitempty,dive,len=36" json:"data_sample_keys"`
 	ObjectiveKey   string            `validate:"required,len=36" json:"objective_key"`
 	Tag            string            `validate:"omitempty,lte=64" json:"tag"`
 	Metadata       map[string]string `validate:"omitempty,lte=100,dive,keys,lte=50,endkeys,lte=100" json:"metadata"`
 	TraintupleKey  string            `validate:"required,len=36" json:"traintuple_key"`
+	Delta          float64           `validate:"gte=0,lt=1" json:"delta"`
+	Epsilon        float64           `validate:"gte=0" json:"epsilon"`
+	Resources      inputResources    `json:"resources"`
 }
 
 type inputKey struct {
@@ -164,7 +212,7 @@ type inputQueryFilter struct {
 
 // inputConputePlan represent a coherent set of tuples uploaded together.
 type inputComputePlan struct {
-	Key                  string                                `validate:"required,len=36" json:"key"`
+	Key                  string                                `validate:"omitempty,len=36" json:"key"`
 	Traintuples          []inputComputePlanTraintuple          `validate:"omitempty" json:"traintuples"`
 	Aggregatetuples      []inputComputePlanAggregatetuple      `validate:"omitempty" json:"aggregatetuples"`
 	CompositeTraintuples []inputComputePlanCompositeTraintuple `validate:"omitempty" json:"composite_traintuples"`
@@ -177,32 +225,45 @@ type inputNewComputePlan struct {
 	CleanModels bool              `json:"clean_models"` // whether or not to delete intermediary models
 	Tag         string            `validate:"omitempty,lte=64" json:"tag"`
 	Metadata    map[string]string `validate:"lte=100,dive,keys,lte=50,endkeys,lte=100" json:"metadata"`
+	Priority    int               `validate:"gte=0,lte=1000" json:"priority"`
 	inputComputePlan
 }
 
+type inputComputePlanPriority struct {
+	Key      string `validate:"required,len=36" json:"key"`
+	Priority int    `validate:"gte=0,lte=1000" json:"priority"`
+}
+
 type inputComputePlanTraintuple struct {
-	Key            string            `validate:"required,len=36" json:"key"`
-	DataManagerKey string            `validate:"required,len=36" json:"data_manager_key"`
-	DataSampleKeys []string          `validate:"required,dive,len=36" json:"data_sample_keys"`
-	AlgoKey        string            `validate:"required,len=36" json:"algo_key"`
-	ID             string            `validate:"required,lte=64" json:"id"`
-	InModelsIDs    []string          `validate:"omitempty,dive,lte=64" json:"in_models_ids"`
-	Tag            string            `validate:"omitempty,lte=64" json:"tag"`
-	Metadata       map[string]string `validate:"omitempty,lte=100,dive,keys,lte=50,endkeys,lte=100" json:"metadata"`
+	Key            string                     `validate:"omitempty,len=36" json:"key"`
+	DataManagerKey string                     `validate:"required,len=36" json:"data_manager_key"`
+	DataSampleKeys []string                   `validate:"required,dive,len=36" json:"data_sample_keys"`
+	AlgoKey        string                     `validate:"required,len=36" json:"algo_key"`
+	ID             string                     `validate:"required,lte=64" json:"id"`
+	InModelsIDs    []string                   `validate:"omitempty,dive,lte=64" json:"in_models_ids"`
+	Tag            string                     `validate:"omitempty,lte=64" json:"tag"`
+	Metadata       map[string]string          `validate:"omitempty,lte=100,dive,keys,lte=50,endkeys,lte=100" json:"metadata"`
+	Delta          float64                    `validate:"gte=0,lt=1" json:"delta"`
+	Epsilon        float64                    `validate:"gte=0" json:"epsilon"`
+	Resources      inputResources             `json:"resources"`
+	Condition      *inputComputePlanCondition `json:"condition"`
 }
 
 type inputComputePlanAggregatetuple struct {
-	Key         string            `validate:"required,len=36" json:"key"`
+	Key         string            `validate:"omitempty,len=36" json:"key"`
 	AlgoKey     string            `validate:"required,len=36" json:"algo_key"`
 	ID          string            `validate:"required,lte=64" json:"id"`
 	InModelsIDs []string          `validate:"omitempty,dive,lte=64" json:"in_models_ids"`
 	Tag         string            `validate:"omitempty,lte=64" json:"tag"`
 	Metadata    map[string]string `validate:"omitempty,lte=100,dive,keys,lte=50,endkeys,lte=100" json:"metadata"`
 	Worker      string            `validate:"required" json:"worker"`
+	Resources   inputResources    `json:"resources"`
+	// conditions are only supported on traintuples
+	Condition *inputComputePlanCondition `validate:"isdefault" json:"condition"`
 }
 
 type inputComputePlanCompositeTraintuple struct {
-	Key                      string            `validate:"required,len=36" json:"key"`
+	Key                      string            `validate:"omitempty,len=36" json:"key"`
 	DataManagerKey           string            `validate:"required,len=36" json:"data_manager_key"`
 	DataSampleKeys           []string          `validate:"required,dive,len=36" json:"data_sample_keys"`
 	AlgoKey                  string            `validate:"required,len=36" json:"algo_key"`
@@ -212,21 +273,76 @@ type inputComputePlanCompositeTraintuple struct {
 	OutTrunkModelPermissions inputPermissions  `validate:"required" json:"out_trunk_model_permissions"`
 	Tag                      string            `validate:"omitempty,lte=64" json:"tag"`
 	Metadata                 map[string]string `validate:"omitempty,lte=100,dive,keys,lte=50,endkeys,lte=100" json:"metadata"`
+	Delta                    float64           `validate:"gte=0,lt=1" json:"delta"`
+	Epsilon                  float64           `validate:"gte=0" json:"epsilon"`
+	Resources                inputResources    `json:"resources"`
+	// conditions are only supported on traintuples
+	Condition *inputComputePlanCondition `validate:"isdefault" json:"condition"`
 }
 
 type inputComputePlanTesttuple struct {
-	Key            string            `validate:"required,len=36" json:"key"`
+	Key            string            `validate:"omitempty,len=36" json:"key"`
 	DataManagerKey string            `validate:"omitempty,len=36" json:"data_manager_key"`
 	DataSampleKeys []string          `validate:"omitempty,dive,len=36" json:"data_sample_keys"`
 	ObjectiveKey   string            `validate:"required,len=36" json:"objective_key"`
 	Tag            string            `validate:"omitempty,lte=64" json:"tag"`
 	Metadata       map[string]string `validate:"omitempty,lte=100,dive,keys,lte=50,endkeys,lte=100" json:"metadata"`
 	TraintupleID   string            `validate:"required,lte=64" json:"traintuple_id"`
+	Delta          float64           `validate:"gte=0,lt=1" json:"delta"`
+	Epsilon        float64           `validate:"gte=0" json:"epsilon"`
+	Resources      inputResources    `json:"resources"`
+	ID             string            `validate:"omitempty,lte=64" json:"id"`
+}
+
+// inputComputePlanCondition is the representation of a condition on the perf of a testtuple
+// of the compute plan, referenced by its ID
+type inputComputePlanCondition struct {
+	TesttupleID string  `validate:"required,lte=64" json:"testtuple_id"`
+	Operator    string  `validate:"required,oneof=lt lte gt gte" json:"operator"`
+	Value       float32 `json:"value"`
 }
 
 type inputLeaderboard struct {
-	ObjectiveKey   string `validate:"omitempty,len=36" json:"objective_key"`
-	AscendingOrder bool   `json:"ascendingOrder,required"`
+	ObjectiveKey       string `validate:"omitempty,len=36" json:"objective_key"`
+	AscendingOrder     bool   `json:"ascendingOrder,required"`
+	TestDatasetVersion int    `validate:"gte=0" json:"test_dataset_version"`
+}
+
+// inputGovernance is the representation of the governance given when instantiating the chaincode
+type inputGovernance struct {
+	Admins    []string          `validate:"required,unique,gt=0,dive,required" json:"admins"`
+	Threshold int               `validate:"required,gte=1" json:"threshold"`
+	Settings  map[string]string `json:"settings"`
+}
+
+// inputProposal is the representation of input args to create a governance proposal
+type inputProposal struct {
+	Key          string `validate:"required,len=36" json:"key"`
+	Type         string `validate:"required,oneof=addNode removeNode setSetting addAdmin removeAdmin setThreshold" json:"type"`
+	NodeID       string `validate:"omitempty,lte=64" json:"node_id"`
+	SettingName  string `validate:"omitempty,lte=64" json:"setting_name"`
+	SettingValue string `validate:"omitempty,lte=200" json:"setting_value"`
+	Admin        string `validate:"omitempty,lte=64" json:"admin"`
+	Threshold    int    `validate:"gte=0" json:"threshold"`
+}
+
+// inputNode is the representation of the optional input args to register a node
+type inputNode struct {
+	MaxConcurrentTasks *int            `validate:"omitempty,gte=0" json:"max_concurrent_tasks"`
+	Capacity           *inputResources `json:"capacity"`
+}
+
+type inputNextTasks struct {
+	Worker string `validate:"required,lte=64" json:"worker"`
+	N      int    `validate:"required,gt=0" json:"n"`
+}
+
+type inputWorker struct {
+	Worker string `validate:"required,lte=64" json:"worker"`
+}
+
+type inputQuotaUsage struct {
+	Org string `validate:"omitempty,lte=64" json:"org"`
 }
 
 type inputPermissions struct {
@@ -237,3 +353,44 @@ type inputPermission struct {
 	Public        bool     `json:"public,required"`
 	AuthorizedIDs []string `validate:"required" json:"authorized_ids"`
 }
+
+// inputComputePlanTemplate is the representation of input args to register a compute plan template
+type inputComputePlanTemplate struct {
+	Key             string               `validate:"required,len=36" json:"key"`
+	Name            string               `validate:"required,gte=1,lte=100" json:"name"`
+	Rounds          int                  `validate:"required,gte=1,lte=1000" json:"rounds"`
+	TestEvery       int                  `validate:"gte=0" json:"test_every"`
+	Parties         []inputTemplateParty `validate:"required,min=1,dive" json:"parties"`
+	AggregateWorker string               `validate:"required,lte=64" json:"aggregate_worker"`
+	CompositeAlgo   string               `validate:"required,lte=64" json:"composite_algo"`
+	AggregateAlgo   string               `validate:"required,lte=64" json:"aggregate_algo"`
+	Metadata        map[string]string    `validate:"lte=100,dive,keys,lte=50,endkeys,lte=100" json:"metadata"`
+}
+
+type inputTemplateParty struct {
+	Worker      string `validate:"required,lte=64" json:"worker"`
+	DataManager string `validate:"required,lte=64" json:"data_manager"`
+}
+
+// inputInstantiateComputePlan binds the symbols of a compute plan template to create a compute plan
+type inputInstantiateComputePlan struct {
+	TemplateKey  string                              `validate:"required,len=36" json:"template_key"`
+	Key          string                              `validate:"omitempty,len=36" json:"key"`
+	Rounds       int                                 `validate:"gte=0,lte=1000" json:"rounds"` // defaults to the rounds of the template
+	Workers      map[string]string                   `validate:"required,dive,required" json:"workers"`
+	DataManagers map[string]inputTemplateDataManager `validate:"required,dive" json:"data_managers"`
+	Algos        map[string]string                   `validate:"required,dive,len=36" json:"algos"`
+	ObjectiveKey string                              `validate:"omitempty,len=36" json:"objective_key"`
+	CleanModels  bool                                `json:"clean_models"`
+	Tag          string                              `validate:"omitempty,lte=64" json:"tag"`
+	Metadata     map[string]string                   `validate:"lte=100,dive,keys,lte=50,endkeys,lte=100" json:"metadata"`
+	Priority     int                                 `validate:"gte=0,lte=1000" json:"priority"`
+}
+
+// inputTemplateDataManager is the data manager bound to a symbol, with the data samples to train and
+// test on. Parties without test data samples are not tested.
+type inputTemplateDataManager struct {
+	Key                 string   `validate:"required,len=36" json:"key"`
+	TrainDataSampleKeys []string `validate:"required,min=1,dive,len=36" json:"train_data_sample_keys"`
+	TestDataSampleKeys  []string `validate:"omitempty,dive,len=36" json:"test_data_sample_keys"`
+}
@@ -16,7 +16,7 @@ package main
 
 // inputAggregatetuple is the representation of input args to register an aggregate Tuple
 type inputAggregatetuple struct {
-	Key            string            `validate:"required,len=36" json:"key"`
+	Key            string            `validate:"omitempty,len=36" json:"key"`
 	AlgoKey        string            `validate:"required,len=36" json:"algo_key"`
 	InModels       []string          `validate:"omitempty,dive,len=36" json:"in_models"`
 	ComputePlanKey string            `validate:"required_with=Rank" json:"compute_plan_key"`
@@ -24,6 +24,9 @@ type inputAggregatetuple struct {
 	Rank           string            `json:"rank"`
 	Tag            string            `validate:"omitempty,lte=64" json:"tag"`
 	Worker         string            `validate:"required" json:"worker"`
+	Resources      inputResources    `json:"resources"`
+	// conditions are only supported on traintuples
+	Condition *inputCondition `validate:"isdefault" json:"condition"`
 }
 
 type inputAggregateAlgo struct {
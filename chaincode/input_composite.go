@@ -16,7 +16,7 @@ package main
 
 // inputCompositeTraintuple is the representation of input args to register a composite Traintuple
 type inputCompositeTraintuple struct {
-	Key                      string            `validate:"required,len=36" json:"key"`
+	Key                      string            `validate:"omitempty,len=36" json:"key"`
 	AlgoKey                  string            `validate:"required,len=36" json:"algo_key"`
 	InHeadModelKey           string            `validate:"required_with=InTrunkModelKey,omitempty,len=36" json:"in_head_model_key"`
 	InTrunkModelKey          string            `validate:"required_with=InHeadModelKey,omitempty,len=36" json:"in_trunk_model_key"`
@@ -27,6 +27,11 @@ type inputCompositeTraintuple struct {
 	Rank                     string            `json:"rank"`
 	Tag                      string            `validate:"omitempty,lte=64" json:"tag"`
 	Metadata                 map[string]string `validate:"lte=100,dive,keys,lte=50,endkeys,lte=100" json:"metadata"`
+	Delta                    float64           `validate:"gte=0,lt=1" json:"delta"`
+	Epsilon                  float64           `validate:"gte=0" json:"epsilon"`
+	Resources                inputResources    `json:"resources"`
+	// conditions are only supported on traintuples
+	Condition *inputCondition `validate:"isdefault" json:"condition"`
 }
 
 type inputCompositeAlgo struct {
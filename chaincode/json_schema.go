@@ -0,0 +1,228 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const jsonSchemaVersion = "http://json-schema.org/draft-07/schema#"
+
+// hexadecimalPattern is the pattern checked by the hexadecimal validation
+const hexadecimalPattern = "^(0[xX])?[0-9a-fA-F]+$"
+
+// newJSONSchema returns the JSON Schema of a type, generated from the json and validate tags of its fields
+func newJSONSchema(t reflect.Type) map[string]interface{} {
+	schema := typeSchema(t, nil, map[reflect.Type]bool{})
+	schema["$schema"] = jsonSchemaVersion
+	return schema
+}
+
+// typeSchema returns the schema of a type checked by the rules of a validate tag.
+// The rules following "dive" apply to the items of the lists and the values of the maps,
+// the ones between "keys" and "endkeys" to the keys of the maps.
+// The structs being visited are not expanded again, to stop on recursive types.
+func typeSchema(t reflect.Type, rules []string, visiting map[reflect.Type]bool) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	own, dive := rules, []string(nil)
+	for i, rule := range rules {
+		if rule == "dive" {
+			own, dive = rules[:i], rules[i+1:]
+			break
+		}
+	}
+
+	schema := map[string]interface{}{}
+	switch t.Kind() {
+	case reflect.String:
+		schema["type"] = "string"
+	case reflect.Bool:
+		schema["type"] = "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		schema["type"] = "integer"
+	case reflect.Float32, reflect.Float64:
+		schema["type"] = "number"
+	case reflect.Slice, reflect.Array:
+		schema["type"] = "array"
+		schema["items"] = typeSchema(t.Elem(), dive, visiting)
+	case reflect.Map:
+		var keys []string
+		if len(dive) > 0 && dive[0] == "keys" {
+			for i, rule := range dive {
+				if rule == "endkeys" {
+					keys, dive = dive[1:i], dive[i+1:]
+					break
+				}
+			}
+		}
+		schema["type"] = "object"
+		schema["propertyNames"] = typeSchema(t.Key(), keys, visiting)
+		schema["additionalProperties"] = typeSchema(t.Elem(), dive, visiting)
+	case reflect.Struct:
+		schema["type"] = "object"
+		if visiting[t] {
+			break
+		}
+		visiting[t] = true
+		fields := newStructFields()
+		fields.add(t, visiting)
+		delete(visiting, t)
+		schema["properties"] = fields.properties
+		if len(fields.required) > 0 {
+			schema["required"] = fields.required
+		}
+		if dependencies := fields.dependencies(); len(dependencies) > 0 {
+			schema["dependencies"] = dependencies
+		}
+	}
+	addRules(schema, t.Kind(), own)
+	return schema
+}
+
+// structFields gathers the schemas of the fields of a struct, including the ones of its embedded structs
+type structFields struct {
+	properties   map[string]interface{}
+	required     []string
+	jsonNames    map[string]string   // the JSON names of the fields, by Go name
+	requiredWith map[string][]string // the fields required along with a field, by Go name
+}
+
+func newStructFields() *structFields {
+	return &structFields{
+		properties:   map[string]interface{}{},
+		required:     []string{},
+		jsonNames:    map[string]string{},
+		requiredWith: map[string][]string{},
+	}
+}
+
+// add adds the fields of a struct. The fields of the embedded structs are added as the ones of the struct.
+func (s *structFields) add(t reflect.Type, visiting map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			s.add(field.Type, visiting)
+			continue
+		}
+		if field.PkgPath != "" || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		s.jsonNames[field.Name] = name
+
+		var rules []string
+		if tag := field.Tag.Get("validate"); tag != "" {
+			rules = strings.Split(tag, ",")
+		}
+		for _, rule := range rules {
+			if rule == "dive" {
+				break
+			}
+			ruleName, param := splitRule(rule)
+			switch ruleName {
+			case "required":
+				s.required = append(s.required, name)
+			case "required_with":
+				for _, other := range strings.Fields(param) {
+					s.requiredWith[other] = append(s.requiredWith[other], name)
+				}
+			}
+		}
+		s.properties[name] = typeSchema(field.Type, rules, visiting)
+	}
+}
+
+// dependencies returns the fields required along with a field, by JSON name
+func (s *structFields) dependencies() map[string][]string {
+	dependencies := map[string][]string{}
+	for other, names := range s.requiredWith {
+		if jsonName, ok := s.jsonNames[other]; ok {
+			dependencies[jsonName] = append(dependencies[jsonName], names...)
+		}
+	}
+	return dependencies
+}
+
+// addRules adds to a schema the constraints of the validate rules applying to a value of a given kind.
+// The rules which can't be expressed in a JSON Schema are ignored.
+func addRules(schema map[string]interface{}, kind reflect.Kind, rules []string) {
+	minName, maxName := "minimum", "maximum"
+	switch kind {
+	case reflect.String:
+		minName, maxName = "minLength", "maxLength"
+	case reflect.Slice, reflect.Array:
+		minName, maxName = "minItems", "maxItems"
+	case reflect.Map:
+		minName, maxName = "minProperties", "maxProperties"
+	}
+	numeric := minName == "minimum"
+
+	for _, rule := range rules {
+		ruleName, param := splitRule(rule)
+		value, err := strconv.ParseFloat(param, 64)
+		isNumber := err == nil
+		switch {
+		case ruleName == "len" && isNumber:
+			schema[minName], schema[maxName] = value, value
+		case (ruleName == "min" || ruleName == "gte") && isNumber:
+			schema[minName] = value
+		case (ruleName == "max" || ruleName == "lte") && isNumber:
+			schema[maxName] = value
+		case ruleName == "gt" && isNumber && numeric:
+			schema["exclusiveMinimum"] = value
+		case ruleName == "gt" && isNumber:
+			schema[minName] = value + 1
+		case ruleName == "lt" && isNumber && numeric:
+			schema["exclusiveMaximum"] = value
+		case ruleName == "lt" && isNumber:
+			schema[maxName] = value - 1
+		case ruleName == "oneof":
+			enum := []interface{}{}
+			for _, option := range strings.Fields(param) {
+				if n, err := strconv.ParseFloat(option, 64); err == nil && numeric {
+					enum = append(enum, n)
+				} else {
+					enum = append(enum, option)
+				}
+			}
+			schema["enum"] = enum
+		case ruleName == "unique":
+			schema["uniqueItems"] = true
+		case ruleName == "hexadecimal":
+			schema["pattern"] = hexadecimalPattern
+		case ruleName == "semver":
+			schema["pattern"] = semverRegexp.String()
+		case ruleName == "url":
+			schema["format"] = "uri"
+		}
+	}
+}
+
+// splitRule splits a validate rule into its name and its parameter
+func splitRule(rule string) (string, string) {
+	parts := strings.SplitN(rule, "=", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
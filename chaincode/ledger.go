@@ -34,6 +34,8 @@ const (
 	AggregatetupleType
 	TesttupleType
 	ComputePlanType
+	ProposalType
+	ComputePlanTemplateType
 	// when adding a new type here, don't forget to update
 	// the String() function in utils.go
 )
@@ -45,29 +47,64 @@ type StatusUpdater interface {
 
 // Objective is the representation of one of the element type stored in the ledger
 type Objective struct {
-	Key         string               `json:"key"`
-	Name        string               `json:"name"`
-	AssetType   AssetType            `json:"asset_type"`
-	Description *ChecksumAddress     `json:"description"`
-	Metrics     *ChecksumAddressName `json:"metrics"`
-	Owner       string               `json:"owner"`
-	TestDataset *Dataset             `json:"test_dataset"`
-	Permissions Permissions          `json:"permissions"`
-	Metadata    map[string]string    `json:"metadata"`
+	Key                string               `json:"key"`
+	Name               string               `json:"name"`
+	AssetType          AssetType            `json:"asset_type"`
+	Description        *ChecksumAddress     `json:"description"`
+	Metrics            *ChecksumAddressName `json:"metrics"`
+	Owner              string               `json:"owner"`
+	TestDataset        *Dataset             `json:"test_dataset"`
+	TestDatasetVersion int                  `json:"test_dataset_version"`
+	Permissions        Permissions          `json:"permissions"`
+	Metadata           map[string]string    `json:"metadata"`
 }
 
 // DataManager is the representation of one of the elements type stored in the ledger
 type DataManager struct {
-	Key          string            `json:"key"`
-	Name         string            `json:"name"`
-	AssetType    AssetType         `json:"asset_type"`
-	Opener       *ChecksumAddress  `json:"opener"`
-	Type         string            `json:"type"`
-	Description  *ChecksumAddress  `json:"description"`
-	Owner        string            `json:"owner"`
-	ObjectiveKey string            `json:"objective_key"`
-	Permissions  Permissions       `json:"permissions"`
-	Metadata     map[string]string `json:"metadata"`
+	Key           string            `json:"key"`
+	Name          string            `json:"name"`
+	AssetType     AssetType         `json:"asset_type"`
+	Opener        *ChecksumAddress  `json:"opener"`
+	Type          string            `json:"type"`
+	Description   *ChecksumAddress  `json:"description"`
+	Owner         string            `json:"owner"`
+	ObjectiveKey  string            `json:"objective_key"`
+	Permissions   Permissions       `json:"permissions"`
+	Metadata      map[string]string `json:"metadata"`
+	PrivacyBudget *PrivacyBudget    `json:"privacy_budget"`
+	PrivateHash   string            `json:"private_hash"`
+}
+
+// PrivacyBudget is the differential privacy budget (epsilon, delta) a dataManager
+// can spend over its whole lifetime
+type PrivacyBudget struct {
+	Epsilon float64 `json:"epsilon"`
+	Delta   float64 `json:"delta"`
+}
+
+// PrivacyBudgetSpend is the (epsilon, delta) consumed by a tuple on a dataManager
+type PrivacyBudgetSpend struct {
+	TupleKey string  `json:"tuple_key"`
+	Epsilon  float64 `json:"epsilon"`
+	Delta    float64 `json:"delta"`
+}
+
+// PrivacyBudgetReservation is the (epsilon, delta) a tuple which has not ended yet
+// will consume on a dataManager
+type PrivacyBudgetReservation struct {
+	DataManagerKey string  `json:"data_manager_key"`
+	Epsilon        float64 `json:"epsilon"`
+	Delta          float64 `json:"delta"`
+}
+
+// PrivacyBudgetState keeps track of the epsilon and delta consumed and reserved on a dataManager
+// It is stored under its own key so the dataManager itself is never updated by workers.
+// The spends and the reservations are stored under their own keys so the state does not grow.
+type PrivacyBudgetState struct {
+	Spent         float64 `json:"spent"`
+	Reserved      float64 `json:"reserved"`
+	DeltaSpent    float64 `json:"delta_spent"`
+	DeltaReserved float64 `json:"delta_reserved"`
 }
 
 // DataSample is the representation of one of the element type stored in the ledger
@@ -76,6 +113,8 @@ type DataSample struct {
 	DataManagerKeys []string  `json:"data_manager_keys"`
 	Owner           string    `json:"owner"`
 	TestOnly        bool      `json:"testOnly"`
+	Revoked         bool      `json:"revoked"`
+	PrivateHash     string    `json:"private_hash"`
 }
 
 // Algo is the representation of one of the element type stored in the ledger
@@ -89,6 +128,10 @@ type Algo struct {
 	Owner          string            `json:"owner"`
 	Permissions    Permissions       `json:"permissions"`
 	Metadata       map[string]string `json:"metadata"`
+	ParentKey      string            `json:"parent_key"`
+	Version        string            `json:"version"`
+	Deprecated     bool              `json:"deprecated"`
+	Resources      Resources         `json:"resources"`
 }
 
 // CompositeAlgo is the representation of one of the element type stored in the ledger
@@ -124,14 +167,26 @@ type Traintuple struct {
 	ComputePlanKey string              `json:"compute_plan_key"`
 	Creator        string              `json:"creator"`
 	Log            string              `json:"log"`
+	PrivateHash    string              `json:"private_hash"`
 	Metadata       map[string]string   `json:"metadata"`
 	Rank           int                 `json:"rank"`
 	Status         string              `json:"status"`
 	Tag            string              `json:"tag"`
 	Dataset        *Dataset            `json:"dataset"`
+	Delta          float64             `json:"delta"`
+	Epsilon        float64             `json:"epsilon"`
 	InModelKeys    []string            `json:"in_models"`
 	OutModel       *KeyChecksumAddress `json:"out_model"`
 	Permissions    Permissions         `json:"permissions"`
+	Resources      Resources           `json:"resources"`
+	Condition      *Condition          `json:"condition"`
+}
+
+// Condition makes a traintuple run only if the perf of a testtuple satisfies it
+type Condition struct {
+	TesttupleKey string  `json:"testtuple_key"`
+	Operator     string  `json:"operator"`
+	Value        float32 `json:"value"`
 }
 
 // CompositeTraintuple is like a traintuple, but for composite model composition
@@ -142,15 +197,19 @@ type CompositeTraintuple struct {
 	ComputePlanKey string                          `json:"compute_plan_key"`
 	Creator        string                          `json:"creator"`
 	Log            string                          `json:"log"`
+	PrivateHash    string                          `json:"private_hash"`
 	Metadata       map[string]string               `json:"metadata"`
 	Rank           int                             `json:"rank"`
 	Status         string                          `json:"status"`
 	Tag            string                          `json:"tag"`
 	Dataset        *Dataset                        `json:"dataset"`
+	Delta          float64                         `json:"delta"`
+	Epsilon        float64                         `json:"epsilon"`
 	InHeadModel    string                          `json:"in_head_model"`
 	InTrunkModel   string                          `json:"in_trunk_model"`
 	OutHeadModel   CompositeTraintupleOutHeadModel `json:"out_head_model"`
 	OutTrunkModel  CompositeTraintupleOutModel     `json:"out_trunk_model"`
+	Resources      Resources                       `json:"resources"`
 }
 
 // Aggregatetuple is like a traintuple, but for aggregate model composition
@@ -161,6 +220,7 @@ type Aggregatetuple struct {
 	ComputePlanKey string              `json:"compute_plan_key"`
 	Creator        string              `json:"creator"`
 	Log            string              `json:"log"`
+	PrivateHash    string              `json:"private_hash"`
 	Metadata       map[string]string   `json:"metadata"`
 	Rank           int                 `json:"rank"`
 	Status         string              `json:"status"`
@@ -168,6 +228,7 @@ type Aggregatetuple struct {
 	InModelKeys    []string            `json:"in_models"`
 	OutModel       *KeyChecksumAddress `json:"out_model"`
 	Permissions    Permissions         `json:"permissions"` // TODO (aggregate): what do permissions mean here?
+	Resources      Resources           `json:"resources"`
 	Worker         string              `json:"worker"`
 }
 
@@ -185,21 +246,26 @@ type CompositeTraintupleOutHeadModel struct {
 
 // Testtuple is the representation of one the element type stored in the ledger. It describes a training task occuring on the platform
 type Testtuple struct {
-	Key            string            `json:"key"`
-	AlgoKey        string            `json:"algo"`
-	AssetType      AssetType         `json:"asset_type"`
-	Certified      bool              `json:"certified"`
-	ComputePlanKey string            `json:"compute_plan_key"`
-	Creator        string            `json:"creator"`
-	Dataset        *TtDataset        `json:"dataset"`
-	Log            string            `json:"log"`
-	Metadata       map[string]string `json:"metadata"`
-	TraintupleKey  string            `json:"traintuple_key"`
-	ObjectiveKey   string            `json:"objective"`
-	Permissions    Permissions       `json:"permissions"`
-	Rank           int               `json:"rank"`
-	Status         string            `json:"status"`
-	Tag            string            `json:"tag"`
+	Key                string            `json:"key"`
+	AlgoKey            string            `json:"algo"`
+	AssetType          AssetType         `json:"asset_type"`
+	Certified          bool              `json:"certified"`
+	ComputePlanKey     string            `json:"compute_plan_key"`
+	Creator            string            `json:"creator"`
+	Dataset            *TtDataset        `json:"dataset"`
+	Delta              float64           `json:"delta"`
+	Epsilon            float64           `json:"epsilon"`
+	Log                string            `json:"log"`
+	PrivateHash        string            `json:"private_hash"`
+	Metadata           map[string]string `json:"metadata"`
+	TraintupleKey      string            `json:"traintuple_key"`
+	ObjectiveKey       string            `json:"objective"`
+	Permissions        Permissions       `json:"permissions"`
+	Rank               int               `json:"rank"`
+	Status             string            `json:"status"`
+	Tag                string            `json:"tag"`
+	TestDatasetVersion int               `json:"test_dataset_version"`
+	Resources          Resources         `json:"resources"`
 }
 
 // ComputePlan is the ledger's representation of a compute plan.
@@ -209,8 +275,10 @@ type ComputePlan struct {
 	AssetType               AssetType            `json:"asset_type"`
 	CleanModels             bool                 `json:"clean_models"` // whether or not to delete intermediary models
 	CompositeTraintupleKeys []string             `json:"composite_traintuple_keys"`
+	Creator                 string               `json:"creator"`
 	IDToTrainTask           map[string]TrainTask `json:"id_to_train_task"`
 	Metadata                map[string]string    `json:"metadata"`
+	Priority                int                  `json:"priority"`
 	State                   ComputePlanState     `json:"-"` // "-" means this field is excluded from JSON (de)serialization
 	StateKey                string               `json:"state_key"`
 	Tag                     string               `json:"tag"`
@@ -222,22 +290,28 @@ type ComputePlan struct {
 // ComputePlanState is the ledger's representation of the compute plan state.
 // To minimize the size of every compute plan, update its state record under another
 // key in the ledger. It will reduce the growing rate of the blockchain size.
+// The status is the one of the last compaction, failed and canceled statuses excepted:
+// the current status is derived from the tuple counters.
 type ComputePlanState struct {
 	Status string `json:"status"`
 }
 
 // ComputePlanWorkerState contains state information for a given
-// compute plan and worker
+// compute plan and worker. The counts are the ones of the last compaction,
+// the transitions since then are stored as counter deltas.
 type ComputePlanWorkerState struct {
 	IntermediaryModelsInUse []string `json:"intermediary_models_in_use"`
 	DoneCount               int      `json:"done_count"`  // the number of tuples in the "done" state for this compute plan and worker
 	TupleCount              int      `json:"tuple_count"` // the total number of tuples registered for this compute plan and worker
+	TodoCount               int      `json:"todo_count"`  // the number of tuples which reached the "todo" state since the compute plan was last done
+	DoingCount              int      `json:"doing_count"` // the number of tuples which reached the "doing" state since the compute plan was last done
 }
 
 // TrainTask is represent the information for one tuple in a Compute Plan
 type TrainTask struct {
-	Depth int    `json:"depth"`
-	Key   string `json:"key"`
+	Depth     int    `json:"depth"`
+	Key       string `json:"key"`
+	Testtuple bool   `json:"testtuple,omitempty"` // testtuples produce no model, their ID can't be an input model
 }
 
 // ---------------------------------------------------------------------------------
@@ -316,5 +390,83 @@ type TtObjective struct {
 // Node stores informations about node registered into the network,
 // would be used to list authorized nodes for permissions
 type Node struct {
-	ID string `json:"id"`
+	ID                 string     `json:"id"`
+	MaxConcurrentTasks int        `json:"max_concurrent_tasks"` // 0 means unlimited
+	Capacity           *Resources `json:"capacity"`             // nil means undeclared
+}
+
+// Resources describes the resources required by a task or available on a node.
+// CPU is a number of cores, Memory is in MB and MaxRuntime is in seconds. 0 means unspecified.
+type Resources struct {
+	CPU        int `json:"cpu"`
+	Memory     int `json:"memory"`
+	GPU        int `json:"gpu"`
+	MaxRuntime int `json:"max_runtime"`
+}
+
+// Governance stores the admins of the network, the number of admin approvals
+// required to execute a proposal and the global settings
+type Governance struct {
+	Admins    []string          `json:"admins"`
+	Threshold int               `json:"threshold"`
+	Settings  map[string]string `json:"settings"`
+}
+
+// QuotaUsage is the number of active tuples and compute plans of an organization
+type QuotaUsage struct {
+	ActiveTuples       int `json:"active_tuples"`
+	ActiveComputePlans int `json:"active_compute_plans"`
+}
+
+// Proposal is a change of the network which must be approved by the admins before being executed
+type Proposal struct {
+	Key          string    `json:"key"`
+	AssetType    AssetType `json:"asset_type"`
+	Type         string    `json:"type"`
+	NodeID       string    `json:"node_id"`
+	SettingName  string    `json:"setting_name"`
+	SettingValue string    `json:"setting_value"`
+	Admin        string    `json:"admin"`
+	Threshold    int       `json:"threshold"`
+	Proposer     string    `json:"proposer"`
+	Approvals    []string  `json:"approvals"`
+	Status       string    `json:"status"`
+}
+
+// ComputePlanTemplate describes the shape of a federated compute plan: at each round, every party trains
+// a composite traintuple on its data, then the trunk models are aggregated. Workers, data managers and
+// algos are symbols, bound to actual assets when the template is instantiated.
+type ComputePlanTemplate struct {
+	Key             string            `json:"key"`
+	AssetType       AssetType         `json:"asset_type"`
+	Name            string            `json:"name"`
+	Owner           string            `json:"owner"`
+	Rounds          int               `json:"rounds"`
+	TestEvery       int               `json:"test_every"`
+	Parties         []TemplateParty   `json:"parties"`
+	AggregateWorker string            `json:"aggregate_worker"`
+	CompositeAlgo   string            `json:"composite_algo"`
+	AggregateAlgo   string            `json:"aggregate_algo"`
+	Metadata        map[string]string `json:"metadata"`
+}
+
+// TemplateParty is a symbolic worker training on a symbolic data manager in a compute plan template
+type TemplateParty struct {
+	Worker      string `json:"worker"`
+	DataManager string `json:"data_manager"`
+}
+
+// DataManagerPrivateData is the part of a data manager stored in the private data collection of its owner
+type DataManagerPrivateData struct {
+	DescriptionStorageAddress string `json:"description_storage_address"`
+}
+
+// DataSamplePrivateData is the part of a data sample stored in the private data collection of its owner
+type DataSamplePrivateData struct {
+	Metadata map[string]string `json:"metadata"`
+}
+
+// TuplePrivateData is the part of a tuple stored in the private data collection of its worker
+type TuplePrivateData struct {
+	FailureLog string `json:"failure_log"`
 }
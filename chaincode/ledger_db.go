@@ -16,16 +16,22 @@ package main
 
 import (
 	"chaincode/errors"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"sort"
 	"strings"
 	"sync"
 
+	"github.com/hyperledger/fabric-chaincode-go/pkg/statebased"
 	"github.com/hyperledger/fabric-chaincode-go/shim"
 )
 
 // State is a in-memory representation of the db state
 type State struct {
 	items map[string]([]byte)
+	// indexes lists the composite keys created (true) or deleted (false) during the transaction
+	indexes map[string]bool
 }
 
 // LedgerDB to access the chaincode database during the lifetime of a SmartContract
@@ -34,6 +40,23 @@ type LedgerDB struct {
 	event            *Event
 	transactionState State
 	mutex            *sync.RWMutex
+	// dryRun keeps the writes in the transaction state instead of sending them to the ledger
+	dryRun bool
+	// readOnly rejects the writes, it is set for the contracts which must not change the ledger
+	readOnly bool
+	stats    transactionStats
+	limits   transactionLimits
+	// writtenKeys is the size of the keys written during the transaction, by write set
+	writtenKeys map[string]int
+	// tupleTransitions lists the tuples created or transitioned during the transaction
+	tupleTransitions []tupleTransition
+	eventPayloadSize int
+}
+
+// tupleTransition is the creation of a tuple or the change of its status
+type tupleTransition struct {
+	AssetType AssetType
+	Status    string
 }
 
 // NewLedgerDB create a new db to access the chaincode during a SmartContract
@@ -41,10 +64,36 @@ func NewLedgerDB(stub shim.ChaincodeStubInterface) *LedgerDB {
 	return &LedgerDB{
 		cc: stub,
 		transactionState: State{
-			items: make(map[string]([]byte)),
+			items:   make(map[string]([]byte)),
+			indexes: make(map[string]bool),
 		},
-		mutex: &sync.RWMutex{},
+		mutex:       &sync.RWMutex{},
+		writtenKeys: make(map[string]int),
+	}
+}
+
+// NewDryRunLedgerDB create a db whose writes are only visible during the SmartContract and never
+// reach the ledger. It is used to run the checks of a SmartContract without changing the state.
+func NewDryRunLedgerDB(stub shim.ChaincodeStubInterface) *LedgerDB {
+	db := NewLedgerDB(stub)
+	db.dryRun = true
+	return db
+}
+
+// NewReadOnlyLedgerDB create a db which rejects the writes. It is used to run the read-only
+// SmartContracts, so that a bug can't make them change the state.
+func NewReadOnlyLedgerDB(stub shim.ChaincodeStubInterface) *LedgerDB {
+	db := NewLedgerDB(stub)
+	db.readOnly = true
+	return db
+}
+
+// checkWritable fails on a write made by a read-only SmartContract, which is a bug of the contract
+func (db *LedgerDB) checkWritable(operation string, key string) error {
+	if db.readOnly {
+		return errors.Internal("cannot %s %s: the contract is read-only", operation, key)
 	}
+	return nil
 }
 
 // ----------------------------------------------
@@ -59,6 +108,10 @@ func (db *LedgerDB) getTransactionState(key string) ([]byte, bool) {
 	if !ok {
 		return nil, false
 	}
+	if transactionState == nil {
+		// the object has been deleted during the transaction
+		return nil, true
+	}
 	state := make([]byte, len(transactionState))
 	copy(state, transactionState)
 	return state, true
@@ -78,26 +131,48 @@ func (db *LedgerDB) Get(key string, object interface{}) error {
 
 	buff, ok := db.getTransactionState(key)
 	if !ok {
+		if err = db.countRead(); err != nil {
+			return err
+		}
 		buff, err = db.cc.GetState(key)
 		if err != nil || buff == nil {
 			return errors.NotFound(err, "no asset for key %s", key)
 		}
 		db.putTransactionState(key, buff)
 	}
+	if buff == nil {
+		return errors.NotFound("no asset for key %s", key)
+	}
 
 	return json.Unmarshal(buff, &object)
 }
 
-// KeyExists checks if a key is stored in the chaincode db
+// KeyExists checks if a key is stored in the chaincode db, or has been written during the transaction
 func (db *LedgerDB) KeyExists(key string) (bool, error) {
+	if buff, ok := db.getTransactionState(key); ok {
+		return buff != nil, nil
+	}
+	if err := db.countRead(); err != nil {
+		return false, err
+	}
 	buff, err := db.cc.GetState(key)
 	return buff != nil, err
 }
 
 // Put stores an object in the chaincode db, if the object already exists it is replaced
 func (db *LedgerDB) Put(key string, object interface{}) error {
+	if err := db.checkWritable("put", key); err != nil {
+		return err
+	}
 	buff, _ := json.Marshal(object)
 
+	if db.dryRun {
+		db.putTransactionState(key, buff)
+		return nil
+	}
+	if err := db.countWrite(stateWriteSet, key, buff); err != nil {
+		return err
+	}
 	if err := db.cc.PutState(key, buff); err != nil {
 		return err
 	}
@@ -111,6 +186,9 @@ func (db *LedgerDB) Put(key string, object interface{}) error {
 
 // Add stores an object in the chaincode db, it fails if the object already exists
 func (db *LedgerDB) Add(key string, object interface{}) error {
+	if err := db.checkWritable("add", key); err != nil {
+		return err
+	}
 	ok, err := db.KeyExists(key)
 	if err != nil {
 		return err
@@ -121,30 +199,69 @@ func (db *LedgerDB) Add(key string, object interface{}) error {
 	return db.Put(key, object)
 }
 
+// Delete removes an object from the chaincode db
+func (db *LedgerDB) Delete(key string) error {
+	if err := db.checkWritable("delete", key); err != nil {
+		return err
+	}
+	if !db.dryRun {
+		if err := db.countWrite(stateWriteSet, key, nil); err != nil {
+			return err
+		}
+		if err := db.cc.DelState(key); err != nil {
+			return err
+		}
+	}
+	// the ledger keeps returning the object until the transaction is committed
+	db.putTransactionState(key, nil)
+	return nil
+}
+
 // ----------------------------------------------
 // Low-level functions to handle indexes
 // ----------------------------------------------
 
 // CreateIndex adds a new composite key to the chaincode db
 func (db *LedgerDB) CreateIndex(index string, attributes []string) error {
+	if err := db.checkWritable("create index", index); err != nil {
+		return err
+	}
 	compositeKey, err := db.cc.CreateCompositeKey(index, attributes)
 	if err != nil {
 		return errors.Internal("cannot create index %s: %s", index, err.Error())
 	}
-	value := []byte{0x00}
-	if err = db.cc.PutState(compositeKey, value); err != nil {
-		return errors.Internal("cannot create index %s: %s", index, err.Error())
+	if !db.dryRun {
+		value := []byte{0x00}
+		if err = db.countWrite(stateWriteSet, compositeKey, value); err != nil {
+			return err
+		}
+		if err = db.cc.PutState(compositeKey, value); err != nil {
+			return errors.Internal("cannot create index %s: %s", index, err.Error())
+		}
 	}
+	db.putTransactionIndex(compositeKey, true)
 	return nil
 }
 
 // DeleteIndex deletes a composite key in the chaincode db
 func (db *LedgerDB) DeleteIndex(index string, attributes []string) error {
+	if err := db.checkWritable("delete index", index); err != nil {
+		return err
+	}
 	compositeKey, err := db.cc.CreateCompositeKey(index, attributes)
 	if err != nil {
 		return err
 	}
-	return db.cc.DelState(compositeKey)
+	if !db.dryRun {
+		if err = db.countWrite(stateWriteSet, compositeKey, nil); err != nil {
+			return err
+		}
+		if err = db.cc.DelState(compositeKey); err != nil {
+			return err
+		}
+	}
+	db.putTransactionIndex(compositeKey, false)
+	return nil
 }
 
 // UpdateIndex updates an existing composite key in the chaincode db
@@ -155,37 +272,79 @@ func (db *LedgerDB) UpdateIndex(index string, oldAttributes []string, newAttribu
 	return db.CreateIndex(index, newAttribues)
 }
 
-// GetIndexKeys returns keys matching composite key values from the chaincode db
+// IndexExists checks if a composite key is stored in the chaincode db, or has been created during the transaction
+func (db *LedgerDB) IndexExists(index string, attributes []string) (bool, error) {
+	compositeKey, err := db.cc.CreateCompositeKey(index, attributes)
+	if err != nil {
+		return false, errors.Internal("cannot read index %s: %s", index, err.Error())
+	}
+	db.mutex.Lock()
+	created, ok := db.transactionState.indexes[compositeKey]
+	db.mutex.Unlock()
+	if ok {
+		return created, nil
+	}
+	if err = db.countRead(); err != nil {
+		return false, err
+	}
+	buff, err := db.cc.GetState(compositeKey)
+	return buff != nil, err
+}
+
+// GetIndexKeys returns keys matching composite key values from the chaincode db,
+// including the index changes made earlier in the transaction
 func (db *LedgerDB) GetIndexKeys(index string, attributes []string) ([]string, error) {
-	keys := make([]string, 0)
+	partialCompositeKey, err := db.cc.CreateCompositeKey(index, attributes)
+	if err != nil {
+		return nil, errors.Internal("get index %s failed: %s", index, err.Error())
+	}
 	iterator, err := db.cc.GetStateByPartialCompositeKey(index, attributes)
 	if err != nil {
 		return nil, errors.Internal("get index %s failed: %s", index, err.Error())
 	}
 	defer iterator.Close()
+	compositeKeys := []string{}
 	for iterator.HasNext() {
 		compositeKey, err := iterator.Next()
 		if err != nil {
 			return nil, err
 		}
-		_, keyParts, err := db.cc.SplitCompositeKey(compositeKey.Key)
-		if err != nil {
-			return nil, errors.Internal("get index %s failed: cannot split key %s: %s", index, compositeKey.Key, err.Error())
-		}
-		keys = append(keys, keyParts[len(keyParts)-1])
+		compositeKeys = append(compositeKeys, compositeKey.Key)
 	}
-	return keys, nil
+	db.countIndexQuery(len(compositeKeys))
+	compositeKeys = db.mergeTransactionIndexes(compositeKeys, partialCompositeKey, partialCompositeKey, "")
+	return db.splitIndexKeys(index, compositeKeys)
 }
 
-// GetIndexKeysWithPagination returns keys matching composite key values from the chaincode db
+// GetIndexKeysWithPagination returns keys matching composite key values from the chaincode db,
+// including the index changes made earlier in the transaction
 func (db *LedgerDB) GetIndexKeysWithPagination(index string, attributes []string, pageSize int32, bookmark string) ([]string, string, error) {
-	keys := make([]string, 0)
+	entries, bookmark, err := db.GetIndexAttributesWithPagination(index, attributes, pageSize, bookmark)
+	if err != nil {
+		return nil, "", err
+	}
+	keys := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		keys = append(keys, entry[len(entry)-1])
+	}
+	return keys, bookmark, nil
+}
+
+// GetIndexAttributesWithPagination returns all the attributes of the composite keys matching composite key values
+// from the chaincode db, including the index changes made earlier in the transaction
+func (db *LedgerDB) GetIndexAttributesWithPagination(index string, attributes []string, pageSize int32, bookmark string) ([][]string, string, error) {
+	partialCompositeKey, err := db.cc.CreateCompositeKey(index, attributes)
+	if err != nil {
+		return nil, "", errors.Internal("get index %s failed: %s", index, err.Error())
+	}
+	startKey := partialCompositeKey
 
 	if bookmark != "" {
 		// Transform bookmark from JSON-friendly format to CouchDB format
 		bookmark = strings.Replace(bookmark, "/", "\x00", -1)
 		bookmark = strings.Replace(bookmark, "#", "\\u0000", -1)
 		bookmark = strings.Replace(bookmark, "END", "\U0010ffff", -1)
+		startKey = bookmark
 	}
 
 	iterator, metadata, err := db.cc.GetStateByPartialCompositeKeyWithPagination(index, attributes, pageSize, bookmark)
@@ -193,26 +352,201 @@ func (db *LedgerDB) GetIndexKeysWithPagination(index string, attributes []string
 		return nil, "", errors.Internal("get index %s failed: %s", index, err.Error())
 	}
 	defer iterator.Close()
+	compositeKeys := []string{}
 	for iterator.HasNext() {
 		compositeKey, err := iterator.Next()
 		if err != nil {
 			return nil, "", err
 		}
-		_, keyParts, err := db.cc.SplitCompositeKey(compositeKey.Key)
+		compositeKeys = append(compositeKeys, compositeKey.Key)
+	}
+	db.countIndexQuery(len(compositeKeys))
+
+	nextBookmark := ""
+	bookmark = ""
+	if metadata != nil {
+		nextBookmark = metadata.Bookmark
+		bookmark = jsonBookmark(metadata.Bookmark)
+	}
+
+	// the index keys created during the transaction are returned in the page they belong to.
+	// If they make the page longer than pageSize, the page ends before the first extra key
+	// and the next one starts from it.
+	compositeKeys = db.mergeTransactionIndexes(compositeKeys, partialCompositeKey, startKey, nextBookmark)
+	if pageSize > 0 && int32(len(compositeKeys)) > pageSize {
+		bookmark = jsonBookmark(compositeKeys[pageSize])
+		compositeKeys = compositeKeys[:pageSize]
+	}
+	entries, err := db.splitIndexAttributes(index, compositeKeys)
+	if err != nil {
+		return nil, "", err
+	}
+	return entries, bookmark, nil
+}
+
+// putTransactionIndex records the creation or the deletion of a composite key during the transaction
+func (db *LedgerDB) putTransactionIndex(compositeKey string, created bool) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	db.transactionState.indexes[compositeKey] = created
+}
+
+// mergeTransactionIndexes applies the index changes of the transaction to composite keys read from
+// the ledger. The created keys having the prefix are added if they are in [startKey, endKey),
+// endKey being ignored when empty.
+func (db *LedgerDB) mergeTransactionIndexes(compositeKeys []string, prefix, startKey, endKey string) []string {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	if len(db.transactionState.indexes) == 0 {
+		return compositeKeys
+	}
+	merged := []string{}
+	found := map[string]bool{}
+	for _, compositeKey := range compositeKeys {
+		if created, ok := db.transactionState.indexes[compositeKey]; ok && !created {
+			continue
+		}
+		merged = append(merged, compositeKey)
+		found[compositeKey] = true
+	}
+	added := false
+	for compositeKey, created := range db.transactionState.indexes {
+		if !created || found[compositeKey] || !strings.HasPrefix(compositeKey, prefix) {
+			continue
+		}
+		if compositeKey < startKey || (endKey != "" && compositeKey >= endKey) {
+			continue
+		}
+		merged = append(merged, compositeKey)
+		added = true
+	}
+	if added {
+		sort.Strings(merged)
+	}
+	return merged
+}
+
+// jsonBookmark transforms a bookmark from CouchDB format to JSON-friendly format
+func jsonBookmark(bookmark string) string {
+	bookmark = strings.Replace(bookmark, "\x00", "/", -1)
+	bookmark = strings.Replace(bookmark, "\\u0000", "#", -1)
+	return strings.Replace(bookmark, "\U0010ffff", "END", -1)
+}
+
+// splitIndexKeys returns the last attribute of composite keys, which is the key of the indexed object
+func (db *LedgerDB) splitIndexKeys(index string, compositeKeys []string) ([]string, error) {
+	entries, err := db.splitIndexAttributes(index, compositeKeys)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		keys = append(keys, entry[len(entry)-1])
+	}
+	return keys, nil
+}
+
+// splitIndexAttributes returns the attributes of composite keys
+func (db *LedgerDB) splitIndexAttributes(index string, compositeKeys []string) ([][]string, error) {
+	entries := make([][]string, 0, len(compositeKeys))
+	for _, compositeKey := range compositeKeys {
+		_, keyParts, err := db.cc.SplitCompositeKey(compositeKey)
 		if err != nil {
-			return nil, "", errors.Internal("get index %s failed: cannot split key %s: %s", index, compositeKey.Key, err.Error())
+			return nil, errors.Internal("get index %s failed: cannot split key %s: %s", index, compositeKey, err.Error())
 		}
-		keys = append(keys, keyParts[len(keyParts)-1])
+		entries = append(entries, keyParts)
 	}
+	return entries, nil
+}
 
-	if metadata != nil {
-		// Transform bookmark from CouchDB format to JSON-friendly format
-		bookmark = strings.Replace(metadata.Bookmark, "\x00", "/", -1)
-		bookmark = strings.Replace(bookmark, "\\u0000", "#", -1)
-		bookmark = strings.Replace(bookmark, "\U0010ffff", "END", -1)
+// ----------------------------------------------
+// Low-level functions to handle endorsement policies
+// ----------------------------------------------
+
+// SetEndorsers sets the endorsement policy of a key: further changes of the key must be endorsed
+// by a peer of each organization
+func (db *LedgerDB) SetEndorsers(key string, orgs []string) error {
+	if err := db.checkWritable("set the endorsement policy of", key); err != nil {
+		return err
 	}
+	ep, err := statebased.NewStateEP(nil)
+	if err != nil {
+		return errors.Internal("cannot create endorsement policy of %s: %s", key, err.Error())
+	}
+	if err = ep.AddOrgs(statebased.RoleTypePeer, orgs...); err != nil {
+		return errors.Internal("cannot create endorsement policy of %s: %s", key, err.Error())
+	}
+	policy, err := ep.Policy()
+	if err != nil {
+		return errors.Internal("cannot create endorsement policy of %s: %s", key, err.Error())
+	}
+	if db.dryRun {
+		return nil
+	}
+	if err = db.countWrite(metadataWriteSet, key, policy); err != nil {
+		return err
+	}
+	if err = db.cc.SetStateValidationParameter(key, policy); err != nil {
+		return errors.Internal("cannot set endorsement policy of %s: %s", key, err.Error())
+	}
+	return nil
+}
 
-	return keys, bookmark, nil
+// ----------------------------------------------
+// Low-level functions to handle private data
+// ----------------------------------------------
+
+// privateCollection returns the implicit private data collection of an organization,
+// whose data is only stored by the peers of this organization
+func privateCollection(owner string) string {
+	return "_implicit_org_" + owner
+}
+
+// privateStateKey returns the key of private data in the transaction state
+func privateStateKey(collection string, key string) string {
+	return collection + "\x00" + key
+}
+
+// PutPrivate stores an object in the private data collection of its owner and returns its hash,
+// which is meant to be kept in the public record of the asset
+func (db *LedgerDB) PutPrivate(owner string, key string, object interface{}) (string, error) {
+	if err := db.checkWritable("put private data", key); err != nil {
+		return "", err
+	}
+	buff, _ := json.Marshal(object)
+	collection := privateCollection(owner)
+	if !db.dryRun {
+		if err := db.countWrite(collection, key, buff); err != nil {
+			return "", err
+		}
+		if err := db.cc.PutPrivateData(collection, key, buff); err != nil {
+			return "", errors.Internal("cannot store private data of %s: %s", key, err.Error())
+		}
+	}
+	// Private data written during the transaction can't be read back from the peer
+	db.putTransactionState(privateStateKey(collection, key), buff)
+	hash := sha256.Sum256(buff)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// GetPrivate retrieves an object stored in the private data collection of its owner
+func (db *LedgerDB) GetPrivate(owner string, key string, object interface{}) error {
+	collection := privateCollection(owner)
+	buff, ok := db.getTransactionState(privateStateKey(collection, key))
+	if !ok {
+		if err := db.countRead(); err != nil {
+			return err
+		}
+		var err error
+		buff, err = db.cc.GetPrivateData(collection, key)
+		if err != nil {
+			return errors.Internal(err, "get private data for key %s failed", key)
+		}
+		if buff == nil {
+			return errors.NotFound("no private data for key %s", key)
+		}
+	}
+	return json.Unmarshal(buff, &object)
 }
 
 // ----------------------------------------------
@@ -316,6 +650,19 @@ func (db *LedgerDB) GetAggregateAlgo(key string) (AggregateAlgo, error) {
 	return algo, nil
 }
 
+// GetGenericAlgo fetches an Algo, a CompositeAlgo or an AggregateAlgo from the ledger
+// using its unique key and returns the fields they have in common
+func (db *LedgerDB) GetGenericAlgo(key string) (Algo, error) {
+	algo := Algo{}
+	if err := db.Get(key, &algo); err != nil {
+		return algo, err
+	}
+	if !typeInSlice(algo.AssetType, []AssetType{AlgoType, CompositeAlgoType, AggregateAlgoType}) {
+		return algo, errors.NotFound("algo %s not found", key)
+	}
+	return algo, nil
+}
+
 // GetObjective fetches an Objective from the ledger using its unique key
 func (db *LedgerDB) GetObjective(key string) (Objective, error) {
 	objective := Objective{}
@@ -340,6 +687,15 @@ func (db *LedgerDB) GetDataManager(key string) (DataManager, error) {
 	return dataManager, nil
 }
 
+// GetPrivacyBudgetState fetches the privacy budget state of a DataManager
+func (db *LedgerDB) GetPrivacyBudgetState(dataManagerKey string) (PrivacyBudgetState, error) {
+	state := PrivacyBudgetState{}
+	if err := db.Get(getPrivacyBudgetStateKey(dataManagerKey), &state); err != nil {
+		return state, err
+	}
+	return state, nil
+}
+
 // GetDataSample fetches a DataSample from the ledger using its unique key
 func (db *LedgerDB) GetDataSample(key string) (DataSample, error) {
 	dataSample := DataSample{}
@@ -488,6 +844,50 @@ func (db *LedgerDB) GetNode(key string) (Node, error) {
 	return node, nil
 }
 
+// GetGovernance fetches the governance of the network
+func (db *LedgerDB) GetGovernance() (Governance, error) {
+	governance := Governance{}
+	if err := db.Get(governanceKey, &governance); err != nil {
+		return governance, errors.NotFound(err, "governance is not initialized")
+	}
+	return governance, nil
+}
+
+// GetQuotaUsage counts the active tuples and compute plans of an organization
+func (db *LedgerDB) GetQuotaUsage(org string) (QuotaUsage, error) {
+	usage := QuotaUsage{}
+	var err error
+	if usage.ActiveTuples, err = countQuotaUsage(db, org, quotaTuple); err != nil {
+		return usage, err
+	}
+	usage.ActiveComputePlans, err = countQuotaUsage(db, org, quotaComputePlan)
+	return usage, err
+}
+
+// GetProposal fetches a Proposal from the ledger using its unique key
+func (db *LedgerDB) GetProposal(key string) (Proposal, error) {
+	proposal := Proposal{}
+	if err := db.Get(key, &proposal); err != nil {
+		return proposal, err
+	}
+	if proposal.AssetType != ProposalType {
+		return proposal, errors.NotFound("proposal %s not found", key)
+	}
+	return proposal, nil
+}
+
+// GetComputePlanTemplate fetches a ComputePlanTemplate from the ledger using its key
+func (db *LedgerDB) GetComputePlanTemplate(key string) (ComputePlanTemplate, error) {
+	template := ComputePlanTemplate{}
+	if err := db.Get(key, &template); err != nil {
+		return template, err
+	}
+	if template.AssetType != ComputePlanTemplateType {
+		return template, errors.NotFound("compute plan template %s not found", key)
+	}
+	return template, nil
+}
+
 // ----------------------------------------------
 // High-level functions for events
 // ----------------------------------------------
@@ -495,9 +895,12 @@ func (db *LedgerDB) GetNode(key string) (Node, error) {
 // SendEvent sends an event with updated tuples if there is any
 // Only one event can be sent per transaction
 func (db *LedgerDB) SendEvent() error {
-	if db.event == nil {
+	if db.event == nil || db.dryRun {
 		return nil
 	}
+	if err := db.checkWritable("send event", "chaincode-updates"); err != nil {
+		return err
+	}
 	payload, err := json.Marshal(*(db.event))
 	if err != nil {
 		return err
@@ -506,9 +909,32 @@ func (db *LedgerDB) SendEvent() error {
 	if err != nil {
 		return err
 	}
+	db.eventPayloadSize = len(payload)
 	return nil
 }
 
+// EventPayloadSize returns the size of the event sent by the transaction, if any
+func (db *LedgerDB) EventPayloadSize() int {
+	return db.eventPayloadSize
+}
+
+// recordTupleStatus records the creation of a tuple or the change of its status
+func (db *LedgerDB) recordTupleStatus(assetType AssetType, status string) {
+	if db.dryRun {
+		return
+	}
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	db.tupleTransitions = append(db.tupleTransitions, tupleTransition{AssetType: assetType, Status: status})
+}
+
+// TupleTransitions returns the tuples created or transitioned during the transaction
+func (db *LedgerDB) TupleTransitions() []tupleTransition {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	return append([]tupleTransition{}, db.tupleTransitions...)
+}
+
 // AddTupleEvent add the output tuple matching the tupleKey to the event struct
 func (db *LedgerDB) AddTupleEvent(tupleKey string) error {
 	// We take advantage of the fact that Testtuples have the fields "AssetType"
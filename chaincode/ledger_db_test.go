@@ -15,6 +15,7 @@
 package main
 
 import (
+	"chaincode/errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -50,3 +51,86 @@ func TestGetOutModelKeyChecksumAddress(t *testing.T) {
 	_, err = db.GetOutModelKeyChecksumAddress(composite, []AssetType{TraintupleType})
 	assert.Error(t, err, "the composite traintuple should be found when requesting regular traintuples only")
 }
+
+func TestTransactionIndexes(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+	assert.NoError(t, db.CreateIndex("test~key", []string{"test", "a"}))
+	assert.NoError(t, db.CreateIndex("test~key", []string{"test", "c"}))
+	assert.NoError(t, db.Put("a", "value"))
+
+	// The writes of a dry run never reach the stub, as the uncommitted writes of a transaction
+	db = NewDryRunLedgerDB(mockStub)
+	assert.NoError(t, db.CreateIndex("test~key", []string{"test", "b"}))
+	assert.NoError(t, db.DeleteIndex("test~key", []string{"test", "a"}))
+	assert.NoError(t, db.Delete("a"))
+	assert.NoError(t, db.Put("b", "value"))
+
+	keys, err := db.GetIndexKeys("test~key", []string{"test"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b", "c"}, keys)
+
+	keys, bookmark, err := db.GetIndexKeysWithPagination("test~key", []string{"test"}, 1, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b"}, keys)
+	keys, _, err = db.GetIndexKeysWithPagination("test~key", []string{"test"}, 1, bookmark)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c"}, keys)
+
+	// The pages never exceed the page size, even with several keys created during the transaction
+	assert.NoError(t, db.CreateIndex("test~key", []string{"test", "bb"}))
+	allKeys := []string{}
+	bookmark = ""
+	for i := 0; i < 5; i++ {
+		keys, bookmark, err = db.GetIndexKeysWithPagination("test~key", []string{"test"}, 1, bookmark)
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, len(keys), 1)
+		allKeys = append(allKeys, keys...)
+		if bookmark == "" {
+			break
+		}
+	}
+	assert.Equal(t, []string{"b", "bb", "c"}, allKeys)
+
+	exists, err := db.KeyExists("a")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = db.KeyExists("b")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestReadOnlyLedgerDB(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+	assert.NoError(t, db.Put("a", "value"))
+	mockStub.MockTransactionEnd("42")
+
+	mockStub.MockTransactionStart("43")
+	db = NewReadOnlyLedgerDB(mockStub)
+	var value string
+	assert.NoError(t, db.Get("a", &value))
+	assert.Equal(t, "value", value)
+
+	db.event = &Event{}
+	_, errPrivate := db.PutPrivate(workerA, "b", "value")
+	for name, err := range map[string]error{
+		"Put":          db.Put("b", "value"),
+		"Add":          db.Add("b", "value"),
+		"Delete":       db.Delete("a"),
+		"CreateIndex":  db.CreateIndex("test~key", []string{"test", "b"}),
+		"DeleteIndex":  db.DeleteIndex("test~key", []string{"test", "a"}),
+		"SetEndorsers": db.SetEndorsers("a", []string{workerA}),
+		"PutPrivate":   errPrivate,
+		"SendEvent":    db.SendEvent(),
+	} {
+		if assert.Error(t, err, name) {
+			assert.Equal(t, 500, errors.Wrap(err).HTTPStatusCode(), name)
+		}
+	}
+	assert.Equal(t, 0, db.Stats().Writes)
+}
@@ -0,0 +1,150 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+)
+
+// redacted replaces the values which are not safe to log
+const redacted = "[redacted]"
+
+// logMaxPayloadSize is the size above which the logged args and responses are truncated, 0 meaning unlimited.
+// It is set by LOG_MAX_PAYLOAD_SIZE.
+var logMaxPayloadSize = 1024
+
+// logSafeFields lists, by function, the fields of the args and responses which are safe to log.
+// The fields listed for "" are safe for all the functions. The values of the other fields are redacted,
+// the objects and lists they hold are filtered the same way.
+var logSafeFields = map[string][]string{
+	"": {
+		"key", "keys", "status", "error", "bookmark", "asset_type",
+		"compute_plan_key", "rank", "worker", "creator", "owner", "priority",
+		"algo_key", "objective_key", "data_manager_key", "data_manager_keys", "data_sample_keys",
+		"traintuple_key", "testtuple_key", "in_models_keys", "template_key",
+		"done_count", "tuple_count",
+	},
+	"createComputePlan":           {"id", "in_models_ids", "traintuple_id", "in_head_model_id", "in_trunk_model_id", "clean_models", "id_to_key"},
+	"updateComputePlan":           {"id", "in_models_ids", "traintuple_id", "in_head_model_id", "in_trunk_model_id", "id_to_key"},
+	"validateComputePlan":         {"id", "in_models_ids", "traintuple_id", "in_head_model_id", "in_trunk_model_id", "valid", "errors", "type"},
+	"instantiateComputePlan":      {"rounds", "workers", "algos", "clean_models"},
+	"registerComputePlanTemplate": {"name", "rounds", "test_every", "aggregate_worker"},
+	"createProposal":              {"type", "node_id", "setting_name", "setting_value"},
+	"approveProposal":             {"type", "node_id", "setting_name", "setting_value", "approvals"},
+	"executeProposal":             {"type", "node_id", "setting_name", "setting_value", "approvals"},
+	"registerNode":                {"id"},
+	"queryNodes":                  {"id"},
+	"queryNextTasks":              {"n", "type"},
+	"queryContractMetadata":       {"name", "read_only", "paginated", "roles", "input", "output"},
+}
+
+// configureLogger sets the level, the format and the payload size of the logs from the environment:
+// LOG_LEVEL (a logrus level, info by default), LOG_FORMAT (text or json) and LOG_MAX_PAYLOAD_SIZE
+func configureLogger(logger *logrus.Logger) error {
+	logger.SetOutput(os.Stdout)
+
+	level := logrus.InfoLevel
+	if value := os.Getenv("LOG_LEVEL"); value != "" {
+		var err error
+		if level, err = logrus.ParseLevel(value); err != nil {
+			return fmt.Errorf("invalid LOG_LEVEL %s: %s", value, err)
+		}
+	}
+	logger.SetLevel(level)
+
+	switch format := os.Getenv("LOG_FORMAT"); format {
+	case "", "text":
+		logger.SetFormatter(&logrus.TextFormatter{
+			ForceColors:   true,
+			FullTimestamp: true,
+		})
+	case "json":
+		logger.SetFormatter(&logrus.JSONFormatter{})
+	default:
+		return fmt.Errorf("invalid LOG_FORMAT %s, expecting text or json", format)
+	}
+
+	if value := os.Getenv("LOG_MAX_PAYLOAD_SIZE"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 0 {
+			return fmt.Errorf("invalid LOG_MAX_PAYLOAD_SIZE %s, expecting a positive integer", value)
+		}
+		logMaxPayloadSize = size
+	}
+	return nil
+}
+
+// logPayload returns a JSON payload of a function as it can be logged: with the values of the fields
+// which are not safe redacted, and truncated to logMaxPayloadSize
+func logPayload(fn string, payload []byte) string {
+	var value interface{}
+	if err := json.Unmarshal(payload, &value); err != nil {
+		return redacted
+	}
+	safeFields := map[string]bool{}
+	for _, name := range append(logSafeFields[""], logSafeFields[fn]...) {
+		safeFields[name] = true
+	}
+	buff, _ := json.Marshal(redact(value, safeFields))
+	return truncate(string(buff))
+}
+
+// logArgs returns the args of a function as they can be logged
+func logArgs(fn string, args []string) []string {
+	res := []string{}
+	for _, arg := range args {
+		res = append(res, logPayload(fn, []byte(arg)))
+	}
+	return res
+}
+
+// redact replaces the scalar values which are not held by a safe field in a decoded JSON value.
+// Objects and lists are kept but filtered.
+func redact(value interface{}, safeFields map[string]bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		res := map[string]interface{}{}
+		for name, fieldValue := range v {
+			if safeFields[name] {
+				res[name] = fieldValue
+			} else {
+				res[name] = redact(fieldValue, safeFields)
+			}
+		}
+		return res
+	case []interface{}:
+		res := []interface{}{}
+		for _, item := range v {
+			res = append(res, redact(item, safeFields))
+		}
+		return res
+	case nil:
+		return nil
+	}
+	return redacted
+}
+
+// truncate shortens a logged payload to logMaxPayloadSize
+func truncate(payload string) string {
+	if logMaxPayloadSize == 0 || len(payload) <= logMaxPayloadSize {
+		return payload
+	}
+	return fmt.Sprintf("%s...(%d bytes)", payload[:logMaxPayloadSize], len(payload))
+}
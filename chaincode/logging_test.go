@@ -0,0 +1,76 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogPayloadRedaction(t *testing.T) {
+	payload := []byte(`{"key":"k1","name":"secret","metadata":{"owner":"o1","path":"/data"},"in_models_keys":["m1"],"tags":["t1"],"rank":2}`)
+	assert.JSONEq(t,
+		`{"key":"k1","name":"[redacted]","metadata":{"owner":"o1","path":"[redacted]"},"in_models_keys":["m1"],"tags":["[redacted]"],"rank":2}`,
+		logPayload("registerDataManager", payload))
+	// name is safe for the templates only
+	assert.JSONEq(t,
+		`{"key":"k1","name":"secret","metadata":{"owner":"o1","path":"[redacted]"},"in_models_keys":["m1"],"tags":["[redacted]"],"rank":2}`,
+		logPayload("registerComputePlanTemplate", payload))
+
+	assert.Equal(t, redacted, logPayload("registerDataManager", []byte("not json")))
+	assert.Equal(t, []string{`{"key":"k1"}`}, logArgs("queryTraintuple", []string{`{"key":"k1"}`}))
+}
+
+func TestLogPayloadTruncation(t *testing.T) {
+	defer func(size int) { logMaxPayloadSize = size }(logMaxPayloadSize)
+
+	payload := `{"keys":["` + strings.Repeat("a", 100) + `"]}`
+	logMaxPayloadSize = 20
+	assert.Equal(t, payload[:20]+"...(113 bytes)", logPayload("queryTraintuples", []byte(payload)))
+	logMaxPayloadSize = 0
+	assert.Equal(t, payload, logPayload("queryTraintuples", []byte(payload)))
+}
+
+func TestConfigureLogger(t *testing.T) {
+	defer func(size int) { logMaxPayloadSize = size }(logMaxPayloadSize)
+	defer os.Unsetenv("LOG_LEVEL")
+	defer os.Unsetenv("LOG_FORMAT")
+	defer os.Unsetenv("LOG_MAX_PAYLOAD_SIZE")
+
+	l := logrus.New()
+	assert.NoError(t, configureLogger(l))
+	assert.Equal(t, logrus.InfoLevel, l.GetLevel())
+	assert.IsType(t, &logrus.TextFormatter{}, l.Formatter)
+
+	os.Setenv("LOG_LEVEL", "debug")
+	os.Setenv("LOG_FORMAT", "json")
+	os.Setenv("LOG_MAX_PAYLOAD_SIZE", "42")
+	assert.NoError(t, configureLogger(l))
+	assert.Equal(t, logrus.DebugLevel, l.GetLevel())
+	assert.IsType(t, &logrus.JSONFormatter{}, l.Formatter)
+	assert.Equal(t, 42, logMaxPayloadSize)
+
+	for name, value := range map[string]string{"LOG_LEVEL": "verbose", "LOG_FORMAT": "xml", "LOG_MAX_PAYLOAD_SIZE": "-1"} {
+		t.Run(name, func(t *testing.T) {
+			defer os.Setenv(name, os.Getenv(name))
+			os.Setenv(name, value)
+			assert.Error(t, configureLogger(l))
+		})
+	}
+}
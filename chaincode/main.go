@@ -17,9 +17,8 @@ package main
 import (
 	"chaincode/errors"
 	"encoding/json"
-	"io/ioutil"
 	"math/rand"
-	"os"
+	"net/http"
 	"time"
 
 	"github.com/hyperledger/fabric-chaincode-go/shim"
@@ -41,8 +40,15 @@ var logger = logrus.New()
 func (t *SubstraChaincode) Init(stub shim.ChaincodeStubInterface) peer.Response {
 	// Get the args from the transaction proposal
 	args := stub.GetStringArgs()
-	if len(args) != 1 {
-		return shim.Error("Incorrect arguments. Expecting nothing...")
+	if len(args) < 1 || len(args) > 2 {
+		return shim.Error("Incorrect arguments. Expecting at most the governance of the network...")
+	}
+	// Bootstrap the admins of the network if given
+	if len(args) == 2 {
+		db := NewLedgerDB(stub)
+		if err := initGovernance(db, args[1:]); err != nil {
+			return formatErrorResponse(err)
+		}
 	}
 	return shim.Success(nil)
 }
@@ -51,8 +57,22 @@ func (t *SubstraChaincode) Init(stub shim.ChaincodeStubInterface) peer.Response
 func (t *SubstraChaincode) Invoke(stub shim.ChaincodeStubInterface) peer.Response {
 
 	start := time.Now()
-	// Log all input for potential debug later on.
-	logger.Infof("[%s][%s] Args received: '%s'", stub.GetChannelID(), stub.GetTxID()[:10], stub.GetStringArgs())
+
+	// Extract the function and args from the transaction proposal
+	fn, args := stub.GetFunctionAndParameters()
+	log := logger.WithFields(logrus.Fields{
+		"channel":  stub.GetChannelID(),
+		"tx_id":    stub.GetTxID(),
+		"function": fn,
+	})
+	// Log the input for potential debug later on, without the fields which are not safe to log
+	log.WithField("args", logArgs(fn, args)).Debug("args received")
+
+	// Reject the invocations received once the server is shutting down
+	if !invocations.begin() {
+		return formatErrorResponse(errors.Internal("the chaincode server is shutting down"))
+	}
+	defer invocations.end()
 
 	// Seed with a timestamp from the channel header so the chaincode's output
 	// stay determinist for each transaction. It's necessary because endorsers
@@ -64,170 +84,88 @@ func (t *SubstraChaincode) Invoke(stub shim.ChaincodeStubInterface) peer.Respons
 	seedTime := time.Unix(timestamp.GetSeconds(), int64(timestamp.GetNanos()))
 	rand.Seed(seedTime.UnixNano())
 
-	// Extract the function and args from the transaction proposal
-	fn, args := stub.GetFunctionAndParameters()
-
-	db := NewLedgerDB(stub)
+	// Record the metrics of the invocation, err is the one of the returned response
+	var db *LedgerDB
+	defer func() {
+		observeInvocation(fn, time.Since(start), err, db)
+	}()
+
+	// The read-only contracts can't write in the ledger
+	c, ok := contracts[fn]
+	if c.ReadOnly {
+		db = NewReadOnlyLedgerDB(stub)
+	} else {
+		db = NewLedgerDB(stub)
+	}
+	// The limits only apply to the transactions which can be submitted to the orderer
+	if ok && !c.ReadOnly {
+		if err = db.loadLimits(); err != nil {
+			return formatErrorResponse(err)
+		}
+	}
 
 	var result interface{}
-	hasBookmark := false
 	var bookmark string
-
-	switch fn {
-	case "createComputePlan":
-		result, err = createComputePlan(db, args)
-	case "createTesttuple":
-		result, err = createTesttuple(db, args)
-	case "createTraintuple":
-		result, err = createTraintuple(db, args)
-	case "createCompositeTraintuple":
-		result, err = createCompositeTraintuple(db, args)
-	case "createAggregatetuple":
-		result, err = createAggregatetuple(db, args)
-	case "cancelComputePlan":
-		result, err = cancelComputePlan(db, args)
-	case "logFailTest":
-		result, err = logFailTest(db, args)
-	case "logFailTrain":
-		result, err = logFailTrain(db, args)
-	case "logFailCompositeTrain":
-		result, err = logFailCompositeTrain(db, args)
-	case "logFailAggregate":
-		result, err = logFailAggregate(db, args)
-	case "logStartTest":
-		result, err = logStartTest(db, args)
-	case "logStartTrain":
-		result, err = logStartTrain(db, args)
-	case "logStartCompositeTrain":
-		result, err = logStartCompositeTrain(db, args)
-	case "logStartAggregate":
-		result, err = logStartAggregate(db, args)
-	case "logSuccessTest":
-		result, err = logSuccessTest(db, args)
-	case "logSuccessTrain":
-		result, err = logSuccessTrain(db, args)
-	case "logSuccessCompositeTrain":
-		result, err = logSuccessCompositeTrain(db, args)
-	case "logSuccessAggregate":
-		result, err = logSuccessAggregate(db, args)
-	case "queryAlgo":
-		result, err = queryAlgo(db, args)
-	case "queryAlgos":
-		result, bookmark, err = queryAlgos(db, args)
-		hasBookmark = true
-	case "queryCompositeAlgo":
-		result, err = queryCompositeAlgo(db, args)
-	case "queryCompositeAlgos":
-		result, bookmark, err = queryCompositeAlgos(db, args)
-		hasBookmark = true
-	case "queryAggregateAlgo":
-		result, err = queryAggregateAlgo(db, args)
-	case "queryAggregateAlgos":
-		result, bookmark, err = queryAggregateAlgos(db, args)
-		hasBookmark = true
-	case "queryDataManager":
-		result, err = queryDataManager(db, args)
-	case "queryDataManagers":
-		result, bookmark, err = queryDataManagers(db, args)
-		hasBookmark = true
-	case "queryDataSamples":
-		result, bookmark, err = queryDataSamples(db, args)
-		hasBookmark = true
-	case "queryDataset":
-		result, err = queryDataset(db, args)
-	case "queryFilter":
-		result, err = queryFilter(db, args)
-	case "queryModel":
-		result, err = queryModel(db, args)
-	case "queryModelDetails":
-		result, err = queryModelDetails(db, args)
-	case "queryModels":
-		result, bookmark, err = queryModels(db, args)
-		hasBookmark = true
-	case "queryObjective":
-		result, err = queryObjective(db, args)
-	case "queryObjectiveLeaderboard":
-		result, err = queryObjectiveLeaderboard(db, args)
-	case "queryObjectives":
-		result, bookmark, err = queryObjectives(db, args)
-		hasBookmark = true
-	case "queryTesttuple":
-		result, err = queryTesttuple(db, args)
-	case "queryTesttuples":
-		result, bookmark, err = queryTesttuples(db, args)
-		hasBookmark = true
-	case "queryTraintuple":
-		result, err = queryTraintuple(db, args)
-	case "queryCompositeTraintuple":
-		result, err = queryCompositeTraintuple(db, args)
-	case "queryAggregatetuple":
-		result, err = queryAggregatetuple(db, args)
-	case "queryTraintuples":
-		result, bookmark, err = queryTraintuples(db, args)
-		hasBookmark = true
-	case "queryCompositeTraintuples":
-		result, bookmark, err = queryCompositeTraintuples(db, args)
-		hasBookmark = true
-	case "queryAggregatetuples":
-		result, bookmark, err = queryAggregatetuples(db, args)
-		hasBookmark = true
-	case "queryComputePlan":
-		result, err = queryComputePlan(db, args)
-	case "queryComputePlans":
-		result, bookmark, err = queryComputePlans(db, args)
-		hasBookmark = true
-	case "registerAlgo":
-		result, err = registerAlgo(db, args)
-	case "registerCompositeAlgo":
-		result, err = registerCompositeAlgo(db, args)
-	case "registerAggregateAlgo":
-		result, err = registerAggregateAlgo(db, args)
-	case "registerDataManager":
-		result, err = registerDataManager(db, args)
-	case "registerDataSample":
-		result, err = registerDataSample(db, args)
-	case "registerObjective":
-		result, err = registerObjective(db, args)
-	case "updateComputePlan":
-		result, err = updateComputePlan(db, args)
-	case "updateDataManager":
-		result, err = updateDataManager(db, args)
-	case "updateDataSample":
-		result, err = updateDataSample(db, args)
-	case "registerNode":
-		result, err = registerNode(db, args)
-	case "queryNodes":
-		result, err = queryNodes(db, args)
-	default:
+	if !ok {
 		err = errors.BadRequest("function \"%s\" not implemented", fn)
+	} else if err = checkContractPolicy(db, c); err == nil {
+		// The roles of the transaction creator are checked before calling the contract
+		result, bookmark, err = c.invoke(db, args)
 	}
 
 	// Invoke duration
 	duration := int(time.Since(start).Nanoseconds()) / 1e6
+	log = log.WithFields(logrus.Fields{
+		"duration_ms": duration,
+		"stats":       db.Stats(),
+	})
 	// Return the result as success payload
 	if err != nil {
-		logger.Errorf("[%s][%s] Response (%dms): '%#v','%s' - Error: '%s'", stub.GetChannelID(), stub.GetTxID()[:10], duration, result, bookmark, err)
+		log.WithFields(logrus.Fields{
+			"status": errors.Wrap(err).HTTPStatusCode(),
+			"error":  err.Error(),
+		}).Error("response")
 		return formatErrorResponse(err)
 	}
 
 	// Add bookmark (if any) to response
-	if hasBookmark {
+	if c.Paginated {
 		result = map[string]interface{}{
 			"results":  result,
 			"bookmark": bookmark,
 		}
 	}
 
+	// Add the ledger accesses to the response when requested in the transient data
+	if transient, _ := stub.GetTransient(); transient[debugTransientKey] != nil {
+		if c.Paginated {
+			result.(map[string]interface{})["debug"] = db.Stats()
+		} else {
+			result = map[string]interface{}{
+				"results": result,
+				"debug":   db.Stats(),
+			}
+		}
+	}
+
 	// Marshal to json the smartcontract result
 	resp, err := json.Marshal(result)
 
 	if err != nil {
-		logger.Infof("[%s][%s] Response (%dms): '%#v','%s'", stub.GetChannelID(), stub.GetTxID()[:10], duration, result, bookmark)
-		return formatErrorResponse(errors.Internal("could not format response: %s", err.Error()))
+		err = errors.Internal("could not format response: %s", err.Error())
+		log.WithFields(logrus.Fields{
+			"status": errors.Wrap(err).HTTPStatusCode(),
+			"error":  err.Error(),
+		}).Error("response")
+		return formatErrorResponse(err)
 	}
 
-	// Log with no errors
-	logger.Infof("[%s][%s] Response (%dms): '%s'", stub.GetChannelID(), stub.GetTxID()[:10], duration, resp)
+	// Log with no errors, the payload only in debug
+	log = log.WithField("status", shim.OK)
+	if logger.IsLevelEnabled(logrus.DebugLevel) {
+		log = log.WithField("response", logPayload(fn, resp))
+	}
+	log.Info("response")
 
 	// Send event if there is any. It's done in one batch since we can only send
 	// one event per call
@@ -262,47 +200,44 @@ func formatErrorResponse(err error) peer.Response {
 }
 
 func main() {
-	logger.SetFormatter(&logrus.TextFormatter{
-		ForceColors:   true,
-		FullTimestamp: true,
-	})
-
-	logger.SetOutput(os.Stdout)
-	logger.SetLevel(logrus.DebugLevel)
-	logger.Infof("Load TLS certificates")
-
-	key, err := ioutil.ReadFile(os.Getenv("TLS_KEY_FILE"))
-	if err != nil {
-		logger.Errorf("Cannot read key file: %s", err)
+	if err := configureLogger(logger); err != nil {
+		logger.Fatalf("Invalid logging configuration: %s", err)
 	}
-
-	cert, err := ioutil.ReadFile(os.Getenv("TLS_CERT_FILE"))
+	config, err := loadServerConfig()
 	if err != nil {
-		logger.Errorf("Cannot read cert file: %s", err)
+		logger.Fatalf("Invalid chaincode server configuration: %s", err)
+	}
+	if config.TLSDisabled {
+		logger.Warnf("TLS is disabled, for development only")
 	}
 
-	ca, err := ioutil.ReadFile(os.Getenv("TLS_ROOTCERT_FILE"))
-	if err != nil {
-		logger.Errorf("Cannot read ca cert file: %s", err)
+	// Serve the probes and the metrics if requested
+	if config.HTTPAddress != "" {
+		go func() {
+			logger.Infof("Serve probes and metrics on %s", config.HTTPAddress)
+			if err := http.ListenAndServe(config.HTTPAddress, newHTTPHandler()); err != nil {
+				logger.Fatalf("Cannot serve probes and metrics: %s", err)
+			}
+		}()
 	}
 
 	server := &shim.ChaincodeServer{
-		CCID:    os.Getenv("CHAINCODE_CCID"),
-		Address: os.Getenv("CHAINCODE_ADDRESS"),
+		CCID:    config.CCID,
+		Address: config.Address,
 		CC:      new(SubstraChaincode),
 		TLSProps: shim.TLSProperties{
-			Disabled:      false,
-			Key:           key,
-			Cert:          cert,
-			ClientCACerts: ca,
+			Disabled:      config.TLSDisabled,
+			Key:           config.Key,
+			Cert:          config.Cert,
+			ClientCACerts: config.ClientCACerts,
 		},
 	}
 
 	// Start the chaincode external server
+	go shutdownOnSignal(config.ShutdownTimeout)
 	logger.Infof("Start Substra ChaincodeServer")
-	err = server.Start()
-
-	if err != nil {
-		logger.Errorf("Error starting SubstraChaincode chaincode: %s", err)
+	invocations.setReady(true)
+	if err := server.Start(); err != nil {
+		logger.Fatalf("Error starting SubstraChaincode chaincode: %s", err)
 	}
 }
@@ -0,0 +1,91 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// The metrics are exposed in the Prometheus text format by the chaincode server
+// when HTTP_ADDRESS is set. They are local to the peer running the chaincode.
+var (
+	invocationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "substra_chaincode_invocations_total",
+		Help: "Number of invocations by function.",
+	}, []string{"function"})
+	invocationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "substra_chaincode_invocation_duration_seconds",
+		Help:    "Duration of the invocations by function.",
+		Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"function"})
+	errorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "substra_chaincode_errors_total",
+		Help: "Number of failed invocations by function and error kind.",
+	}, []string{"function", "kind"})
+	eventPayloadSize = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "substra_chaincode_event_payload_bytes",
+		Help:    "Size of the chaincode event payloads.",
+		Buckets: []float64{256, 1024, 4096, 16384, 65536, 262144, 1048576},
+	})
+	tuplesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "substra_chaincode_tuples_total",
+		Help: "Number of tuples created or transitioned by type and status.",
+	}, []string{"type", "status"})
+
+	metricsRegistry = prometheus.NewRegistry()
+)
+
+func init() {
+	metricsRegistry.MustRegister(invocationsTotal, invocationDuration, errorsTotal, eventPayloadSize, tuplesTotal)
+}
+
+// metricsHandler serves all the metrics in the Prometheus text format
+var metricsHandler http.Handler = promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+
+// metricsFunction returns the function label of an invocation. Functions which are not
+// contracts share the same label, so that callers can't create an unbounded number of series.
+func metricsFunction(fn string) string {
+	if _, ok := contracts[fn]; !ok {
+		return "unknown"
+	}
+	return fn
+}
+
+// observeInvocation records the metrics of an invocation once it returned.
+// The tuples and the event are only counted for successful invocations. They are counted
+// when the peer endorses the invocation, the transaction might still not be committed.
+func observeInvocation(fn string, duration time.Duration, err error, db *LedgerDB) {
+	fn = metricsFunction(fn)
+	invocationsTotal.WithLabelValues(fn).Inc()
+	invocationDuration.WithLabelValues(fn).Observe(duration.Seconds())
+	if err != nil {
+		errorsTotal.WithLabelValues(fn, errors.Wrap(err).Kind.String()).Inc()
+		return
+	}
+	if db == nil {
+		return
+	}
+	for _, transition := range db.TupleTransitions() {
+		tuplesTotal.WithLabelValues(transition.AssetType.String(), transition.Status).Inc()
+	}
+	if size := db.EventPayloadSize(); size > 0 {
+		eventPayloadSize.Observe(float64(size))
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "traintuple")
+	resp := mockStub.MockInvoke([][]byte{[]byte("notAContract")})
+	assert.EqualValues(t, 400, resp.Status)
+
+	recorder := httptest.NewRecorder()
+	metricsHandler.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body := recorder.Body.String()
+	assert.Contains(t, body, "# TYPE substra_chaincode_invocations_total counter")
+	assert.Contains(t, body, `substra_chaincode_invocations_total{function="createTraintuple"}`)
+	assert.Contains(t, body, `substra_chaincode_invocation_duration_seconds_count{function="createTraintuple"}`)
+	assert.Contains(t, body, `substra_chaincode_errors_total{function="unknown",kind="bad_request"}`)
+	assert.NotContains(t, body, "notAContract")
+	assert.Contains(t, body, `substra_chaincode_tuples_total{status="todo",type="traintuple"}`)
+	assert.Contains(t, body, "substra_chaincode_event_payload_bytes_count ")
+}
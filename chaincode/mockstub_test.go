@@ -16,11 +16,21 @@ package main
 
 import (
 	"container/list"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/gogo/protobuf/proto"
 	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric-chaincode-go/pkg/attrmgr"
 	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
 	"github.com/hyperledger/fabric-protos-go/msp"
@@ -44,6 +54,10 @@ type MockStub struct {
 	// The transaction creator
 	Creator string
 
+	// The roles of the transaction creator, stored as an attribute of its certificate.
+	// No attribute is set when empty.
+	CreatorRole string
+
 	// arguments the stub was called with
 	args [][]byte
 
@@ -84,6 +98,9 @@ type MockStub struct {
 	ChaincodeEventsChannel chan *pb.ChaincodeEvent
 
 	Decorations map[string][]byte
+
+	// transient data of the proposal
+	Transient map[string][]byte
 }
 
 func (stub *MockStub) GetTxID() string {
@@ -398,18 +415,56 @@ yuGnBXj8ytqU0CwIPX4WecigUCAkVDNx
 -----END CERTIFICATE-----
 `
 
+// fakeCertificatesWithRole caches the certificates generated for each role
+var fakeCertificatesWithRole = map[string][]byte{}
+
+// getFakeCertificateWithRole returns a self-signed certificate with the role as attribute
+func getFakeCertificateWithRole(role string) ([]byte, error) {
+	if cert, ok := fakeCertificatesWithRole[role]; ok {
+		return cert, nil
+	}
+	attrs, err := json.Marshal(attrmgr.Attributes{Attrs: map[string]string{RoleAttribute: role}})
+	if err != nil {
+		return nil, err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:    big.NewInt(1),
+		Subject:         pkix.Name{CommonName: "substra"},
+		NotBefore:       time.Now(),
+		NotAfter:        time.Now().Add(time.Hour),
+		ExtraExtensions: []pkix.Extension{{Id: attrmgr.AttrOID, Value: attrs}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	fakeCertificatesWithRole[role] = cert
+	return cert, nil
+}
+
 func (stub *MockStub) GetCreator() ([]byte, error) {
+	idBytes := []byte(fakeCertificate)
+	if stub.CreatorRole != "" {
+		var err error
+		if idBytes, err = getFakeCertificateWithRole(stub.CreatorRole); err != nil {
+			return nil, err
+		}
+	}
 	sid := &msp.SerializedIdentity{
 		Mspid:   stub.Creator,
-		IdBytes: []byte(fakeCertificate),
+		IdBytes: idBytes,
 	}
 
 	return proto.Marshal(sid)
 }
 
-// Not implemented
 func (stub *MockStub) GetTransient() (map[string][]byte, error) {
-	return nil, nil
+	return stub.Transient, nil
 }
 
 // Not implemented
@@ -488,6 +543,7 @@ func (stub *MockStub) GetPrivateDataValidationParameter(collection, key string)
 func NewMockStub(name string, cc shim.Chaincode) *MockStub {
 	s := new(MockStub)
 	s.Creator = workerA
+	s.CreatorRole = strings.Join([]string{RoleAdmin, RoleWorker, RoleUser}, ",")
 	s.Name = name
 	s.cc = cc
 	s.State = make(map[string][]byte)
@@ -18,36 +18,60 @@ import (
 	"chaincode/errors"
 )
 
+// registerNode registers the node of the transaction requester.
+// The node can declare the maximum number of tasks it runs concurrently and its capacity,
+// registering it again updates the values provided and keeps the other ones.
 func registerNode(db *LedgerDB, args []string) (Node, error) {
+	inp := inputNode{}
+	if len(args) > 1 {
+		return Node{}, errors.BadRequest("incorrect number of arguments, expecting at most one argument")
+	}
+	if len(args) == 1 && args[0] != "" {
+		if err := AssetFromJSON(args, &inp); err != nil {
+			return Node{}, err
+		}
+	}
+
 	txCreator, err := GetTxCreator(db.cc)
 	if err != nil {
 		return Node{}, err
 	}
 
-	node := Node{}
-	node.ID = txCreator
-
 	// Not using db.Add because we need to handle conflict as silent event without errors
-	exists, err := db.KeyExists(node.ID)
-	if err != nil {
+	node, err := db.GetNode(txCreator)
+	exists := err == nil
+	if err != nil && !errors.IsNotFound(err) {
 		return Node{}, err
 	}
+	node.ID = txCreator
+	if inp.MaxConcurrentTasks != nil {
+		node.MaxConcurrentTasks = *inp.MaxConcurrentTasks
+	}
+	if inp.Capacity != nil {
+		capacity := NewResources(*inp.Capacity)
+		node.Capacity = &capacity
+	}
 
 	if exists {
+		if err = db.Put(node.ID, node); err != nil {
+			return Node{}, err
+		}
 		return node, nil
 	}
 
-	err = db.Put(node.ID, node)
+	// Once the governance is initialized, only admins can register themselves,
+	// other nodes are added through proposals
+	governed, err := hasGovernance(db)
 	if err != nil {
 		return Node{}, err
 	}
-
-	err = db.CreateIndex("node~key", []string{"node", node.ID})
-	if err != nil {
-		return Node{}, err
+	if governed {
+		if _, _, err = checkGovernanceAdmin(db); err != nil {
+			return Node{}, errors.Forbidden(err, "nodes must be added through a governance proposal")
+		}
 	}
 
-	return node, nil
+	return addNode(db, node)
 }
 
 func queryNodes(db *LedgerDB, args []string) (nodes []Node, err error) {
@@ -18,6 +18,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNode(t *testing.T) {
@@ -44,3 +45,32 @@ func TestQueryNodes(t *testing.T) {
 	assert.EqualValuesf(t, 200, response.Status, "Node Created")
 	assert.Contains(t, string(response.Payload), "\"id\":\"SampleOrg\"", "Query nodes")
 }
+
+func TestNodeUpdate(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	maxConcurrentTasks := 2
+	_, err := registerNode(db, assetToArgs(inputNode{MaxConcurrentTasks: &maxConcurrentTasks, Capacity: &inputResources{CPU: 8}}))
+	require.NoError(t, err)
+
+	// Registering again without arguments keeps the declared values
+	node, err := registerNode(db, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, node.MaxConcurrentTasks)
+	require.NotNil(t, node.Capacity)
+	assert.Equal(t, 8, node.Capacity.CPU)
+
+	// Only the provided fields are updated
+	maxConcurrentTasks = 0
+	node, err = registerNode(db, assetToArgs(inputNode{MaxConcurrentTasks: &maxConcurrentTasks}))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, node.MaxConcurrentTasks)
+	require.NotNil(t, node.Capacity)
+	assert.Equal(t, 8, node.Capacity.CPU)
+	stored, err := db.GetNode(workerA)
+	assert.NoError(t, err)
+	assert.Equal(t, node, stored)
+}
@@ -38,6 +38,7 @@ func (objective *Objective) Set(db *LedgerDB, inp inputObjective) (dataManagerKe
 			DataManagerKey: dataManagerKey,
 			DataSampleKeys: dataSampleKeys,
 		}
+		objective.TestDatasetVersion = 1
 	} else {
 		objective.TestDataset = nil
 	}
@@ -149,8 +150,90 @@ func queryObjectives(db *LedgerDB, args []string) (outObjectives []outputObjecti
 	return
 }
 
+// updateObjectiveTestDataset sets the test dataset of an objective, extends it with new test only dataSamples
+// or, when replace is set, replaces it with another dataManager or another set of dataSamples.
+// Each update creates a new version of the test dataset: the testtuples certified on a previous version
+// are kept but only appear in the leaderboard of the version they were evaluated on.
+func updateObjectiveTestDataset(db *LedgerDB, args []string) (resp outputKey, err error) {
+	inp := inputUpdateObjectiveTestDataset{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	objective, err := db.GetObjective(inp.ObjectiveKey)
+	if err != nil {
+		return
+	}
+	txCreator, err := GetTxCreator(db.cc)
+	if err != nil {
+		return
+	}
+	if objective.Owner != txCreator {
+		err = errors.Forbidden("%s is not the owner of the objective %s", txCreator, inp.ObjectiveKey)
+		return
+	}
+	dataManager, err := db.GetDataManager(inp.DataManagerKey)
+	if err != nil {
+		err = errors.BadRequest(err, "could not retrieve dataManager with key %s", inp.DataManagerKey)
+		return
+	}
+	testOnly, _, err := checkSameDataManager(db, inp.DataManagerKey, inp.DataSampleKeys)
+	if err != nil {
+		err = errors.BadRequest(err, "invalid test dataSample")
+		return
+	} else if !testOnly {
+		err = errors.BadRequest("test dataSample are not tagged as testOnly dataSample")
+		return
+	}
+
+	switch {
+	case objective.TestDataset == nil || inp.Replace:
+		unchanged := objective.TestDataset != nil && objective.TestDataset.DataManagerKey == inp.DataManagerKey &&
+			len(objective.TestDataset.DataSampleKeys) == len(inp.DataSampleKeys)
+		for _, dataSampleKey := range inp.DataSampleKeys {
+			unchanged = unchanged && stringInSlice(dataSampleKey, objective.TestDataset.DataSampleKeys)
+		}
+		if unchanged {
+			err = errors.BadRequest("the test dataset of objective %s already has these dataSamples", inp.ObjectiveKey)
+			return
+		}
+		objective.TestDataset = &Dataset{
+			DataManagerKey: inp.DataManagerKey,
+			DataSampleKeys: inp.DataSampleKeys,
+		}
+		if dataManager.ObjectiveKey != objective.Key {
+			if err = addObjectiveDataManager(db, inp.DataManagerKey, objective.Key); err != nil {
+				return
+			}
+		}
+	case objective.TestDataset.DataManagerKey != inp.DataManagerKey:
+		err = errors.BadRequest("the test dataset of objective %s belongs to dataManager %s", inp.ObjectiveKey, objective.TestDataset.DataManagerKey)
+		return
+	default:
+		var newDataSampleKeys []string
+		for _, dataSampleKey := range inp.DataSampleKeys {
+			if !stringInSlice(dataSampleKey, objective.TestDataset.DataSampleKeys) {
+				newDataSampleKeys = append(newDataSampleKeys, dataSampleKey)
+			}
+		}
+		if len(newDataSampleKeys) == 0 {
+			err = errors.BadRequest("dataSamples are already in the test dataset of objective %s", inp.ObjectiveKey)
+			return
+		}
+		objective.TestDataset.DataSampleKeys = append(objective.TestDataset.DataSampleKeys, newDataSampleKeys...)
+	}
+	objective.TestDatasetVersion++
+
+	if err = db.Put(objective.Key, objective); err != nil {
+		return
+	}
+	return outputKey{Key: objective.Key}, nil
+}
+
 // getObjectiveLeaderboard returns for an objective, all its certified testtuples with a done status, ordered by their perf
 // It can be an ascending sort or not depending on the ascendingOrder value.
+// Only the testtuples evaluated on the requested version of the test dataset are returned, the current one by default,
+// and the testtuples using revoked dataSamples are left out.
 func queryObjectiveLeaderboard(db *LedgerDB, args []string) (outputLeaderboard, error) {
 	inp := inputLeaderboard{}
 	err := AssetFromJSON(args, &inp)
@@ -166,18 +249,32 @@ func queryObjectiveLeaderboard(db *LedgerDB, args []string) (outputLeaderboard,
 	outObjective.Fill(objective)
 	out := outputLeaderboard{Objective: outObjective, Testtuples: []outputBoardTuple{}}
 
+	testDatasetVersion := objective.TestDatasetVersion
+	if inp.TestDatasetVersion > 0 {
+		testDatasetVersion = inp.TestDatasetVersion
+	}
+
 	testtupleKeys, err := db.GetIndexKeys("testtuple~objective~certified~key", []string{"testtuple", inp.ObjectiveKey, "true"})
 	if err != nil {
 		return outputLeaderboard{}, err
 	}
 
+	revoked := map[string]bool{}
 	for _, testtupleKey := range testtupleKeys {
 		var boardTuple outputBoardTuple
 		testtuple, err := db.GetTesttuple(testtupleKey)
 		if err != nil {
 			return outputLeaderboard{}, err
 		}
-		if testtuple.Status != StatusDone {
+		if testtuple.Status != StatusDone || testtuple.TestDatasetVersion != testDatasetVersion {
+			continue
+		}
+		// the evaluations on revoked dataSamples are not ranked
+		usesRevoked, err := usesRevokedDataSamples(db, testtuple.Dataset.DataSampleKeys, revoked)
+		if err != nil {
+			return outputLeaderboard{}, err
+		}
+		if usesRevoked {
 			continue
 		}
 		err = boardTuple.Fill(db, testtuple, testtupleKey)
@@ -199,6 +296,26 @@ func queryObjectiveLeaderboard(db *LedgerDB, args []string) (outputLeaderboard,
 // Utils for objectivess
 // -------------------------------------------------------------------------------------------
 
+// usesRevokedDataSamples returns whether one of the dataSamples is revoked.
+// The revocation status of the dataSamples already read is cached in revoked.
+func usesRevokedDataSamples(db *LedgerDB, dataSampleKeys []string, revoked map[string]bool) (bool, error) {
+	for _, dataSampleKey := range dataSampleKeys {
+		isRevoked, ok := revoked[dataSampleKey]
+		if !ok {
+			dataSample, err := db.GetDataSample(dataSampleKey)
+			if err != nil {
+				return false, err
+			}
+			isRevoked = dataSample.Revoked
+			revoked[dataSampleKey] = isRevoked
+		}
+		if isRevoked {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // addObjectiveDataManager associates a objective to a dataManager, more precisely, it adds the objective key to the dataManager
 func addObjectiveDataManager(db *LedgerDB, dataManagerKey string, objectiveKey string) error {
 	dataManager, err := db.GetDataManager(dataManagerKey)
@@ -15,6 +15,7 @@
 package main
 
 import (
+	"chaincode/errors"
 	"encoding/json"
 	"testing"
 
@@ -71,6 +72,176 @@ func TestLeaderBoard(t *testing.T) {
 	assert.Equal(t, algoName, leaderboard.Testtuples[0].Algo.Name)
 	assert.Equal(t, algoStorageAddress, leaderboard.Testtuples[0].Algo.StorageAddress)
 }
+
+func TestLeaderBoardRevokedDataSamples(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	db := NewLedgerDB(mockStub)
+	registerItem(t, *mockStub, "")
+	mockStub.MockTransactionStart("42")
+
+	inputTest := inputTesttuple{}
+	inputTest.fillDefaults()
+	keyMap, err := createTesttuple(db, assetToArgs(inputTest))
+	require.NoError(t, err)
+	testtuple, err := db.GetTesttuple(keyMap.Key)
+	require.NoError(t, err)
+	testtuple.Status = StatusDone
+	require.NoError(t, db.Put(keyMap.Key, testtuple))
+
+	_, err = revokeDataSamples(db, assetToArgs(inputRevokeDataSamples{Keys: []string{testDataSampleKey1}}))
+	require.NoError(t, err)
+
+	// The evaluation on the revoked dataSample is no longer ranked
+	leaderboard, err := queryObjectiveLeaderboard(db, assetToArgs(inputLeaderboard{ObjectiveKey: objectiveKey}))
+	assert.NoError(t, err)
+	assert.Len(t, leaderboard.Testtuples, 0)
+
+	// and new certified testtuples are evaluated on the remaining dataSamples
+	inputTest.Key = RandomUUID()
+	keyMap, err = createTesttuple(db, assetToArgs(inputTest))
+	require.NoError(t, err)
+	testtuple, err = db.GetTesttuple(keyMap.Key)
+	require.NoError(t, err)
+	assert.True(t, testtuple.Certified)
+	assert.Equal(t, []string{testDataSampleKey2}, testtuple.Dataset.DataSampleKeys)
+	testtuple.Status = StatusDone
+	require.NoError(t, db.Put(keyMap.Key, testtuple))
+
+	leaderboard, err = queryObjectiveLeaderboard(db, assetToArgs(inputLeaderboard{ObjectiveKey: objectiveKey}))
+	assert.NoError(t, err)
+	require.Len(t, leaderboard.Testtuples, 1)
+	assert.Equal(t, keyMap.Key, leaderboard.Testtuples[0].Key)
+
+	// until all the test dataSamples are revoked
+	_, err = revokeDataSamples(db, assetToArgs(inputRevokeDataSamples{Keys: []string{testDataSampleKey2}}))
+	require.NoError(t, err)
+	inputTest.Key = RandomUUID()
+	_, err = createTesttuple(db, assetToArgs(inputTest))
+	assert.Error(t, err)
+	assert.Equal(t, 400, errors.Wrap(err).HTTPStatusCode())
+}
+
+func TestUpdateObjectiveTestDataset(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	db := NewLedgerDB(mockStub)
+	registerItem(t, *mockStub, "")
+	mockStub.MockTransactionStart("42")
+
+	// Add a certified testtuple on the first version of the test dataset
+	inputTest := inputTesttuple{}
+	inputTest.fillDefaults()
+	keyMap, err := createTesttuple(db, assetToArgs(inputTest))
+	assert.NoError(t, err)
+	testtuple, err := db.GetTesttuple(keyMap.Key)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, testtuple.TestDatasetVersion)
+	testtuple.Status = StatusDone
+	err = db.Put(keyMap.Key, testtuple)
+	assert.NoError(t, err)
+
+	testDataSampleKey3 := "bb3bb7c3-1f62-244c-0f3a-761cc1688042"
+	inpDataSample := inputDataSample{
+		Keys:     []string{testDataSampleKey3},
+		TestOnly: "true",
+	}
+	resp := mockStub.MockInvoke(inpDataSample.createDefault())
+	require.EqualValuesf(t, 200, resp.Status, "when adding test dataSample with status %d and message %s", resp.Status, resp.Message)
+	mockStub.MockTransactionStart("42")
+
+	inp := inputUpdateObjectiveTestDataset{
+		ObjectiveKey:   objectiveKey,
+		DataManagerKey: dataManagerKey,
+		DataSampleKeys: []string{trainDataSampleKey1},
+	}
+	_, err = updateObjectiveTestDataset(db, assetToArgs(inp))
+	assert.Error(t, err, "train only dataSamples can not be added to a test dataset")
+
+	inp.DataSampleKeys = []string{testDataSampleKey3}
+	mockStub.Creator = workerB
+	_, err = updateObjectiveTestDataset(db, assetToArgs(inp))
+	assert.Error(t, err, "only the objective owner can update its test dataset")
+	mockStub.Creator = workerA
+
+	_, err = updateObjectiveTestDataset(db, assetToArgs(inp))
+	assert.NoError(t, err)
+	objective, err := queryObjective(db, keyToArgs(objectiveKey))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, objective.TestDatasetVersion)
+	assert.Equal(t, []string{testDataSampleKey1, testDataSampleKey2, testDataSampleKey3}, objective.TestDataset.DataSampleKeys)
+
+	_, err = updateObjectiveTestDataset(db, assetToArgs(inp))
+	assert.Error(t, err, "the dataSamples are already in the test dataset")
+
+	// Replacing the test dataset removes the dataSamples left out
+	inp.Replace = true
+	inp.DataSampleKeys = []string{testDataSampleKey2, testDataSampleKey3}
+	_, err = updateObjectiveTestDataset(db, assetToArgs(inp))
+	assert.NoError(t, err)
+	objective, err = queryObjective(db, keyToArgs(objectiveKey))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, objective.TestDatasetVersion)
+	assert.Equal(t, []string{testDataSampleKey2, testDataSampleKey3}, objective.TestDataset.DataSampleKeys)
+
+	_, err = updateObjectiveTestDataset(db, assetToArgs(inp))
+	assert.Error(t, err, "the test dataset is replaced with the same dataSamples")
+
+	// The testtuple certified on the previous version only appears in the previous leaderboard
+	inpLeaderboard := inputLeaderboard{ObjectiveKey: objectiveKey}
+	leaderboard, err := queryObjectiveLeaderboard(db, assetToArgs(inpLeaderboard))
+	assert.NoError(t, err)
+	assert.Len(t, leaderboard.Testtuples, 0)
+
+	inpLeaderboard.TestDatasetVersion = 1
+	leaderboard, err = queryObjectiveLeaderboard(db, assetToArgs(inpLeaderboard))
+	assert.NoError(t, err)
+	require.Len(t, leaderboard.Testtuples, 1)
+	assert.Equal(t, keyMap.Key, leaderboard.Testtuples[0].Key)
+}
+
+func TestReplaceObjectiveTestDataManager(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	db := NewLedgerDB(mockStub)
+	registerItem(t, *mockStub, "")
+
+	// Add a second dataManager with its own test only dataSample
+	otherDataManagerKey := "da2bb7c3-1f62-244c-0f3a-761cc1688042"
+	otherDataSampleKey := "bb4bb7c3-1f62-244c-0f3a-761cc1688042"
+	inpDataManager := inputDataManager{Key: otherDataManagerKey}
+	resp := mockStub.MockInvoke(inpDataManager.createDefault())
+	require.EqualValuesf(t, 200, resp.Status, "when adding dataManager with status %d and message %s", resp.Status, resp.Message)
+	inpDataSample := inputDataSample{
+		Keys:            []string{otherDataSampleKey},
+		DataManagerKeys: []string{otherDataManagerKey},
+		TestOnly:        "true",
+	}
+	resp = mockStub.MockInvoke(inpDataSample.createDefault())
+	require.EqualValuesf(t, 200, resp.Status, "when adding test dataSample with status %d and message %s", resp.Status, resp.Message)
+	mockStub.MockTransactionStart("42")
+
+	inp := inputUpdateObjectiveTestDataset{
+		ObjectiveKey:   objectiveKey,
+		DataManagerKey: otherDataManagerKey,
+		DataSampleKeys: []string{otherDataSampleKey},
+	}
+	_, err := updateObjectiveTestDataset(db, assetToArgs(inp))
+	assert.Error(t, err, "the dataManager of the test dataset can only be changed by a replacement")
+
+	inp.Replace = true
+	_, err = updateObjectiveTestDataset(db, assetToArgs(inp))
+	assert.NoError(t, err)
+	objective, err := queryObjective(db, keyToArgs(objectiveKey))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, objective.TestDatasetVersion)
+	assert.Equal(t, otherDataManagerKey, objective.TestDataset.DataManagerKey)
+	assert.Equal(t, []string{otherDataSampleKey}, objective.TestDataset.DataSampleKeys)
+	dataManager, err := db.GetDataManager(otherDataManagerKey)
+	assert.NoError(t, err)
+	assert.Equal(t, objectiveKey, dataManager.ObjectiveKey)
+}
+
 func TestRegisterObjectiveWhitoutDataset(t *testing.T) {
 	scc := new(SubstraChaincode)
 	mockStub := NewMockStubWithRegisterNode("substra", scc)
@@ -164,7 +335,8 @@ func TestObjective(t *testing.T) {
 			DataSampleKeys: []string{testDataSampleKey1, testDataSampleKey2},
 			Metadata:       map[string]string{},
 		},
-		Name: inpObjective.Name,
+		TestDatasetVersion: 1,
+		Name:               inpObjective.Name,
 		Description: &ChecksumAddress{
 			StorageAddress: inpObjective.DescriptionStorageAddress,
 			Checksum:       objectiveDescriptionChecksum,
@@ -25,14 +25,15 @@ const OutputPageSize = 500
 // Struct use as output representation of ledger data
 
 type outputObjective struct {
-	Key         string               `json:"key"`
-	Name        string               `json:"name"`
-	Description *ChecksumAddress     `json:"description"`
-	Metrics     *ChecksumAddressName `json:"metrics"`
-	Owner       string               `json:"owner"`
-	TestDataset *Dataset             `json:"test_dataset"`
-	Permissions outputPermissions    `json:"permissions"`
-	Metadata    map[string]string    `json:"metadata"`
+	Key                string               `json:"key"`
+	Name               string               `json:"name"`
+	Description        *ChecksumAddress     `json:"description"`
+	Metrics            *ChecksumAddressName `json:"metrics"`
+	Owner              string               `json:"owner"`
+	TestDataset        *Dataset             `json:"test_dataset"`
+	TestDatasetVersion int                  `json:"test_dataset_version"`
+	Permissions        outputPermissions    `json:"permissions"`
+	Metadata           map[string]string    `json:"metadata"`
 }
 
 func (out *outputObjective) Fill(in Objective) {
@@ -45,21 +46,23 @@ func (out *outputObjective) Fill(in Objective) {
 	if out.TestDataset != nil {
 		out.TestDataset.Metadata = initMapOutput(in.TestDataset.Metadata)
 	}
+	out.TestDatasetVersion = in.TestDatasetVersion
 	out.Permissions.Fill(in.Permissions)
 	out.Metadata = initMapOutput(in.Metadata)
 }
 
 // outputDataManager is the return representation of the DataManager type stored in the ledger
 type outputDataManager struct {
-	ObjectiveKey string            `json:"objective_key"`
-	Description  *ChecksumAddress  `json:"description"`
-	Key          string            `json:"key"`
-	Metadata     map[string]string `json:"metadata"`
-	Name         string            `json:"name"`
-	Opener       *ChecksumAddress  `json:"opener"`
-	Owner        string            `json:"owner"`
-	Permissions  outputPermissions `json:"permissions"`
-	Type         string            `json:"type"`
+	ObjectiveKey  string            `json:"objective_key"`
+	Description   *ChecksumAddress  `json:"description"`
+	Key           string            `json:"key"`
+	Metadata      map[string]string `json:"metadata"`
+	Name          string            `json:"name"`
+	Opener        *ChecksumAddress  `json:"opener"`
+	Owner         string            `json:"owner"`
+	Permissions   outputPermissions `json:"permissions"`
+	PrivacyBudget *PrivacyBudget    `json:"privacy_budget"`
+	Type          string            `json:"type"`
 }
 
 func (out *outputDataManager) Fill(in DataManager) {
@@ -71,19 +74,53 @@ func (out *outputDataManager) Fill(in DataManager) {
 	out.Opener = in.Opener
 	out.Owner = in.Owner
 	out.Permissions.Fill(in.Permissions)
+	out.PrivacyBudget = in.PrivacyBudget
 	out.Type = in.Type
 }
 
+type outputPrivacyBudget struct {
+	DataManagerKey string               `json:"data_manager_key"`
+	Epsilon        float64              `json:"epsilon"`
+	Delta          float64              `json:"delta"`
+	Spent          float64              `json:"spent"`
+	Reserved       float64              `json:"reserved"`
+	Remaining      float64              `json:"remaining"`
+	DeltaSpent     float64              `json:"delta_spent"`
+	DeltaReserved  float64              `json:"delta_reserved"`
+	DeltaRemaining float64              `json:"delta_remaining"`
+	Spends         []PrivacyBudgetSpend `json:"spends"`
+}
+
+func (out *outputPrivacyBudget) Fill(dataManager DataManager, state PrivacyBudgetState, spends []PrivacyBudgetSpend) {
+	out.DataManagerKey = dataManager.Key
+	out.Epsilon = dataManager.PrivacyBudget.Epsilon
+	out.Delta = dataManager.PrivacyBudget.Delta
+	out.Spent = state.Spent
+	out.Reserved = state.Reserved
+	out.Remaining = dataManager.PrivacyBudget.Epsilon - state.Spent - state.Reserved
+	out.DeltaSpent = state.DeltaSpent
+	out.DeltaReserved = state.DeltaReserved
+	out.DeltaRemaining = dataManager.PrivacyBudget.Delta - state.DeltaSpent - state.DeltaReserved
+	out.Spends = spends
+	if out.Spends == nil {
+		out.Spends = []PrivacyBudgetSpend{}
+	}
+}
+
 type outputDataSample struct {
-	DataManagerKeys []string `json:"data_manager_keys"`
-	Owner           string   `json:"owner"`
-	Key             string   `json:"key"`
+	DataManagerKeys []string          `json:"data_manager_keys"`
+	Owner           string            `json:"owner"`
+	Key             string            `json:"key"`
+	Revoked         bool              `json:"revoked"`
+	Metadata        map[string]string `json:"metadata"`
 }
 
 func (out *outputDataSample) Fill(key string, in DataSample) {
 	out.Key = key
 	out.DataManagerKeys = in.DataManagerKeys
 	out.Owner = in.Owner
+	out.Revoked = in.Revoked
+	out.Metadata = map[string]string{}
 }
 
 type outputDataset struct {
@@ -108,6 +145,10 @@ type outputAlgo struct {
 	Owner       string            `json:"owner"`
 	Permissions outputPermissions `json:"permissions"`
 	Metadata    map[string]string `json:"metadata"`
+	ParentKey   string            `json:"parent_key"`
+	Version     string            `json:"version"`
+	Deprecated  bool              `json:"deprecated"`
+	Resources   Resources         `json:"resources"`
 }
 
 func (out *outputAlgo) Fill(in Algo) {
@@ -121,6 +162,10 @@ func (out *outputAlgo) Fill(in Algo) {
 	out.Owner = in.Owner
 	out.Permissions.Fill(in.Permissions)
 	out.Metadata = initMapOutput(in.Metadata)
+	out.ParentKey = in.ParentKey
+	out.Version = in.Version
+	out.Deprecated = in.Deprecated
+	out.Resources = in.Resources
 }
 
 // outputTtDataset is the representation of a Traintuple Dataset
@@ -140,14 +185,18 @@ type outputTraintuple struct {
 	Creator        string                  `json:"creator"`
 	Dataset        *outputTtDataset        `json:"dataset"`
 	ComputePlanKey string                  `json:"compute_plan_key"`
+	Delta          float64                 `json:"delta"`
+	Epsilon        float64                 `json:"epsilon"`
 	InModels       []*Model                `json:"in_models"`
 	Log            string                  `json:"log"`
 	Metadata       map[string]string       `json:"metadata"`
 	OutModel       *KeyChecksumAddress     `json:"out_model"`
 	Permissions    outputPermissions       `json:"permissions"`
 	Rank           int                     `json:"rank"`
+	Resources      Resources               `json:"resources"`
 	Status         string                  `json:"status"`
 	Tag            string                  `json:"tag"`
+	Condition      *Condition              `json:"condition"`
 }
 
 //Fill is a method of the receiver outputTraintuple. It returns all elements necessary to do a training task from a trainuple stored in the ledger
@@ -156,13 +205,17 @@ func (outputTraintuple *outputTraintuple) Fill(db *LedgerDB, traintuple Traintup
 	outputTraintuple.Key = traintuple.Key
 	outputTraintuple.Creator = traintuple.Creator
 	outputTraintuple.Permissions.Fill(traintuple.Permissions)
-	outputTraintuple.Log = traintuple.Log
+	outputTraintuple.Log = traintuple.Log + getFailureLog(db, traintuple.Dataset.Worker, traintuple.Key, traintuple.PrivateHash)
 	outputTraintuple.Metadata = initMapOutput(traintuple.Metadata)
 	outputTraintuple.Status = traintuple.Status
 	outputTraintuple.Rank = traintuple.Rank
+	outputTraintuple.Resources = traintuple.Resources
+	outputTraintuple.Condition = traintuple.Condition
 	outputTraintuple.ComputePlanKey = traintuple.ComputePlanKey
 	outputTraintuple.OutModel = traintuple.OutModel
 	outputTraintuple.Tag = traintuple.Tag
+	outputTraintuple.Epsilon = traintuple.Epsilon
+	outputTraintuple.Delta = traintuple.Delta
 	// fill algo
 	algo, err := db.GetAlgo(traintuple.AlgoKey)
 	if err != nil {
@@ -214,20 +267,24 @@ func (outputTraintuple *outputTraintuple) Fill(db *LedgerDB, traintuple Traintup
 }
 
 type outputTesttuple struct {
-	Algo           *KeyChecksumAddressName `json:"algo"`
-	Certified      bool                    `json:"certified"`
-	ComputePlanKey string                  `json:"compute_plan_key"`
-	Creator        string                  `json:"creator"`
-	Dataset        *TtDataset              `json:"dataset"`
-	Key            string                  `json:"key"`
-	Log            string                  `json:"log"`
-	Metadata       map[string]string       `json:"metadata"`
-	Objective      *TtObjective            `json:"objective"`
-	Rank           int                     `json:"rank"`
-	Status         string                  `json:"status"`
-	Tag            string                  `json:"tag"`
-	TraintupleKey  string                  `json:"traintuple_key"`
-	TraintupleType string                  `json:"traintuple_type"`
+	Algo               *KeyChecksumAddressName `json:"algo"`
+	Certified          bool                    `json:"certified"`
+	ComputePlanKey     string                  `json:"compute_plan_key"`
+	Creator            string                  `json:"creator"`
+	Dataset            *TtDataset              `json:"dataset"`
+	Delta              float64                 `json:"delta"`
+	Epsilon            float64                 `json:"epsilon"`
+	Key                string                  `json:"key"`
+	Log                string                  `json:"log"`
+	Metadata           map[string]string       `json:"metadata"`
+	Objective          *TtObjective            `json:"objective"`
+	Rank               int                     `json:"rank"`
+	Resources          Resources               `json:"resources"`
+	Status             string                  `json:"status"`
+	Tag                string                  `json:"tag"`
+	TraintupleKey      string                  `json:"traintuple_key"`
+	TraintupleType     string                  `json:"traintuple_type"`
+	TestDatasetVersion int                     `json:"test_dataset_version"`
 }
 
 func (out *outputTesttuple) Fill(db *LedgerDB, in Testtuple) error {
@@ -236,12 +293,16 @@ func (out *outputTesttuple) Fill(db *LedgerDB, in Testtuple) error {
 	out.ComputePlanKey = in.ComputePlanKey
 	out.Creator = in.Creator
 	out.Dataset = in.Dataset
-	out.Log = in.Log
+	out.Epsilon = in.Epsilon
+	out.Delta = in.Delta
+	out.Log = in.Log + getFailureLog(db, in.Dataset.Worker, in.Key, in.PrivateHash)
 	out.Metadata = initMapOutput(in.Metadata)
 	out.Rank = in.Rank
+	out.Resources = in.Resources
 	out.Status = in.Status
 	out.Tag = in.Tag
 	out.TraintupleKey = in.TraintupleKey
+	out.TestDatasetVersion = in.TestDatasetVersion
 
 	// fill type
 	traintupleType, err := db.GetAssetType(in.TraintupleKey)
@@ -346,6 +407,7 @@ type outputComputePlan struct {
 	TupleCount              int               `json:"tuple_count"`
 	DoneCount               int               `json:"done_count"`
 	IDToKey                 map[string]string `json:"id_to_key"`
+	Priority                int               `json:"priority"`
 }
 
 func (out *outputComputePlan) Fill(key string, in ComputePlan, newIDs []string, doneCount int, tupleCount int) {
@@ -368,6 +430,7 @@ func (out *outputComputePlan) Fill(key string, in ComputePlan, newIDs []string,
 	}
 	out.IDToKey = IDToKey
 	out.CleanModels = in.CleanModels
+	out.Priority = in.Priority
 }
 
 // This is the "historical" output permissions, not
@@ -450,6 +513,59 @@ func getLimitedNbSliceElements(s []string) int {
 	return int(math.Min(float64(len(s)), OutputPageSize))
 }
 
+type outputProposal struct {
+	Key          string   `json:"key"`
+	Type         string   `json:"type"`
+	NodeID       string   `json:"node_id"`
+	SettingName  string   `json:"setting_name"`
+	SettingValue string   `json:"setting_value"`
+	Admin        string   `json:"admin"`
+	Threshold    int      `json:"threshold"`
+	Proposer     string   `json:"proposer"`
+	Approvals    []string `json:"approvals"`
+	Status       string   `json:"status"`
+}
+
+func (out *outputProposal) Fill(in Proposal) {
+	out.Key = in.Key
+	out.Type = in.Type
+	out.NodeID = in.NodeID
+	out.SettingName = in.SettingName
+	out.SettingValue = in.SettingValue
+	out.Admin = in.Admin
+	out.Threshold = in.Threshold
+	out.Proposer = in.Proposer
+	out.Approvals = in.Approvals
+	out.Status = in.Status
+}
+
+type outputNextTask struct {
+	Key            string `json:"key"`
+	Type           string `json:"type"`
+	ComputePlanKey string `json:"compute_plan_key"`
+	Creator        string `json:"creator"`
+	Priority       int    `json:"priority"`
+	Rank           int    `json:"rank"`
+}
+
+type outputQuotaUsage struct {
+	Org                     string `json:"org"`
+	ActiveTuples            int    `json:"active_tuples"`
+	ActiveComputePlans      int    `json:"active_compute_plans"`
+	MaxActiveTuples         *int   `json:"max_active_tuples"`
+	MaxTuplesPerComputePlan *int   `json:"max_tuples_per_compute_plan"`
+	MaxActiveComputePlans   *int   `json:"max_active_compute_plans"`
+}
+
+func (out *outputQuotaUsage) Fill(org string, in QuotaUsage, quotas map[string]*int) {
+	out.Org = org
+	out.ActiveTuples = in.ActiveTuples
+	out.ActiveComputePlans = in.ActiveComputePlans
+	out.MaxActiveTuples = quotas[QuotaMaxActiveTuples]
+	out.MaxTuplesPerComputePlan = quotas[QuotaMaxTuplesPerCP]
+	out.MaxActiveComputePlans = quotas[QuotaMaxActiveComputePlans]
+}
+
 type outputKey struct {
 	Key string `json:"key"`
 }
@@ -457,3 +573,45 @@ type outputKey struct {
 type outputMetrics struct {
 	Duration int `json:"duration"`
 }
+
+type outputValidatedTask struct {
+	ID     string   `json:"id"`
+	Type   string   `json:"type"`
+	Rank   int      `json:"rank"`
+	Worker string   `json:"worker"`
+	Errors []string `json:"errors"`
+}
+
+type outputComputePlanValidation struct {
+	Key               string                `json:"key"`
+	Valid             bool                  `json:"valid"`
+	Errors            []string              `json:"errors"`
+	Tasks             []outputValidatedTask `json:"tasks"`
+	WorkerTupleCounts map[string]int        `json:"worker_tuple_counts"`
+}
+
+type outputComputePlanTemplate struct {
+	Key             string            `json:"key"`
+	Name            string            `json:"name"`
+	Owner           string            `json:"owner"`
+	Rounds          int               `json:"rounds"`
+	TestEvery       int               `json:"test_every"`
+	Parties         []TemplateParty   `json:"parties"`
+	AggregateWorker string            `json:"aggregate_worker"`
+	CompositeAlgo   string            `json:"composite_algo"`
+	AggregateAlgo   string            `json:"aggregate_algo"`
+	Metadata        map[string]string `json:"metadata"`
+}
+
+func (out *outputComputePlanTemplate) Fill(in ComputePlanTemplate) {
+	out.Key = in.Key
+	out.Name = in.Name
+	out.Owner = in.Owner
+	out.Rounds = in.Rounds
+	out.TestEvery = in.TestEvery
+	out.Parties = in.Parties
+	out.AggregateWorker = in.AggregateWorker
+	out.CompositeAlgo = in.CompositeAlgo
+	out.AggregateAlgo = in.AggregateAlgo
+	out.Metadata = in.Metadata
+}
@@ -26,6 +26,7 @@ type outputAggregatetuple struct {
 	InModels       []*Model                `json:"in_models"`
 	OutModel       *KeyChecksumAddress     `json:"out_model"`
 	Rank           int                     `json:"rank"`
+	Resources      Resources               `json:"resources"`
 	Status         string                  `json:"status"`
 	Tag            string                  `json:"tag"`
 	Permissions    outputPermissions       `json:"permissions"`
@@ -44,10 +45,11 @@ func (out *outputAggregateAlgo) Fill(in AggregateAlgo) {
 func (outputAggregatetuple *outputAggregatetuple) Fill(db *LedgerDB, traintuple Aggregatetuple) (err error) {
 	outputAggregatetuple.Key = traintuple.Key
 	outputAggregatetuple.Creator = traintuple.Creator
-	outputAggregatetuple.Log = traintuple.Log
+	outputAggregatetuple.Log = traintuple.Log + getFailureLog(db, traintuple.Worker, traintuple.Key, traintuple.PrivateHash)
 	outputAggregatetuple.Metadata = initMapOutput(traintuple.Metadata)
 	outputAggregatetuple.Status = traintuple.Status
 	outputAggregatetuple.Rank = traintuple.Rank
+	outputAggregatetuple.Resources = traintuple.Resources
 	outputAggregatetuple.ComputePlanKey = traintuple.ComputePlanKey
 	outputAggregatetuple.OutModel = traintuple.OutModel
 	outputAggregatetuple.Tag = traintuple.Tag
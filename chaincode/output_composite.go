@@ -26,6 +26,8 @@ type outputCompositeTraintuple struct {
 	Creator        string                  `json:"creator"`
 	Dataset        *outputTtDataset        `json:"dataset"`
 	ComputePlanKey string                  `json:"compute_plan_key"`
+	Delta          float64                 `json:"delta"`
+	Epsilon        float64                 `json:"epsilon"`
 	InHeadModel    *Model                  `json:"in_head_model"`
 	InTrunkModel   *Model                  `json:"in_trunk_model"`
 	Log            string                  `json:"log"`
@@ -33,6 +35,7 @@ type outputCompositeTraintuple struct {
 	OutHeadModel   outHeadModelComposite   `json:"out_head_model"`
 	OutTrunkModel  outModelComposite       `json:"out_trunk_model"`
 	Rank           int                     `json:"rank"`
+	Resources      Resources               `json:"resources"`
 	Status         string                  `json:"status"`
 	Tag            string                  `json:"tag"`
 }
@@ -52,10 +55,11 @@ func (outputCompositeTraintuple *outputCompositeTraintuple) Fill(db *LedgerDB, t
 
 	outputCompositeTraintuple.Key = traintuple.Key
 	outputCompositeTraintuple.Creator = traintuple.Creator
-	outputCompositeTraintuple.Log = traintuple.Log
+	outputCompositeTraintuple.Log = traintuple.Log + getFailureLog(db, traintuple.Dataset.Worker, traintuple.Key, traintuple.PrivateHash)
 	outputCompositeTraintuple.Metadata = initMapOutput(traintuple.Metadata)
 	outputCompositeTraintuple.Status = traintuple.Status
 	outputCompositeTraintuple.Rank = traintuple.Rank
+	outputCompositeTraintuple.Resources = traintuple.Resources
 	outputCompositeTraintuple.ComputePlanKey = traintuple.ComputePlanKey
 	outputCompositeTraintuple.OutHeadModel = outHeadModelComposite{
 		OutModel:    traintuple.OutHeadModel.OutModel,
@@ -64,6 +68,8 @@ func (outputCompositeTraintuple *outputCompositeTraintuple) Fill(db *LedgerDB, t
 		OutModel:    traintuple.OutTrunkModel.OutModel,
 		Permissions: getOutPermissions(traintuple.OutTrunkModel.Permissions)}
 	outputCompositeTraintuple.Tag = traintuple.Tag
+	outputCompositeTraintuple.Epsilon = traintuple.Epsilon
+	outputCompositeTraintuple.Delta = traintuple.Delta
 	// fill algo
 	algo, err := db.GetCompositeAlgo(traintuple.AlgoKey)
 	if err != nil {
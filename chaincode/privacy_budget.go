@@ -0,0 +1,210 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"fmt"
+)
+
+const privacyBudgetSpendIndex = "privacyBudgetSpend~dataManager~tuple"
+
+// getPrivacyBudgetStateKey returns the key of the privacy budget state of a dataManager
+func getPrivacyBudgetStateKey(dataManagerKey string) string {
+	return fmt.Sprintf("privacyBudget~%v", dataManagerKey)
+}
+
+// getPrivacyBudgetReservationKey returns the key of the privacy budget reserved by a tuple
+func getPrivacyBudgetReservationKey(tupleKey string) string {
+	return fmt.Sprintf("privacyBudgetReservation~%v", tupleKey)
+}
+
+// getPrivacyBudgetSpendKey returns the key of the privacy budget consumed by a tuple
+func getPrivacyBudgetSpendKey(tupleKey string) string {
+	return fmt.Sprintf("privacyBudgetSpend~%v", tupleKey)
+}
+
+// createPrivacyBudgetState initializes the privacy budget state of a dataManager if it has a budget
+func createPrivacyBudgetState(db *LedgerDB, dataManager DataManager) error {
+	if dataManager.PrivacyBudget == nil {
+		return nil
+	}
+	return db.Add(getPrivacyBudgetStateKey(dataManager.Key), PrivacyBudgetState{})
+}
+
+// checkPrivacyBudget verifies that a tuple consuming epsilon and delta on the dataManager
+// does not exceed its remaining privacy budget.
+// The budget reserved by the tuples which have not ended yet is not available.
+func checkPrivacyBudget(db *LedgerDB, dataManager DataManager, epsilon float64, delta float64) error {
+	if dataManager.PrivacyBudget == nil {
+		return nil
+	}
+	if epsilon == 0 {
+		return errors.BadRequest("dataManager %s has a privacy budget, the epsilon consumed by the tuple is required", dataManager.Key)
+	}
+	state, err := db.GetPrivacyBudgetState(dataManager.Key)
+	if err != nil {
+		return err
+	}
+	remaining := dataManager.PrivacyBudget.Epsilon - state.Spent - state.Reserved
+	if epsilon > remaining {
+		return errors.BadRequest("epsilon %v exceeds the remaining privacy budget %v of dataManager %s", epsilon, remaining, dataManager.Key)
+	}
+	deltaRemaining := dataManager.PrivacyBudget.Delta - state.DeltaSpent - state.DeltaReserved
+	if delta > deltaRemaining {
+		return errors.BadRequest("delta %v exceeds the remaining privacy budget %v of dataManager %s", delta, deltaRemaining, dataManager.Key)
+	}
+	return nil
+}
+
+// reservePrivacyBudget reserves the epsilon and delta a new tuple will consume on the dataManager
+// so that concurrent tuples cannot exceed its privacy budget.
+// It must be called once checkPrivacyBudget succeeded.
+func reservePrivacyBudget(db *LedgerDB, dataManagerKey string, tupleKey string, epsilon float64, delta float64) error {
+	if epsilon == 0 {
+		return nil
+	}
+	dataManager, err := db.GetDataManager(dataManagerKey)
+	if err != nil {
+		return err
+	}
+	if dataManager.PrivacyBudget == nil {
+		return nil
+	}
+	state, err := db.GetPrivacyBudgetState(dataManagerKey)
+	if err != nil {
+		return err
+	}
+	state.Reserved += epsilon
+	state.DeltaReserved += delta
+	reservation := PrivacyBudgetReservation{DataManagerKey: dataManagerKey, Epsilon: epsilon, Delta: delta}
+	if err := db.Add(getPrivacyBudgetReservationKey(tupleKey), reservation); err != nil {
+		return err
+	}
+	return db.Put(getPrivacyBudgetStateKey(dataManagerKey), state)
+}
+
+// releasePrivacyBudget gives back the privacy budget reserved by a tuple.
+// It does nothing if the tuple has no reservation, either because its dataManager has no
+// privacy budget or because the reservation was already released.
+func releasePrivacyBudget(db *LedgerDB, tupleKey string) error {
+	reservationKey := getPrivacyBudgetReservationKey(tupleKey)
+	reservation := PrivacyBudgetReservation{}
+	if err := db.Get(reservationKey, &reservation); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	state, err := db.GetPrivacyBudgetState(reservation.DataManagerKey)
+	if err != nil {
+		return err
+	}
+	state.Reserved -= reservation.Epsilon
+	state.DeltaReserved -= reservation.Delta
+	if err := db.Delete(reservationKey); err != nil {
+		return err
+	}
+	return db.Put(getPrivacyBudgetStateKey(reservation.DataManagerKey), state)
+}
+
+// releaseTuplePrivacyBudget releases the privacy budget reserved by a tuple which ends without success
+func releaseTuplePrivacyBudget(db *LedgerDB, newStatus string, tupleKey string) error {
+	if newStatus != StatusFailed && newStatus != StatusAborted {
+		return nil
+	}
+	return releasePrivacyBudget(db, tupleKey)
+}
+
+// consumePrivacyBudget commits the epsilon and delta spent by a successful tuple on the dataManager
+// privacy budget in place of its reservation.
+// The spend is recorded even if it exceeds the remaining budget since the data has already been used.
+func consumePrivacyBudget(db *LedgerDB, dataManagerKey string, tupleKey string, epsilon float64, delta float64) error {
+	if err := releasePrivacyBudget(db, tupleKey); err != nil {
+		return err
+	}
+	dataManager, err := db.GetDataManager(dataManagerKey)
+	if err != nil {
+		return err
+	}
+	if dataManager.PrivacyBudget == nil || epsilon == 0 {
+		return nil
+	}
+	state, err := db.GetPrivacyBudgetState(dataManagerKey)
+	if err != nil {
+		return err
+	}
+	state.Spent += epsilon
+	state.DeltaSpent += delta
+	if state.Spent > dataManager.PrivacyBudget.Epsilon {
+		logger.Warnf("privacy budget of dataManager %s exceeded by tuple %s: spent %v out of %v", dataManagerKey, tupleKey, state.Spent, dataManager.PrivacyBudget.Epsilon)
+	}
+	spend := PrivacyBudgetSpend{TupleKey: tupleKey, Epsilon: epsilon, Delta: delta}
+	if err := db.Add(getPrivacyBudgetSpendKey(tupleKey), spend); err != nil {
+		return err
+	}
+	if err := db.CreateIndex(privacyBudgetSpendIndex, []string{"privacyBudgetSpend", dataManagerKey, tupleKey}); err != nil {
+		return err
+	}
+	return db.Put(getPrivacyBudgetStateKey(dataManagerKey), state)
+}
+
+// getPrivacyBudgetSpends returns the spends committed on the privacy budget of a dataManager
+func getPrivacyBudgetSpends(db *LedgerDB, dataManagerKey string) ([]PrivacyBudgetSpend, error) {
+	tupleKeys, err := db.GetIndexKeys(privacyBudgetSpendIndex, []string{"privacyBudgetSpend", dataManagerKey})
+	if err != nil {
+		return nil, err
+	}
+	spends := []PrivacyBudgetSpend{}
+	for _, tupleKey := range tupleKeys {
+		spend := PrivacyBudgetSpend{}
+		if err := db.Get(getPrivacyBudgetSpendKey(tupleKey), &spend); err != nil {
+			return nil, err
+		}
+		spends = append(spends, spend)
+	}
+	return spends, nil
+}
+
+// -----------------------------------------------------------------
+// ----------------------- Smart Contracts  ------------------------
+// -----------------------------------------------------------------
+
+// queryPrivacyBudget returns the privacy budget of a dataManager with its spent and reserved amounts and its spends
+func queryPrivacyBudget(db *LedgerDB, args []string) (out outputPrivacyBudget, err error) {
+	inp := inputKey{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	dataManager, err := db.GetDataManager(inp.Key)
+	if err != nil {
+		return
+	}
+	if dataManager.PrivacyBudget == nil {
+		err = errors.NotFound("dataManager %s has no privacy budget", inp.Key)
+		return
+	}
+	state, err := db.GetPrivacyBudgetState(inp.Key)
+	if err != nil {
+		return
+	}
+	spends, err := getPrivacyBudgetSpends(db, inp.Key)
+	if err != nil {
+		return
+	}
+	out.Fill(dataManager, state, spends)
+	return
+}
@@ -0,0 +1,186 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func registerPrivacyBudgetDataManager(t *testing.T, mockStub *MockStub, epsilon float64) {
+	inpDataManager := inputDataManager{
+		Key:           dataManagerKey2,
+		PrivacyBudget: inputPrivacyBudget{Epsilon: epsilon, Delta: 0.00001},
+	}
+	args := inpDataManager.createDefault()
+	resp := mockStub.MockInvoke(args)
+	require.EqualValuesf(t, 200, resp.Status, "when adding dataManager with status %d and message %s", resp.Status, resp.Message)
+
+	inpDataSample := inputDataSample{
+		Keys:            []string{trainDataSampleKeyWorker2},
+		DataManagerKeys: []string{dataManagerKey2},
+		TestOnly:        "false",
+	}
+	args = inpDataSample.createDefault()
+	resp = mockStub.MockInvoke(args)
+	require.EqualValuesf(t, 200, resp.Status, "when adding dataSample with status %d and message %s", resp.Status, resp.Message)
+}
+
+func TestPrivacyBudget(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "algo")
+	registerPrivacyBudgetDataManager(t, mockStub, 1)
+
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	dataManager, err := queryDataManager(db, keyToArgs(dataManagerKey2))
+	assert.NoError(t, err)
+	assert.Equal(t, &PrivacyBudget{Epsilon: 1, Delta: 0.00001}, dataManager.PrivacyBudget)
+
+	inpTraintuple := inputTraintuple{
+		DataManagerKey: dataManagerKey2,
+		DataSampleKeys: []string{trainDataSampleKeyWorker2},
+	}
+	inpTraintuple.createDefault()
+
+	// A tuple using a dataManager with a privacy budget must declare its epsilon
+	_, err = createTraintuple(db, assetToArgs(inpTraintuple))
+	assert.Error(t, err)
+	assert.Equal(t, 400, errors.Wrap(err).HTTPStatusCode())
+
+	inpTraintuple.Epsilon = 1.5
+	_, err = createTraintuple(db, assetToArgs(inpTraintuple))
+	assert.Error(t, err, "a tuple should not exceed the dataManager privacy budget")
+
+	inpTraintuple.Epsilon = 0.75
+	_, err = createTraintuple(db, assetToArgs(inpTraintuple))
+	assert.NoError(t, err)
+
+	// Nothing is spent until the tuple succeeds but its epsilon is reserved
+	budget, err := queryPrivacyBudget(db, keyToArgs(dataManagerKey2))
+	assert.NoError(t, err)
+	assert.Equal(t, float64(0), budget.Spent)
+	assert.Equal(t, 0.75, budget.Reserved)
+	assert.Equal(t, 0.25, budget.Remaining)
+	assert.Len(t, budget.Spends, 0)
+
+	inpTraintuple.Key = traintupleKey2
+	inpTraintuple.Epsilon = 0.5
+	_, err = createTraintuple(db, assetToArgs(inpTraintuple))
+	assert.Error(t, err, "a tuple should not exceed the privacy budget reserved by a running tuple")
+
+	traintupleToDone(t, db, traintupleKey)
+
+	budget, err = queryPrivacyBudget(db, keyToArgs(dataManagerKey2))
+	assert.NoError(t, err)
+	assert.Equal(t, 0.75, budget.Spent)
+	assert.Equal(t, float64(0), budget.Reserved)
+	assert.Equal(t, 0.25, budget.Remaining)
+	assert.Equal(t, []PrivacyBudgetSpend{{TupleKey: traintupleKey, Epsilon: 0.75}}, budget.Spends)
+
+	_, err = createTraintuple(db, assetToArgs(inpTraintuple))
+	assert.Error(t, err, "a tuple should not exceed the remaining privacy budget")
+}
+
+func TestPrivacyBudgetReleasedOnFailure(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "algo")
+	registerPrivacyBudgetDataManager(t, mockStub, 1)
+
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	inpTraintuple := inputTraintuple{
+		DataManagerKey: dataManagerKey2,
+		DataSampleKeys: []string{trainDataSampleKeyWorker2},
+		Epsilon:        0.75,
+		Delta:          0.000005,
+	}
+	inpTraintuple.createDefault()
+	_, err := createTraintuple(db, assetToArgs(inpTraintuple))
+	assert.NoError(t, err)
+
+	_, err = logStartTrain(db, assetToArgs(inputKey{Key: traintupleKey}))
+	assert.NoError(t, err)
+	_, err = logFailTrain(db, assetToArgs(inputLogFailTrain{inputLog{Key: traintupleKey, Log: "out of memory"}}))
+	assert.NoError(t, err)
+
+	budget, err := queryPrivacyBudget(db, keyToArgs(dataManagerKey2))
+	assert.NoError(t, err)
+	assert.Equal(t, float64(0), budget.Spent)
+	assert.Equal(t, float64(0), budget.Reserved)
+	assert.Equal(t, float64(0), budget.DeltaReserved)
+	assert.Equal(t, float64(1), budget.Remaining)
+	assert.Len(t, budget.Spends, 0)
+}
+
+func TestPrivacyBudgetDelta(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "algo")
+	registerPrivacyBudgetDataManager(t, mockStub, 1)
+
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	inpTraintuple := inputTraintuple{
+		DataManagerKey: dataManagerKey2,
+		DataSampleKeys: []string{trainDataSampleKeyWorker2},
+		Epsilon:        0.25,
+		Delta:          0.00002,
+	}
+	inpTraintuple.createDefault()
+	_, err := createTraintuple(db, assetToArgs(inpTraintuple))
+	assert.Error(t, err, "a tuple should not exceed the dataManager delta")
+	assert.Equal(t, 400, errors.Wrap(err).HTTPStatusCode())
+
+	inpTraintuple.Delta = 0.000008
+	_, err = createTraintuple(db, assetToArgs(inpTraintuple))
+	assert.NoError(t, err)
+	traintupleToDone(t, db, traintupleKey)
+
+	budget, err := queryPrivacyBudget(db, keyToArgs(dataManagerKey2))
+	assert.NoError(t, err)
+	assert.Equal(t, 0.000008, budget.DeltaSpent)
+	assert.Equal(t, []PrivacyBudgetSpend{{TupleKey: traintupleKey, Epsilon: 0.25, Delta: 0.000008}}, budget.Spends)
+
+	inpTraintuple.Key = traintupleKey2
+	_, err = createTraintuple(db, assetToArgs(inpTraintuple))
+	assert.Error(t, err, "a tuple should not exceed the remaining delta")
+}
+
+func TestPrivacyBudgetNotSet(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "algo")
+
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	inpTraintuple := inputTraintuple{Epsilon: 10}
+	inpTraintuple.createDefault()
+	_, err := createTraintuple(db, assetToArgs(inpTraintuple))
+	assert.NoError(t, err, "epsilon is not checked without a privacy budget")
+
+	_, err = queryPrivacyBudget(db, keyToArgs(dataManagerKey))
+	assert.Error(t, err)
+	assert.Equal(t, 404, errors.Wrap(err).HTTPStatusCode())
+}
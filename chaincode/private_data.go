@@ -0,0 +1,77 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "chaincode/errors"
+
+// canReadPrivateData returns whether the requester belongs to the organization owning private data
+func canReadPrivateData(db *LedgerDB, owner string) bool {
+	requester, err := GetTxCreator(db.cc)
+	return err == nil && requester == owner
+}
+
+// putFailureLog appends the failure log of a tuple to its private data, stored by its worker,
+// and returns the hash to keep in the tuple.
+// The caller must have checked that the requester is the worker of the tuple.
+func putFailureLog(db *LedgerDB, worker string, key string, log string) (string, error) {
+	private := TuplePrivateData{}
+	// the tuple may not have private data yet
+	if err := db.GetPrivate(worker, key, &private); err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+	private.FailureLog += log
+	return db.PutPrivate(worker, key, private)
+}
+
+// getFailureLog returns the failure log of a tuple if the requester is its worker.
+// The creator of the tuple does not get it: the log is kept in the implicit collection of the
+// worker, which the peers of other organizations do not store.
+func getFailureLog(db *LedgerDB, worker string, key string, privateHash string) string {
+	if privateHash == "" || !canReadPrivateData(db, worker) {
+		return ""
+	}
+	private := TuplePrivateData{}
+	if err := db.GetPrivate(worker, key, &private); err != nil {
+		return ""
+	}
+	return private.FailureLog
+}
+
+// fillPrivateData adds the storage address of the description if the requester owns the data manager
+func (out *outputDataManager) fillPrivateData(db *LedgerDB, in DataManager) {
+	if in.PrivateHash == "" || !canReadPrivateData(db, in.Owner) {
+		return
+	}
+	private := DataManagerPrivateData{}
+	if err := db.GetPrivate(in.Owner, in.Key, &private); err != nil {
+		return
+	}
+	out.Description = &ChecksumAddress{
+		Checksum:       in.Description.Checksum,
+		StorageAddress: private.DescriptionStorageAddress,
+	}
+}
+
+// fillPrivateData adds the metadata of the data sample if the requester owns it
+func (out *outputDataSample) fillPrivateData(db *LedgerDB, in DataSample) {
+	if in.PrivateHash == "" || !canReadPrivateData(db, in.Owner) {
+		return
+	}
+	private := DataSamplePrivateData{}
+	if err := db.GetPrivate(in.Owner, out.Key, &private); err != nil {
+		return
+	}
+	out.Metadata = initMapOutput(private.Metadata)
+}
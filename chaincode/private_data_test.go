@@ -0,0 +1,100 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrivateData(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "traintuple")
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+	collection := mockStub.PvtState["_implicit_org_"+workerA]
+
+	// The public record of the data manager only keeps the hash of its private data
+	buff, err := mockStub.GetState(dataManagerKey)
+	require.NoError(t, err)
+	dataManager := DataManager{}
+	require.NoError(t, json.Unmarshal(buff, &dataManager))
+	assert.Empty(t, dataManager.Description.StorageAddress)
+	hash := sha256.Sum256(collection[dataManagerKey])
+	assert.Equal(t, hex.EncodeToString(hash[:]), dataManager.PrivateHash)
+
+	out, err := queryDataManager(db, assetToArgs(inputKey{Key: dataManagerKey}))
+	require.NoError(t, err)
+	assert.NotEmpty(t, out.Description.StorageAddress, "the owner reads the private data")
+
+	// Data sample metadata
+	dataSampleKey := RandomUUID()
+	_, err = registerDataSample(db, assetToArgs(inputDataSample{
+		Keys:            []string{dataSampleKey},
+		DataManagerKeys: []string{dataManagerKey},
+		TestOnly:        "false",
+		Metadata:        map[string]string{"patient": "42"},
+	}))
+	require.NoError(t, err)
+	dataSample, err := db.GetDataSample(dataSampleKey)
+	require.NoError(t, err)
+	assert.NotEmpty(t, dataSample.PrivateHash)
+	outDataSamples, _, err := queryDataSamples(db, []string{})
+	require.NoError(t, err)
+	for _, outDataSample := range outDataSamples {
+		if outDataSample.Key == dataSampleKey {
+			assert.Equal(t, map[string]string{"patient": "42"}, outDataSample.Metadata)
+		}
+	}
+
+	// Failure logs
+	_, err = logStartTrain(db, assetToArgs(inputKey{Key: traintupleKey}))
+	require.NoError(t, err)
+	mockStub.Creator = workerB
+	_, err = logFailTrain(db, assetToArgs(inputLogFailTrain{inputLog{Key: traintupleKey, Log: "forged"}}))
+	assert.Error(t, err, "only the worker can log a failure")
+	assert.Nil(t, mockStub.PvtState["_implicit_org_"+workerA][traintupleKey], "the failure log is not written before the worker is checked")
+	mockStub.Creator = workerA
+	outTraintuple, err := logFailTrain(db, assetToArgs(inputLogFailTrain{inputLog{Key: traintupleKey, Log: "out of memory"}}))
+	require.NoError(t, err)
+	assert.Equal(t, "out of memory", outTraintuple.Log)
+	traintuple, err := db.GetTraintuple(traintupleKey)
+	require.NoError(t, err)
+	assert.Empty(t, traintuple.Log)
+	assert.NotEmpty(t, traintuple.PrivateHash)
+
+	// Other organizations only see the public part
+	mockStub.Creator = workerB
+	mockStub.MockTransactionStart("43")
+	db = NewLedgerDB(mockStub)
+	out, err = queryDataManager(db, assetToArgs(inputKey{Key: dataManagerKey}))
+	require.NoError(t, err)
+	assert.Empty(t, out.Description.StorageAddress)
+	assert.Equal(t, dataManager.Description.Checksum, out.Description.Checksum)
+	outTraintuple, err = queryTraintuple(db, assetToArgs(inputKey{Key: traintupleKey}))
+	require.NoError(t, err)
+	assert.Empty(t, outTraintuple.Log)
+	outDataSamples, _, err = queryDataSamples(db, []string{})
+	require.NoError(t, err)
+	for _, outDataSample := range outDataSamples {
+		assert.Empty(t, outDataSample.Metadata)
+	}
+}
@@ -0,0 +1,260 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"strconv"
+	"strings"
+)
+
+// Quotas are global settings changed through governance proposals.
+// A quota can be set for all the organizations (quota.max_active_tuples)
+// or for a single one (quota.MyOrgMSP.max_active_tuples). Unset quotas are unlimited.
+const (
+	quotaSettingPrefix         = "quota."
+	QuotaMaxActiveTuples       = "max_active_tuples"
+	QuotaMaxTuplesPerCP        = "max_tuples_per_compute_plan"
+	QuotaMaxActiveComputePlans = "max_active_compute_plans"
+)
+
+// The usage of an organization is not stored in a counter, which every tuple transition would
+// rewrite: each active tuple or compute plan has its own key in this index, created when it
+// is reserved and deleted when it is released. Tuples created before the quotas were
+// enforced have no key, they are never released.
+const quotaUsageIndex = "quotaUsage~org~type~key"
+
+// Types of the reservations of the quota usage index
+const (
+	quotaTuple       = "tuple"
+	quotaComputePlan = "computePlan"
+)
+
+// reserveQuota records an active tuple or compute plan of an organization
+func reserveQuota(db *LedgerDB, org string, usageType string, key string) error {
+	return db.CreateIndex(quotaUsageIndex, []string{"quotaUsage", org, usageType, key})
+}
+
+// releaseQuota removes the reservation of a tuple or a compute plan, if any
+func releaseQuota(db *LedgerDB, org string, usageType string, key string) error {
+	attributes := []string{"quotaUsage", org, usageType, key}
+	reserved, err := db.IndexExists(quotaUsageIndex, attributes)
+	if err != nil || !reserved {
+		return err
+	}
+	return db.DeleteIndex(quotaUsageIndex, attributes)
+}
+
+// countQuotaUsage returns the number of active tuples or compute plans of an organization
+func countQuotaUsage(db *LedgerDB, org string, usageType string) (int, error) {
+	keys, err := db.GetIndexKeys(quotaUsageIndex, []string{"quotaUsage", org, usageType})
+	return len(keys), err
+}
+
+// getQuota returns the quota of an organization and whether it is limited
+func getQuota(db *LedgerDB, org string, name string) (int, bool, error) {
+	for _, settingName := range []string{quotaSettingPrefix + org + "." + name, quotaSettingPrefix + name} {
+		value, ok, err := getSetting(db, settingName)
+		if err != nil {
+			return 0, false, err
+		}
+		if !ok {
+			continue
+		}
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, false, errors.Internal("invalid value %s for setting %s", value, settingName)
+		}
+		return limit, true, nil
+	}
+	return 0, false, nil
+}
+
+// validateQuotaSetting checks that the value of a quota setting is a positive integer
+func validateQuotaSetting(name string, value string) error {
+	if !strings.HasPrefix(name, quotaSettingPrefix) {
+		return nil
+	}
+	if limit, err := strconv.Atoi(value); err != nil || limit < 0 {
+		return errors.BadRequest("quota %s must be a positive integer, received: %s", name, value)
+	}
+	return nil
+}
+
+// isActiveStatus returns whether a tuple or a compute plan with this status is still to be processed
+func isActiveStatus(status string) bool {
+	return stringInSlice(status, []string{StatusWaiting, StatusTodo, StatusDoing})
+}
+
+// isTupleQuotaActive returns whether a tuple is counted in the active tuples of its creator.
+// Tuples of failed or canceled compute plans are released all at once when the compute plan ends.
+func isTupleQuotaActive(db *LedgerDB, status string, computePlanKey string) (bool, error) {
+	if !isActiveStatus(status) {
+		return false, nil
+	}
+	if computePlanKey == "" {
+		return true, nil
+	}
+	computePlan, err := db.GetComputePlan(computePlanKey)
+	if err != nil {
+		return false, err
+	}
+	return !stringInSlice(computePlan.State.Status, []string{StatusFailed, StatusCanceled}), nil
+}
+
+// reserveTupleQuota checks that the creator can add a new tuple and counts it in its active tuples.
+// The tuple is counted even when the creator has no quota, so that a quota set later applies to the tuples already active.
+func reserveTupleQuota(db *LedgerDB, creator string, status string, computePlanKey string, tupleKey string) error {
+	active, err := isTupleQuotaActive(db, status, computePlanKey)
+	if err != nil || !active {
+		return err
+	}
+	limit, limited, err := getQuota(db, creator, QuotaMaxActiveTuples)
+	if err != nil {
+		return err
+	}
+	if limited {
+		count, err := countQuotaUsage(db, creator, quotaTuple)
+		if err != nil {
+			return err
+		}
+		if count >= limit {
+			return errors.Forbidden("%s reached its quota of %d active tuples", creator, limit)
+		}
+	}
+	return reserveQuota(db, creator, quotaTuple, tupleKey)
+}
+
+// releaseTupleQuota removes a tuple from the active tuples of its creator when it ends.
+// Tuples which were not reserved, or already released with their compute plan, are ignored.
+func releaseTupleQuota(db *LedgerDB, creator string, newStatus string, tupleKey string) error {
+	if isActiveStatus(newStatus) {
+		return nil
+	}
+	return releaseQuota(db, creator, quotaTuple, tupleKey)
+}
+
+// checkComputePlanQuota checks that the creator can add a new compute plan
+func checkComputePlanQuota(db *LedgerDB, creator string) error {
+	limit, limited, err := getQuota(db, creator, QuotaMaxActiveComputePlans)
+	if err != nil || !limited {
+		return err
+	}
+	count, err := countQuotaUsage(db, creator, quotaComputePlan)
+	if err != nil {
+		return err
+	}
+	if count >= limit {
+		return errors.Forbidden("%s reached its quota of %d active compute plans", creator, limit)
+	}
+	return nil
+}
+
+// checkTuplesPerComputePlanQuota checks that the compute plan will not contain more tuples than its creator's quota
+// once the new tuples are added
+func (cp *ComputePlan) checkTuplesPerComputePlanQuota(db *LedgerDB, newTuples int) error {
+	if cp.Creator == "" {
+		return nil
+	}
+	limit, limited, err := getQuota(db, cp.Creator, QuotaMaxTuplesPerCP)
+	if err != nil || !limited {
+		return err
+	}
+	count := len(cp.TraintupleKeys) + len(cp.CompositeTraintupleKeys) + len(cp.AggregatetupleKeys) + len(cp.TesttupleKeys)
+	if count+newTuples > limit {
+		return errors.Forbidden("%s reached its quota of %d tuples per compute plan", cp.Creator, limit)
+	}
+	return nil
+}
+
+// updateQuotaUsage updates the reservation of the compute plan after a change of its status.
+// When the compute plan fails or is canceled, the quota and the privacy budget reserved
+// by its remaining tuples are released.
+func (cp *ComputePlan) updateQuotaUsage(db *LedgerDB, oldStatus string) error {
+	if oldStatus == cp.State.Status || cp.Creator == "" {
+		return nil
+	}
+	wasActive, isActive := isActiveStatus(oldStatus), isActiveStatus(cp.State.Status)
+	if wasActive && !isActive {
+		if err := releaseQuota(db, cp.Creator, quotaComputePlan, cp.Key); err != nil {
+			return err
+		}
+	} else if !wasActive && isActive {
+		if err := reserveQuota(db, cp.Creator, quotaComputePlan, cp.Key); err != nil {
+			return err
+		}
+	}
+
+	ended := []string{StatusFailed, StatusCanceled}
+	if !stringInSlice(cp.State.Status, ended) || stringInSlice(oldStatus, ended) {
+		return nil
+	}
+	keys := [][]string{cp.TraintupleKeys, cp.CompositeTraintupleKeys, cp.AggregatetupleKeys, cp.TesttupleKeys}
+	for _, tupleKeys := range keys {
+		for _, key := range tupleKeys {
+			tuple := GenericTuple{}
+			if err := db.Get(key, &tuple); err != nil {
+				return err
+			}
+			if err := releaseQuota(db, tuple.Creator, quotaTuple, key); err != nil {
+				return err
+			}
+			if err := releasePrivacyBudget(db, key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// -----------------------------------------------------------------
+// ----------------------- Smart Contracts  ------------------------
+// -----------------------------------------------------------------
+
+// queryQuotaUsage returns the quotas of an organization and its current usage.
+// It defaults to the organization of the transaction requester.
+func queryQuotaUsage(db *LedgerDB, args []string) (out outputQuotaUsage, err error) {
+	inp := inputQuotaUsage{}
+	if len(args) > 1 {
+		err = errors.BadRequest("incorrect number of arguments, expecting at most one argument")
+		return
+	}
+	if len(args) == 1 && args[0] != "" {
+		if err = AssetFromJSON(args, &inp); err != nil {
+			return
+		}
+	}
+	if inp.Org == "" {
+		if inp.Org, err = GetTxCreator(db.cc); err != nil {
+			return
+		}
+	}
+	usage, err := db.GetQuotaUsage(inp.Org)
+	if err != nil {
+		return
+	}
+	quotas := map[string]*int{}
+	for _, name := range []string{QuotaMaxActiveTuples, QuotaMaxTuplesPerCP, QuotaMaxActiveComputePlans} {
+		limit, limited, err := getQuota(db, inp.Org, name)
+		if err != nil {
+			return out, err
+		}
+		if limited {
+			quotas[name] = &limit
+		}
+	}
+	out.Fill(inp.Org, usage, quotas)
+	return
+}
@@ -0,0 +1,170 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// initGovernanceWithSettings makes workerA the only admin of the network and sets the settings
+func initGovernanceWithSettings(t *testing.T, mockStub *MockStub, settings map[string]string) {
+	inpGovernance := inputGovernance{Admins: []string{workerA}, Threshold: 1}
+	resp := mockStub.MockInit("42", [][]byte{[]byte("init"), assetToJSON(inpGovernance)})
+	require.EqualValuesf(t, 200, resp.Status, "when initializing governance, status %d and message %s", resp.Status, resp.Message)
+
+	for name, value := range settings {
+		inp := inputProposal{Key: RandomUUID(), Type: ProposalSetSetting, SettingName: name, SettingValue: value}
+		resp = mockStub.MockInvoke(methodAndAssetToByte("createProposal", inp))
+		require.EqualValuesf(t, 200, resp.Status, "when creating a proposal, status %d and message %s", resp.Status, resp.Message)
+		resp = mockStub.MockInvoke(methodAndAssetToByte("executeProposal", inputKey{Key: inp.Key}))
+		require.EqualValuesf(t, 200, resp.Status, "when executing a proposal, status %d and message %s", resp.Status, resp.Message)
+	}
+}
+
+func TestQuotaActiveTuples(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	initGovernanceWithSettings(t, mockStub, map[string]string{
+		"quota.max_active_tuples":                 "5",
+		"quota." + workerA + ".max_active_tuples": "1",
+	})
+	registerItem(t, *mockStub, "traintuple")
+
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	// The quota of the organization overrides the global one
+	usage, err := queryQuotaUsage(db, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, usage.ActiveTuples)
+	require.NotNil(t, usage.MaxActiveTuples)
+	assert.Equal(t, 1, *usage.MaxActiveTuples)
+	assert.Nil(t, usage.MaxActiveComputePlans)
+
+	inpTraintuple := inputTraintuple{Key: traintupleKey2}
+	inpTraintuple.createDefault()
+	_, err = createTraintuple(db, assetToArgs(inpTraintuple))
+	assert.Error(t, err)
+	assert.Equal(t, 403, errors.Wrap(err).HTTPStatusCode())
+
+	// Done tuples are released
+	traintupleToDone(t, db, traintupleKey)
+	usage, err = queryQuotaUsage(db, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, usage.ActiveTuples)
+
+	_, err = createTraintuple(db, assetToArgs(inpTraintuple))
+	assert.NoError(t, err)
+
+	// Other organizations use the global quota
+	usage, err = queryQuotaUsage(db, assetToArgs(inputQuotaUsage{Org: workerB}))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, usage.ActiveTuples)
+	require.NotNil(t, usage.MaxActiveTuples)
+	assert.Equal(t, 5, *usage.MaxActiveTuples)
+}
+
+func TestQuotaUnreservedTuples(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	initGovernanceWithSettings(t, mockStub, map[string]string{"quota.max_active_tuples": "2"})
+	registerItem(t, *mockStub, "traintuple")
+
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	// Each active tuple has its own reservation key
+	reserved, err := db.GetIndexKeys(quotaUsageIndex, []string{"quotaUsage", workerA, quotaTuple})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{traintupleKey}, reserved)
+
+	// A tuple created before the quotas were enforced has no reservation
+	err = db.DeleteIndex(quotaUsageIndex, []string{"quotaUsage", workerA, quotaTuple, traintupleKey})
+	require.NoError(t, err)
+	inpTraintuple := inputTraintuple{Key: traintupleKey2}
+	inpTraintuple.createDefault()
+	_, err = createTraintuple(db, assetToArgs(inpTraintuple))
+	require.NoError(t, err)
+
+	// Its end does not release the reservation of another tuple
+	traintupleToDone(t, db, traintupleKey)
+	usage, err := queryQuotaUsage(db, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, usage.ActiveTuples)
+}
+
+func TestQuotaComputePlans(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	initGovernanceWithSettings(t, mockStub, map[string]string{
+		"quota.max_active_compute_plans":    "1",
+		"quota.max_tuples_per_compute_plan": "2",
+	})
+	registerItem(t, *mockStub, "aggregateAlgo")
+
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	// The default compute plan has 3 tuples
+	_, err := createComputePlanInternal(db, defaultComputePlan, tag, map[string]string{}, false, 0)
+	assert.Error(t, err)
+	assert.Equal(t, 403, errors.Wrap(err).HTTPStatusCode())
+
+	mockStub.MockTransactionStart("42")
+	db = NewLedgerDB(mockStub)
+	inpCP := defaultComputePlan
+	inpCP.Testtuples = []inputComputePlanTesttuple{}
+	out, err := createComputePlanInternal(db, inpCP, tag, map[string]string{}, false, 0)
+	assert.NoError(t, err)
+
+	usage, err := queryQuotaUsage(db, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, usage.ActiveTuples)
+	assert.Equal(t, 1, usage.ActiveComputePlans)
+
+	_, err = createComputePlanInternal(db, inputComputePlan{Key: RandomUUID()}, tag, map[string]string{}, false, 0)
+	assert.Error(t, err)
+	assert.Equal(t, 403, errors.Wrap(err).HTTPStatusCode())
+
+	// Canceling the compute plan releases it and its tuples
+	_, err = cancelComputePlan(db, assetToArgs(inputKey{Key: out.Key}))
+	assert.NoError(t, err)
+	usage, err = queryQuotaUsage(db, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, usage.ActiveTuples)
+	assert.Equal(t, 0, usage.ActiveComputePlans)
+
+	_, err = cancelComputePlan(db, assetToArgs(inputKey{Key: out.Key}))
+	assert.NoError(t, err)
+	usage, err = queryQuotaUsage(db, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, usage.ActiveTuples, "tuples should only be released once")
+}
+
+func TestQuotaSettingValidation(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	initGovernanceWithSettings(t, mockStub, map[string]string{})
+
+	for _, value := range []string{"", "abc", "-1"} {
+		inp := inputProposal{Key: RandomUUID(), Type: ProposalSetSetting, SettingName: "quota.max_active_tuples", SettingValue: value}
+		resp := mockStub.MockInvoke(methodAndAssetToByte("createProposal", inp))
+		assert.EqualValuesf(t, 400, resp.Status, "when setting a quota to %s, status %d and message %s", value, resp.Status, resp.Message)
+	}
+}
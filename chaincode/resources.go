@@ -0,0 +1,87 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"fmt"
+	"strings"
+)
+
+// NewResources converts the input resources to their ledger representation
+func NewResources(inp inputResources) Resources {
+	return Resources{
+		CPU:        inp.CPU,
+		Memory:     inp.Memory,
+		GPU:        inp.GPU,
+		MaxRuntime: inp.MaxRuntime,
+	}
+}
+
+// overrideWith returns the resources where each requirement set in the input replaces the current one
+func (resources Resources) overrideWith(inp inputResources) Resources {
+	if inp.CPU > 0 {
+		resources.CPU = inp.CPU
+	}
+	if inp.Memory > 0 {
+		resources.Memory = inp.Memory
+	}
+	if inp.GPU > 0 {
+		resources.GPU = inp.GPU
+	}
+	if inp.MaxRuntime > 0 {
+		resources.MaxRuntime = inp.MaxRuntime
+	}
+	return resources
+}
+
+// checkPlacement checks that the worker can provide the resources required by a task.
+// Workers which are not registered or did not declare their capacity are not checked,
+// nor are the resources left at 0 in their capacity.
+func checkPlacement(db *LedgerDB, worker string, required Resources) error {
+	node, err := db.GetNode(worker)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil || node.Capacity == nil {
+		return err
+	}
+	capacity := *node.Capacity
+	missing := []string{}
+	for _, r := range []struct {
+		name               string
+		required, capacity int
+	}{
+		{"cpu", required.CPU, capacity.CPU},
+		{"memory", required.Memory, capacity.Memory},
+		{"gpu", required.GPU, capacity.GPU},
+		{"max_runtime", required.MaxRuntime, capacity.MaxRuntime},
+	} {
+		if r.capacity > 0 && r.required > r.capacity {
+			missing = append(missing, fmt.Sprintf("%s %d > %d", r.name, r.required, r.capacity))
+		}
+	}
+	if len(missing) > 0 {
+		return errors.BadRequest("worker %s cannot run the task, the required resources exceed its capacity: %s", worker, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// getTaskResources returns the resources required by a tuple run by the worker:
+// the ones of its algo, each one being overridden by the tuple's when set
+func getTaskResources(db *LedgerDB, worker string, algoResources Resources, inp inputResources) (Resources, error) {
+	resources := algoResources.overrideWith(inp)
+	return resources, checkPlacement(db, worker, resources)
+}
@@ -0,0 +1,82 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourcesPlacement(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "algo")
+
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	gpuAlgoKey := RandomUUID()
+	inpAlgo := inputAlgo{Key: gpuAlgoKey, Resources: inputResources{GPU: 1, Memory: 8000}}
+	inpAlgo.fillDefaults()
+	_, err := registerAlgo(db, assetToArgs(inpAlgo))
+	require.NoError(t, err)
+
+	// Nodes which did not declare their capacity are not checked
+	inpTraintuple := inputTraintuple{Key: RandomUUID(), AlgoKey: gpuAlgoKey}
+	inpTraintuple.createDefault()
+	_, err = createTraintuple(db, assetToArgs(inpTraintuple))
+	assert.NoError(t, err)
+
+	// Nor are the resources they left unspecified
+	_, err = registerNode(db, assetToArgs(inputNode{Capacity: &inputResources{CPU: 8}}))
+	require.NoError(t, err)
+	inpTraintuple = inputTraintuple{Key: RandomUUID(), AlgoKey: gpuAlgoKey}
+	inpTraintuple.createDefault()
+	_, err = createTraintuple(db, assetToArgs(inpTraintuple))
+	assert.NoError(t, err)
+
+	_, err = registerNode(db, assetToArgs(inputNode{Capacity: &inputResources{CPU: 8, Memory: 16000, GPU: 1, MaxRuntime: 3600}}))
+	require.NoError(t, err)
+
+	// A tuple cannot require more GPUs than the node has
+	inpTraintuple = inputTraintuple{Key: RandomUUID(), AlgoKey: gpuAlgoKey, Resources: inputResources{GPU: 2}}
+	inpTraintuple.createDefault()
+	_, err = createTraintuple(db, assetToArgs(inpTraintuple))
+	assert.Error(t, err)
+	assert.Equal(t, 400, errors.Wrap(err).HTTPStatusCode())
+
+	// The requirements of the tuple override the ones of its algo
+	inpTraintuple = inputTraintuple{Key: RandomUUID(), Resources: inputResources{Memory: 32000}}
+	inpTraintuple.createDefault()
+	_, err = createTraintuple(db, assetToArgs(inpTraintuple))
+	assert.Error(t, err)
+	assert.Equal(t, 400, errors.Wrap(err).HTTPStatusCode())
+
+	cpuAlgoKey := RandomUUID()
+	inpAlgo = inputAlgo{Key: cpuAlgoKey, Resources: inputResources{CPU: 2, Memory: 8000}}
+	inpAlgo.fillDefaults()
+	_, err = registerAlgo(db, assetToArgs(inpAlgo))
+	require.NoError(t, err)
+	inpTraintuple = inputTraintuple{Key: RandomUUID(), AlgoKey: cpuAlgoKey, Resources: inputResources{CPU: 4}}
+	inpTraintuple.createDefault()
+	out, err := createTraintuple(db, assetToArgs(inpTraintuple))
+	require.NoError(t, err)
+	traintuple, err := queryTraintuple(db, keyToArgs(out.Key))
+	assert.NoError(t, err)
+	assert.Equal(t, Resources{CPU: 4, Memory: 8000}, traintuple.Resources)
+}
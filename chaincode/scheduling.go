@@ -0,0 +1,344 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// workerStatusIndexes lists the tuple types run by workers with the prefix of their worker~status index
+var workerStatusIndexes = []struct {
+	assetType AssetType
+	prefix    string
+}{
+	{TraintupleType, "traintuple"},
+	{CompositeTraintupleType, "compositeTraintuple"},
+	{AggregatetupleType, "aggregatetuple"},
+	{TesttupleType, "testtuple"},
+}
+
+// getWorkerTupleKeys returns the keys of the tuples of a worker with the given status by tuple type
+func getWorkerTupleKeys(db *LedgerDB, worker string, status string) (map[AssetType][]string, error) {
+	keys := map[AssetType][]string{}
+	for _, index := range workerStatusIndexes {
+		indexKeys, err := db.GetIndexKeys(index.prefix+"~worker~status~key", []string{index.prefix, worker, status})
+		if err != nil {
+			return nil, err
+		}
+		keys[index.assetType] = indexKeys
+	}
+	return keys, nil
+}
+
+// checkWorkerConcurrency checks that the worker can start one more tuple
+// without exceeding the maximum number of concurrent tasks it declared
+func checkWorkerConcurrency(db *LedgerDB, worker string) error {
+	node, err := db.GetNode(worker)
+	if errors.IsNotFound(err) {
+		// nodes which are not registered are not throttled
+		return nil
+	}
+	if err != nil || node.MaxConcurrentTasks == 0 {
+		return err
+	}
+	keys, err := getWorkerTupleKeys(db, worker, StatusDoing)
+	if err != nil {
+		return err
+	}
+	doing := 0
+	for _, tupleKeys := range keys {
+		doing += len(tupleKeys)
+	}
+	if doing >= node.MaxConcurrentTasks {
+		return errors.Forbidden("worker %s is already running %d tasks out of %d", worker, doing, node.MaxConcurrentTasks)
+	}
+	return nil
+}
+
+// MaxComputePlanPriority is the highest priority a compute plan can have
+const MaxComputePlanPriority = 1000
+
+// schedulingIndex lists the todo tuples of each worker by decreasing priority, then by creator and increasing rank
+const schedulingIndex = "worker~priority~creator~rank~key"
+
+// schedulingTuple holds the fields shared by all the tuple types which are needed to schedule them
+type schedulingTuple struct {
+	GenericTuple
+	Dataset       *TtDataset `json:"dataset"`
+	Worker        string     `json:"worker"`
+	TraintupleKey string     `json:"traintuple_key"`
+}
+
+// getSchedulingTask returns the scheduling information of a tuple.
+// Testtuples take the rank of the tuple they test and tuples inherit the priority of their compute plan.
+func getSchedulingTask(db *LedgerDB, key string) (task outputNextTask, worker string, status string, err error) {
+	tuple := schedulingTuple{}
+	if err = db.Get(key, &tuple); err != nil {
+		return
+	}
+	task = outputNextTask{
+		Key:            key,
+		Type:           tuple.AssetType.String(),
+		ComputePlanKey: tuple.ComputePlanKey,
+		Creator:        tuple.Creator,
+		Rank:           tuple.Rank,
+	}
+	worker = tuple.Worker
+	if tuple.Dataset != nil {
+		worker = tuple.Dataset.Worker
+	}
+	if tuple.AssetType == TesttupleType {
+		var traintuple GenericTuple
+		if err = db.Get(tuple.TraintupleKey, &traintuple); err != nil {
+			return
+		}
+		task.Rank = traintuple.Rank
+	}
+	if tuple.ComputePlanKey != "" {
+		var computePlan ComputePlan
+		if computePlan, err = db.GetComputePlan(tuple.ComputePlanKey); err != nil {
+			return
+		}
+		task.Priority = computePlan.Priority
+	}
+	return task, worker, tuple.Status, nil
+}
+
+// getSchedulingIndexAttributes returns the attributes of a tuple in the scheduling index.
+// Priorities are inverted and numbers padded so that the lexical order of the index is the scheduling order.
+func getSchedulingIndexAttributes(worker string, task outputNextTask) []string {
+	return []string{
+		worker,
+		fmt.Sprintf("%04d", MaxComputePlanPriority-task.Priority),
+		task.Creator,
+		fmt.Sprintf("%010d", task.Rank),
+		task.Key,
+	}
+}
+
+// updateSchedulingIndex adds a tuple to the scheduling index of its worker when it becomes todo
+// and removes it when it leaves this status
+func updateSchedulingIndex(db *LedgerDB, key string, oldStatus string, newStatus string) error {
+	if (oldStatus == StatusTodo) == (newStatus == StatusTodo) {
+		return nil
+	}
+	task, worker, _, err := getSchedulingTask(db, key)
+	if err != nil {
+		return err
+	}
+	attributes := getSchedulingIndexAttributes(worker, task)
+	if newStatus == StatusTodo {
+		return db.CreateIndex(schedulingIndex, attributes)
+	}
+	return db.DeleteIndex(schedulingIndex, attributes)
+}
+
+// updatePriority changes the priority of the compute plan and moves its todo tuples in the scheduling index
+func (cp *ComputePlan) updatePriority(db *LedgerDB, key string, priority int) error {
+	oldPriority := cp.Priority
+	cp.Priority = priority
+	if err := cp.Save(db, key); err != nil {
+		return err
+	}
+	keys := [][]string{cp.TraintupleKeys, cp.CompositeTraintupleKeys, cp.AggregatetupleKeys, cp.TesttupleKeys}
+	for _, tupleKeys := range keys {
+		for _, tupleKey := range tupleKeys {
+			task, worker, status, err := getSchedulingTask(db, tupleKey)
+			if err != nil {
+				return err
+			}
+			if status != StatusTodo {
+				continue
+			}
+			oldTask := task
+			oldTask.Priority = oldPriority
+			err = db.UpdateIndex(schedulingIndex, getSchedulingIndexAttributes(worker, oldTask), getSchedulingIndexAttributes(worker, task))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fairShare orders tasks of the same priority so that the creators of compute plans take turns.
+// Creators with the fewest tasks running on the worker go first.
+func fairShare(tasks []outputNextTask, doing map[string]int) []outputNextTask {
+	ordered := []outputNextTask{}
+	for start := 0; start < len(tasks); {
+		end := start
+		for end < len(tasks) && tasks[end].Priority == tasks[start].Priority {
+			end++
+		}
+		queues := map[string][]outputNextTask{}
+		creators := []string{}
+		for _, task := range tasks[start:end] {
+			if _, ok := queues[task.Creator]; !ok {
+				creators = append(creators, task.Creator)
+			}
+			queues[task.Creator] = append(queues[task.Creator], task)
+		}
+		sort.SliceStable(creators, func(i, j int) bool {
+			if doing[creators[i]] != doing[creators[j]] {
+				return doing[creators[i]] < doing[creators[j]]
+			}
+			return creators[i] < creators[j]
+		})
+		for len(ordered) < end {
+			for _, creator := range creators {
+				if len(queues[creator]) > 0 {
+					ordered = append(ordered, queues[creator][0])
+					queues[creator] = queues[creator][1:]
+				}
+			}
+		}
+		start = end
+	}
+	return ordered
+}
+
+// getSchedulingCandidates returns the todo tuples of a worker which can be started, in the order of the
+// scheduling index, until n tuples are found and the priority of the last one is read to its end.
+// Each creator gets at most n tuples of a priority, so that the other creators of this priority are reached
+// without reading all the tuples of a large compute plan.
+func getSchedulingCandidates(db *LedgerDB, worker string, n int) ([]outputNextTask, error) {
+	candidates := []outputNextTask{}
+	creatorCandidates := map[string]int{}
+	stoppedComputePlans := map[string]bool{}
+	priority := ""
+	bookmark := ""
+	for {
+		entries, nextBookmark, err := db.GetIndexAttributesWithPagination(schedulingIndex, []string{worker}, int32(n), bookmark)
+		if err != nil {
+			return nil, err
+		}
+		for _, attributes := range entries {
+			if attributes[1] != priority {
+				if len(candidates) >= n {
+					return candidates, nil
+				}
+				priority = attributes[1]
+				creatorCandidates = map[string]int{}
+			}
+			creator, key := attributes[2], attributes[4]
+			if creatorCandidates[creator] >= n {
+				continue
+			}
+			task, _, _, err := getSchedulingTask(db, key)
+			if err != nil {
+				return nil, err
+			}
+			// tuples of a failed or canceled compute plan must not be started
+			if task.ComputePlanKey != "" {
+				stopped, ok := stoppedComputePlans[task.ComputePlanKey]
+				if !ok {
+					computePlan, err := db.GetComputePlan(task.ComputePlanKey)
+					if err != nil {
+						return nil, err
+					}
+					stopped = stringInSlice(computePlan.State.Status, []string{StatusFailed, StatusCanceled})
+					stoppedComputePlans[task.ComputePlanKey] = stopped
+				}
+				if stopped {
+					continue
+				}
+			}
+			candidates = append(candidates, task)
+			creatorCandidates[creator]++
+		}
+		if len(entries) == 0 || nextBookmark == "" {
+			return candidates, nil
+		}
+		bookmark = nextBookmark
+	}
+}
+
+// -----------------------------------------------------------------
+// ----------------------- Smart Contracts  ------------------------
+// -----------------------------------------------------------------
+
+// queryNextTasks returns the next N todo tuples a worker should start.
+// The tuples are ordered by decreasing priority of their compute plan, then within a priority
+// the compute plan creators take turns, each creator's tuples being ordered by rank.
+func queryNextTasks(db *LedgerDB, args []string) (tasks []outputNextTask, err error) {
+	tasks = []outputNextTask{}
+	inp := inputNextTasks{}
+	if err = AssetFromJSON(args, &inp); err != nil {
+		return
+	}
+	n := int(math.Min(float64(inp.N), OutputPageSize))
+	candidates, err := getSchedulingCandidates(db, inp.Worker, n)
+	if err != nil {
+		return
+	}
+
+	doingKeys, err := getWorkerTupleKeys(db, inp.Worker, StatusDoing)
+	if err != nil {
+		return
+	}
+	doing := map[string]int{}
+	for _, tupleKeys := range doingKeys {
+		for _, key := range tupleKeys {
+			var tuple GenericTuple
+			if err = db.Get(key, &tuple); err != nil {
+				return
+			}
+			doing[tuple.Creator]++
+		}
+	}
+	tasks = fairShare(candidates, doing)
+	if len(tasks) > n {
+		tasks = tasks[:n]
+	}
+	return tasks, nil
+}
+
+// migrateSchedulingIndex adds the todo tuples of a worker created before the scheduling index
+// existed to this index, and returns them. It can safely be called several times.
+func migrateSchedulingIndex(db *LedgerDB, args []string) (tasks []outputNextTask, err error) {
+	tasks = []outputNextTask{}
+	inp := inputWorker{}
+	if err = AssetFromJSON(args, &inp); err != nil {
+		return
+	}
+	todoKeys, err := getWorkerTupleKeys(db, inp.Worker, StatusTodo)
+	if err != nil {
+		return
+	}
+	for _, index := range workerStatusIndexes {
+		for _, key := range todoKeys[index.assetType] {
+			task, worker, _, err := getSchedulingTask(db, key)
+			if err != nil {
+				return nil, err
+			}
+			attributes := getSchedulingIndexAttributes(worker, task)
+			indexed, err := db.IndexExists(schedulingIndex, attributes)
+			if err != nil {
+				return nil, err
+			}
+			if indexed {
+				continue
+			}
+			if err = db.CreateIndex(schedulingIndex, attributes); err != nil {
+				return nil, err
+			}
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
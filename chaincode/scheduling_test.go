@@ -0,0 +1,242 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerConcurrency(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	maxConcurrentTasks := 1
+	resp := mockStub.MockInvoke(methodAndAssetToByte("registerNode", inputNode{MaxConcurrentTasks: &maxConcurrentTasks}))
+	require.EqualValuesf(t, 200, resp.Status, "when registering node, status %d and message %s", resp.Status, resp.Message)
+	registerItem(t, *mockStub, "traintuple")
+
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	node, err := db.GetNode(workerA)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, node.MaxConcurrentTasks)
+
+	inpTraintuple := inputTraintuple{Key: traintupleKey2}
+	inpTraintuple.createDefault()
+	_, err = createTraintuple(db, assetToArgs(inpTraintuple))
+	require.NoError(t, err)
+
+	_, err = logStartTrain(db, assetToArgs(inputKey{Key: traintupleKey}))
+	assert.NoError(t, err)
+	_, err = logStartTrain(db, assetToArgs(inputKey{Key: traintupleKey2}))
+	assert.Error(t, err)
+	assert.Equal(t, 403, errors.Wrap(err).HTTPStatusCode())
+}
+
+func TestQueryNextTasks(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "traintuple")
+
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	_, err := createComputePlanInternal(db, defaultComputePlan, tag, map[string]string{}, false, 0)
+	require.NoError(t, err)
+
+	tasks, err := queryNextTasks(db, assetToArgs(inputNextTasks{Worker: workerA, N: 10}))
+	assert.NoError(t, err)
+	assert.Equal(t, []outputNextTask{
+		{Key: computePlanTraintupleKey1, Type: "traintuple", ComputePlanKey: computePlanKey, Creator: workerA, Rank: 0},
+		{Key: traintupleKey, Type: "traintuple", Creator: workerA, Rank: 0},
+	}, tasks)
+
+	tasks, err = queryNextTasks(db, assetToArgs(inputNextTasks{Worker: workerA, N: 1}))
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 1)
+
+	tasks, err = queryNextTasks(db, assetToArgs(inputNextTasks{Worker: workerB, N: 10}))
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 0)
+
+	// Children become todo once their parent is done
+	traintupleToDone(t, db, computePlanTraintupleKey1)
+	tasks, err = queryNextTasks(db, assetToArgs(inputNextTasks{Worker: workerA, N: 10}))
+	assert.NoError(t, err)
+	assert.Equal(t, []outputNextTask{
+		{Key: traintupleKey, Type: "traintuple", Creator: workerA, Rank: 0},
+		{Key: computePlanTraintupleKey2, Type: "traintuple", ComputePlanKey: computePlanKey, Creator: workerA, Rank: 1},
+	}, tasks)
+
+	// Tuples of canceled compute plans are not returned
+	_, err = cancelComputePlan(db, assetToArgs(inputKey{Key: computePlanKey}))
+	assert.NoError(t, err)
+	tasks, err = queryNextTasks(db, assetToArgs(inputNextTasks{Worker: workerA, N: 10}))
+	assert.NoError(t, err)
+	assert.Equal(t, []outputNextTask{{Key: traintupleKey, Type: "traintuple", Creator: workerA, Rank: 0}}, tasks)
+}
+
+func TestComputePlanPriority(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "traintuple")
+
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	inpTraintuple := inputTraintuple{Key: traintupleKey2}
+	inpTraintuple.createDefault()
+	_, err := createTraintuple(db, assetToArgs(inpTraintuple))
+	require.NoError(t, err)
+
+	mockStub.Creator = workerB
+	cp, err := createComputePlanInternal(db, defaultComputePlan, tag, map[string]string{}, false, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 0, cp.Priority)
+
+	creators := func() []string {
+		tasks, err := queryNextTasks(db, assetToArgs(inputNextTasks{Worker: workerA, N: 10}))
+		require.NoError(t, err)
+		creators := []string{}
+		for _, task := range tasks {
+			creators = append(creators, task.Creator)
+		}
+		return creators
+	}
+
+	// Creators take turns within the same priority
+	assert.Equal(t, []string{workerA, workerB, workerA}, creators())
+
+	// Creators with the fewest running tasks go first
+	mockStub.Creator = workerA
+	_, err = logStartTrain(db, assetToArgs(inputKey{Key: traintupleKey}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{workerB, workerA}, creators())
+
+	// Only the creator of the compute plan can change its priority
+	inpPriority := inputComputePlanPriority{Key: computePlanKey, Priority: 10}
+	_, err = updateComputePlanPriority(db, assetToArgs(inpPriority))
+	assert.Error(t, err)
+	assert.Equal(t, 403, errors.Wrap(err).HTTPStatusCode())
+
+	mockStub.Creator = workerB
+	cp, err = updateComputePlanPriority(db, assetToArgs(inpPriority))
+	require.NoError(t, err)
+	assert.Equal(t, 10, cp.Priority)
+
+	// Tuples of the compute plan, including the ones becoming todo later, come first
+	mockStub.Creator = workerA
+	traintupleToDone(t, db, computePlanTraintupleKey1)
+	tasks, err := queryNextTasks(db, assetToArgs(inputNextTasks{Worker: workerA, N: 10}))
+	assert.NoError(t, err)
+	require.Len(t, tasks, 2)
+	assert.Equal(t, computePlanTraintupleKey2, tasks[0].Key)
+	assert.Equal(t, 10, tasks[0].Priority)
+	assert.Equal(t, traintupleKey2, tasks[1].Key)
+
+	_, err = updateComputePlanPriority(db, assetToArgs(inputComputePlanPriority{Key: computePlanKey, Priority: MaxComputePlanPriority + 1}))
+	assert.Error(t, err)
+	assert.Equal(t, 400, errors.Wrap(err).HTTPStatusCode())
+}
+
+func TestFairShareLargeComputePlan(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "algo")
+
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	// A compute plan with more tuples than requested comes first in the scheduling index
+	inpComputePlan := inputComputePlan{Key: computePlanKey}
+	for i := 0; i < 5; i++ {
+		inpComputePlan.Traintuples = append(inpComputePlan.Traintuples, inputComputePlanTraintuple{
+			Key:            RandomUUID(),
+			DataManagerKey: dataManagerKey,
+			DataSampleKeys: []string{trainDataSampleKey1},
+			AlgoKey:        algoKey,
+			ID:             fmt.Sprintf("traintuple%d", i),
+		})
+	}
+	_, err := createComputePlanInternal(db, inpComputePlan, tag, map[string]string{}, false, 0)
+	require.NoError(t, err)
+
+	mockStub.Creator = workerB
+	inpTraintuple := inputTraintuple{Key: traintupleKey2}
+	inpTraintuple.createDefault()
+	_, err = createTraintuple(db, assetToArgs(inpTraintuple))
+	require.NoError(t, err)
+	mockStub.Creator = workerA
+
+	tasks, err := queryNextTasks(db, assetToArgs(inputNextTasks{Worker: workerA, N: 2}))
+	assert.NoError(t, err)
+	require.Len(t, tasks, 2)
+	assert.Equal(t, workerA, tasks[0].Creator)
+	assert.Equal(t, traintupleKey2, tasks[1].Key)
+}
+
+func TestMigrateSchedulingIndex(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "traintuple")
+
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	// The traintuple was todo before the scheduling index existed
+	task, worker, _, err := getSchedulingTask(db, traintupleKey)
+	require.NoError(t, err)
+	require.NoError(t, db.DeleteIndex(schedulingIndex, getSchedulingIndexAttributes(worker, task)))
+	tasks, err := queryNextTasks(db, assetToArgs(inputNextTasks{Worker: workerA, N: 10}))
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 0)
+
+	tasks, err = migrateSchedulingIndex(db, assetToArgs(inputWorker{Worker: workerA}))
+	assert.NoError(t, err)
+	assert.Equal(t, []outputNextTask{task}, tasks)
+	tasks, err = queryNextTasks(db, assetToArgs(inputNextTasks{Worker: workerA, N: 10}))
+	assert.NoError(t, err)
+	assert.Equal(t, []outputNextTask{task}, tasks)
+
+	// Tuples already indexed are left untouched
+	tasks, err = migrateSchedulingIndex(db, assetToArgs(inputWorker{Worker: workerA}))
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 0)
+}
+
+func TestLegacyComputePlanPriority(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "traintuple")
+
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	out, err := createComputePlanInternal(db, defaultComputePlan, tag, map[string]string{}, false, 0)
+	require.NoError(t, err)
+	cp, err := db.GetComputePlan(out.Key)
+	require.NoError(t, err)
+	cp.Creator = ""
+	require.NoError(t, db.Put(out.Key, cp))
+
+	_, err = updateComputePlanPriority(db, assetToArgs(inputComputePlanPriority{Key: out.Key, Priority: 10}))
+	assert.Error(t, err)
+	assert.Equal(t, 400, errors.Wrap(err).HTTPStatusCode())
+}
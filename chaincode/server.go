@@ -0,0 +1,204 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultShutdownTimeout is the time given to the in-flight invocations to return on SIGTERM
+const defaultShutdownTimeout = 30 * time.Second
+
+// serverConfig is the configuration of the external chaincode server, read from the environment
+type serverConfig struct {
+	CCID            string
+	Address         string
+	TLSDisabled     bool
+	Key             []byte
+	Cert            []byte
+	ClientCACerts   []byte
+	HTTPAddress     string
+	ShutdownTimeout time.Duration
+}
+
+// loadServerConfig reads and validates the configuration of the chaincode server.
+// TLS is mandatory unless TLS_DISABLED is set, for development only.
+func loadServerConfig() (config serverConfig, err error) {
+	config.CCID = os.Getenv("CHAINCODE_CCID")
+	if config.CCID == "" {
+		return config, fmt.Errorf("CHAINCODE_CCID must be set")
+	}
+	config.Address = os.Getenv("CHAINCODE_ADDRESS")
+	if config.Address == "" {
+		return config, fmt.Errorf("CHAINCODE_ADDRESS must be set")
+	}
+
+	if value := os.Getenv("TLS_DISABLED"); value != "" {
+		if config.TLSDisabled, err = strconv.ParseBool(value); err != nil {
+			return config, fmt.Errorf("invalid TLS_DISABLED %s, expecting a boolean", value)
+		}
+	}
+	if !config.TLSDisabled {
+		if config.Key, err = readConfigFile("TLS_KEY_FILE"); err != nil {
+			return
+		}
+		if config.Cert, err = readConfigFile("TLS_CERT_FILE"); err != nil {
+			return
+		}
+		if _, err = tls.X509KeyPair(config.Cert, config.Key); err != nil {
+			return config, fmt.Errorf("invalid TLS key pair: %s", err)
+		}
+		// The client certificates are only verified when a root certificate is given
+		if os.Getenv("TLS_ROOTCERT_FILE") != "" {
+			if config.ClientCACerts, err = readConfigFile("TLS_ROOTCERT_FILE"); err != nil {
+				return
+			}
+		}
+	}
+
+	config.HTTPAddress = os.Getenv("HTTP_ADDRESS")
+
+	config.ShutdownTimeout = defaultShutdownTimeout
+	if value := os.Getenv("SHUTDOWN_TIMEOUT"); value != "" {
+		if config.ShutdownTimeout, err = time.ParseDuration(value); err != nil || config.ShutdownTimeout < 0 {
+			return config, fmt.Errorf("invalid SHUTDOWN_TIMEOUT %s, expecting a positive duration such as 30s", value)
+		}
+	}
+	return config, nil
+}
+
+// readConfigFile reads the file whose path is given by an environment variable
+func readConfigFile(name string) ([]byte, error) {
+	path := os.Getenv(name)
+	if path == "" {
+		return nil, fmt.Errorf("%s must be set, or TLS_DISABLED for development", name)
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %s", name, err)
+	}
+	return content, nil
+}
+
+// invocationTracker follows the in-flight invocations, so that the server
+// stops accepting new ones and lets the others return before exiting
+type invocationTracker struct {
+	mutex    sync.Mutex
+	inflight sync.WaitGroup
+	ready    bool
+	draining bool
+}
+
+// invocations tracks the invocations of the chaincode server
+var invocations = &invocationTracker{}
+
+// begin records an invocation, it returns false if the server is draining
+func (t *invocationTracker) begin() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.draining {
+		return false
+	}
+	t.inflight.Add(1)
+	return true
+}
+
+// end records the return of an invocation
+func (t *invocationTracker) end() {
+	t.inflight.Done()
+}
+
+// setReady marks the server as ready to accept invocations
+func (t *invocationTracker) setReady(ready bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.ready = ready
+}
+
+// isReady returns true if the server is ready and not draining
+func (t *invocationTracker) isReady() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.ready && !t.draining
+}
+
+// drain rejects the new invocations and waits for the in-flight ones.
+// It returns false if they did not return before the timeout.
+func (t *invocationTracker) drain(timeout time.Duration) bool {
+	t.mutex.Lock()
+	t.draining = true
+	t.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		t.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// healthzHandler answers the liveness probes: the process is up
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintln(w, "ok")
+}
+
+// readyzHandler answers the readiness probes: the server accepts invocations
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	if !invocations.isReady() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintln(w, "not ready")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintln(w, "ok")
+}
+
+// newHTTPHandler returns the handler of the HTTP server: probes and metrics
+func newHTTPHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", healthzHandler)
+	mux.HandleFunc("/readyz", readyzHandler)
+	mux.Handle("/metrics", metricsHandler)
+	return mux
+}
+
+// shutdownOnSignal drains the invocations and exits when receiving SIGTERM or SIGINT
+func shutdownOnSignal(timeout time.Duration) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	sig := <-signals
+	logger.Infof("Received %s, drain the in-flight invocations", sig)
+	if !invocations.drain(timeout) {
+		logger.Errorf("In-flight invocations still running after %s, exit anyway", timeout)
+		os.Exit(1)
+	}
+	logger.Infof("Substra ChaincodeServer stopped")
+	os.Exit(0)
+}
@@ -0,0 +1,142 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setServerEnv sets the environment of the chaincode server for a test and returns a function restoring it
+func setServerEnv(env map[string]string) func() {
+	names := []string{"CHAINCODE_CCID", "CHAINCODE_ADDRESS", "TLS_DISABLED", "TLS_KEY_FILE", "TLS_CERT_FILE",
+		"TLS_ROOTCERT_FILE", "HTTP_ADDRESS", "SHUTDOWN_TIMEOUT"}
+	previous := map[string]string{}
+	for _, name := range names {
+		previous[name] = os.Getenv(name)
+		os.Setenv(name, env[name])
+	}
+	return func() {
+		for name, value := range previous {
+			os.Setenv(name, value)
+		}
+	}
+}
+
+func TestLoadServerConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chaincode")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	invalidFile := filepath.Join(dir, "invalid.pem")
+	require.NoError(t, ioutil.WriteFile(invalidFile, []byte("not a pem"), 0600))
+
+	base := map[string]string{"CHAINCODE_CCID": "substra:42", "CHAINCODE_ADDRESS": ":7052"}
+	with := func(env map[string]string) map[string]string {
+		res := map[string]string{}
+		for k, v := range base {
+			res[k] = v
+		}
+		for k, v := range env {
+			res[k] = v
+		}
+		return res
+	}
+
+	for _, tc := range []struct {
+		name  string
+		env   map[string]string
+		valid bool
+	}{
+		{name: "missing ccid", env: map[string]string{"CHAINCODE_ADDRESS": ":7052", "TLS_DISABLED": "true"}},
+		{name: "missing address", env: map[string]string{"CHAINCODE_CCID": "substra:42", "TLS_DISABLED": "true"}},
+		{name: "missing tls files", env: base},
+		{name: "unreadable tls files", env: with(map[string]string{
+			"TLS_KEY_FILE": filepath.Join(dir, "missing.key"), "TLS_CERT_FILE": filepath.Join(dir, "missing.crt")})},
+		{name: "invalid key pair", env: with(map[string]string{"TLS_KEY_FILE": invalidFile, "TLS_CERT_FILE": invalidFile})},
+		{name: "invalid tls disabled", env: with(map[string]string{"TLS_DISABLED": "maybe"})},
+		{name: "invalid shutdown timeout", env: with(map[string]string{"TLS_DISABLED": "true", "SHUTDOWN_TIMEOUT": "soon"})},
+		{name: "tls disabled", env: with(map[string]string{"TLS_DISABLED": "true"}), valid: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			defer setServerEnv(tc.env)()
+			_, err := loadServerConfig()
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+
+	defer setServerEnv(with(map[string]string{"TLS_DISABLED": "1", "HTTP_ADDRESS": ":9102", "SHUTDOWN_TIMEOUT": "5s"}))()
+	config, err := loadServerConfig()
+	assert.NoError(t, err)
+	assert.True(t, config.TLSDisabled)
+	assert.Equal(t, ":9102", config.HTTPAddress)
+	assert.Equal(t, 5*time.Second, config.ShutdownTimeout)
+}
+
+func TestInvocationTrackerDrain(t *testing.T) {
+	tracker := &invocationTracker{}
+	tracker.setReady(true)
+	assert.True(t, tracker.isReady())
+
+	require.True(t, tracker.begin())
+	// The in-flight invocation does not return in time
+	assert.False(t, tracker.drain(10*time.Millisecond))
+	assert.False(t, tracker.isReady())
+	assert.False(t, tracker.begin())
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		tracker.end()
+	}()
+	assert.True(t, tracker.drain(time.Second))
+}
+
+func TestProbes(t *testing.T) {
+	defer func(tracker *invocationTracker) { invocations = tracker }(invocations)
+	invocations = &invocationTracker{}
+	handler := newHTTPHandler()
+	probe := func(path string) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, probe("/healthz"))
+	assert.Equal(t, http.StatusServiceUnavailable, probe("/readyz"))
+	invocations.setReady(true)
+	assert.Equal(t, http.StatusOK, probe("/readyz"))
+	assert.Equal(t, http.StatusOK, probe("/metrics"))
+
+	invocations.drain(time.Second)
+	assert.Equal(t, http.StatusServiceUnavailable, probe("/readyz"))
+	assert.Equal(t, http.StatusOK, probe("/healthz"))
+
+	// The invocations received while draining are rejected
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	resp := mockStub.MockInvoke([][]byte{[]byte("queryObjectives")})
+	assert.EqualValues(t, 500, resp.Status)
+}
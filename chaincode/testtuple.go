@@ -71,7 +71,11 @@ func (testtuple *Testtuple) SetFromInput(db *LedgerDB, inp inputTesttuple) error
 	var objectiveDataSampleKeys []string
 	if objective.TestDataset != nil {
 		objectiveDataManagerKey = objective.TestDataset.DataManagerKey
-		objectiveDataSampleKeys = objective.TestDataset.DataSampleKeys
+		// the objective keeps the dataSamples revoked after it was set, they are left out of the new testtuples
+		objectiveDataSampleKeys, err = withoutRevokedDataSamples(db, objective.TestDataset.DataSampleKeys)
+		if err != nil {
+			return err
+		}
 	}
 
 	var dataManagerKey string
@@ -90,24 +94,45 @@ func (testtuple *Testtuple) SetFromInput(db *LedgerDB, inp inputTesttuple) error
 	case len(inp.DataManagerKey) > 0 || len(inp.DataSampleKeys) > 0:
 		return errors.BadRequest("invalid input: dataManagerKey and dataSampleKey should be provided together")
 	case objective.TestDataset != nil:
+		if len(objectiveDataSampleKeys) == 0 {
+			return errors.BadRequest("can not create a certified testtuple, all the test dataSamples of objective %s are revoked", testtuple.ObjectiveKey)
+		}
 		dataSampleKeys = objectiveDataSampleKeys
 		dataManagerKey = objectiveDataManagerKey
+		_, _, err = checkSameDataManager(db, dataManagerKey, dataSampleKeys)
+		if err != nil {
+			return err
+		}
 		testtuple.Certified = true
 	default:
 		return errors.BadRequest("can not create a certified testtuple, no data associated with objective %s", testtuple.ObjectiveKey)
 	}
+	if testtuple.Certified {
+		testtuple.TestDatasetVersion = objective.TestDatasetVersion
+	}
 	// retrieve dataManager owner
 	dataManager, err := db.GetDataManager(dataManagerKey)
 	if err != nil {
 		return errors.BadRequest(err, "could not retrieve dataManager with key %s", dataManagerKey)
 	}
+	if err = checkPrivacyBudget(db, dataManager, inp.Epsilon, inp.Delta); err != nil {
+		return err
+	}
+	testtuple.Epsilon = inp.Epsilon
+	testtuple.Delta = inp.Delta
 	testtuple.Dataset = &TtDataset{
 		Key:            dataManager.Key,
 		Worker:         dataManager.Owner,
 		DataSampleKeys: dataSampleKeys,
 		OpenerChecksum: dataManager.Opener.Checksum,
 	}
-	return nil
+	// the testtuple runs the algo of its traintuple
+	algo, err := db.GetGenericAlgo(testtuple.AlgoKey)
+	if err != nil {
+		return errors.BadRequest(err, "could not retrieve algo with key %s", testtuple.AlgoKey)
+	}
+	testtuple.Resources, err = getTaskResources(db, testtuple.Dataset.Worker, algo.Resources, inp.Resources)
+	return err
 }
 
 // SetFromTraintuple set the parameters of the testuple depending on traintuple
@@ -211,6 +236,9 @@ func (testtuple *Testtuple) Save(db *LedgerDB, testtupleKey string) error {
 	if err = db.Add(testtupleKey, testtuple); err != nil {
 		return err
 	}
+	if err = setKeyEndorsers(db, testtupleKey, testtuple.Dataset.Worker); err != nil {
+		return err
+	}
 
 	// create composite keys
 	if err = db.CreateIndex("testtuple~objective~certified~key", []string{"testtuple", testtuple.ObjectiveKey, strconv.FormatBool(testtuple.Certified), testtupleKey}); err != nil {
@@ -222,6 +250,10 @@ func (testtuple *Testtuple) Save(db *LedgerDB, testtupleKey string) error {
 	if err = db.CreateIndex("testtuple~worker~status~key", []string{"testtuple", testtuple.Dataset.Worker, testtuple.Status, testtupleKey}); err != nil {
 		return err
 	}
+	if err = updateSchedulingIndex(db, testtupleKey, "", testtuple.Status); err != nil {
+		return err
+	}
+	db.recordTupleStatus(TesttupleType, testtuple.Status)
 	if err = db.CreateIndex("testtuple~traintuple~certified~key", []string{"testtuple", testtuple.TraintupleKey, strconv.FormatBool(testtuple.Certified), testtupleKey}); err != nil {
 		return err
 	}
@@ -246,6 +278,9 @@ func createTesttuple(db *LedgerDB, args []string) (outputKey, error) {
 	if err != nil {
 		return outputKey{}, err
 	}
+	if inp.Key == "" {
+		inp.Key = GenerateKey(db.cc.GetTxID(), TesttupleType.String())
+	}
 	key, err := createTesttupleInternal(db, inp)
 	if err != nil {
 		return outputKey{}, err
@@ -269,6 +304,14 @@ func createTesttupleInternal(db *LedgerDB, inp inputTesttuple) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	err = reserveTupleQuota(db, testtuple.Creator, testtuple.Status, testtuple.ComputePlanKey, testtuple.Key)
+	if err != nil {
+		return "", err
+	}
+	err = reservePrivacyBudget(db, testtuple.Dataset.Key, testtuple.Key, testtuple.Epsilon, testtuple.Delta)
+	if err != nil {
+		return "", err
+	}
 	err = testtuple.Save(db, testtuple.Key)
 	if err != nil {
 		return "", err
@@ -299,6 +342,9 @@ func logStartTest(db *LedgerDB, args []string) (o outputTesttuple, err error) {
 	if err = validateTupleOwner(db, testtuple.Dataset.Worker); err != nil {
 		return
 	}
+	if err = checkWorkerConcurrency(db, testtuple.Dataset.Worker); err != nil {
+		return
+	}
 	if err = testtuple.commitStatusUpdate(db, inp.Key, status); err != nil {
 		return
 	}
@@ -329,6 +375,12 @@ func logSuccessTest(db *LedgerDB, args []string) (o outputTesttuple, err error)
 	if err = testtuple.commitStatusUpdate(db, inp.Key, status); err != nil {
 		return
 	}
+	if err = consumePrivacyBudget(db, testtuple.Dataset.Key, inp.Key, testtuple.Epsilon, testtuple.Delta); err != nil {
+		return
+	}
+	if err = UpdateConditionalChildren(db, inp.Key, testtuple.Status, testtuple.Dataset.Perf); err != nil {
+		return
+	}
 	err = o.Fill(db, testtuple)
 	return
 }
@@ -348,14 +400,20 @@ func logFailTest(db *LedgerDB, args []string) (o outputTesttuple, err error) {
 		return
 	}
 
-	testtuple.Log += inp.Log
-
 	if err = validateTupleOwner(db, testtuple.Dataset.Worker); err != nil {
 		return
 	}
+	testtuple.PrivateHash, err = putFailureLog(db, testtuple.Dataset.Worker, inp.Key, inp.Log)
+	if err != nil {
+		return
+	}
 	if err = testtuple.commitStatusUpdate(db, inp.Key, status); err != nil {
 		return
 	}
+	// the conditional children are failed, or canceled with the compute plan the testtuple belongs to
+	if err = UpdateConditionalChildren(db, inp.Key, testtuple.Status, 0); err != nil {
+		return
+	}
 	err = o.Fill(db, testtuple)
 	return
 }
@@ -475,9 +533,19 @@ func (testtuple *Testtuple) commitStatusUpdate(db *LedgerDB, testtupleKey string
 	if err := db.UpdateIndex(indexName, oldAttributes, newAttributes); err != nil {
 		return err
 	}
+	if err := releaseTupleQuota(db, testtuple.Creator, newStatus, testtupleKey); err != nil {
+		return err
+	}
+	if err := releaseTuplePrivacyBudget(db, newStatus, testtupleKey); err != nil {
+		return err
+	}
+	if err := updateSchedulingIndex(db, testtupleKey, oldStatus, newStatus); err != nil {
+		return err
+	}
 	if err := UpdateComputePlanState(db, testtuple.ComputePlanKey, newStatus, testtupleKey, testtuple.Dataset.Worker); err != nil {
 		return err
 	}
+	db.recordTupleStatus(TesttupleType, newStatus)
 	logger.Infof("testtuple %s status updated: %s (from=%s)", testtupleKey, newStatus, oldStatus)
 	return nil
 }
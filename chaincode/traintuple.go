@@ -52,6 +52,9 @@ func (traintuple *Traintuple) SetFromInput(db *LedgerDB, inp inputTraintuple) er
 	if !algo.Permissions.CanProcess(algo.Owner, creator) {
 		return errors.Forbidden("not authorized to process algo %s", inp.AlgoKey)
 	}
+	if algo.Deprecated {
+		return errors.BadRequest("algo %s is deprecated, no new tuple can use it", inp.AlgoKey)
+	}
 	traintuple.AlgoKey = inp.AlgoKey
 
 	// check if DataSampleKeys are from the same dataManager and if they are not test only dataSample
@@ -70,6 +73,11 @@ func (traintuple *Traintuple) SetFromInput(db *LedgerDB, inp inputTraintuple) er
 	if !dataManager.Permissions.CanProcess(dataManager.Owner, creator) {
 		return errors.Forbidden("not authorized to process dataManager %s", inp.DataManagerKey)
 	}
+	if err = checkPrivacyBudget(db, dataManager, inp.Epsilon, inp.Delta); err != nil {
+		return err
+	}
+	traintuple.Epsilon = inp.Epsilon
+	traintuple.Delta = inp.Delta
 
 	traintuple.Permissions = MergePermissions(dataManager.Permissions, algo.Permissions)
 
@@ -79,6 +87,10 @@ func (traintuple *Traintuple) SetFromInput(db *LedgerDB, inp inputTraintuple) er
 		DataSampleKeys: inp.DataSampleKeys,
 	}
 	traintuple.Dataset.Worker, err = getDataManagerOwner(db, traintuple.Dataset.DataManagerKey)
+	if err != nil {
+		return err
+	}
+	traintuple.Resources, err = getTaskResources(db, traintuple.Dataset.Worker, algo.Resources, inp.Resources)
 	return err
 }
 
@@ -160,6 +172,9 @@ func (traintuple *Traintuple) Save(db *LedgerDB, traintupleKey string) error {
 	if err := db.Add(traintupleKey, traintuple); err != nil {
 		return err
 	}
+	if err := setKeyEndorsers(db, traintupleKey, traintuple.Dataset.Worker); err != nil {
+		return err
+	}
 
 	// create composite keys
 	if err := db.CreateIndex("traintuple~algo~key", []string{"traintuple", traintuple.AlgoKey, traintupleKey}); err != nil {
@@ -168,6 +183,10 @@ func (traintuple *Traintuple) Save(db *LedgerDB, traintupleKey string) error {
 	if err := db.CreateIndex("traintuple~worker~status~key", []string{"traintuple", traintuple.Dataset.Worker, traintuple.Status, traintupleKey}); err != nil {
 		return err
 	}
+	if err := updateSchedulingIndex(db, traintupleKey, "", traintuple.Status); err != nil {
+		return err
+	}
+	db.recordTupleStatus(TraintupleType, traintuple.Status)
 	for _, inModelKey := range traintuple.InModelKeys {
 		if err := db.CreateIndex("tuple~inModel~key", []string{"tuple", inModelKey, traintupleKey}); err != nil {
 			return err
@@ -181,6 +200,11 @@ func (traintuple *Traintuple) Save(db *LedgerDB, traintupleKey string) error {
 			return err
 		}
 	}
+	if traintuple.Condition != nil {
+		if err := db.CreateIndex("tuple~condition~key", []string{"tuple", traintuple.Condition.TesttupleKey, traintupleKey}); err != nil {
+			return err
+		}
+	}
 	if traintuple.Tag != "" {
 		err := db.CreateIndex("traintuple~tag~key", []string{"traintuple", traintuple.Tag, traintupleKey})
 		if err != nil {
@@ -201,6 +225,9 @@ func createTraintuple(db *LedgerDB, args []string) (outputKey, error) {
 	if err != nil {
 		return outputKey{}, err
 	}
+	if inp.Key == "" {
+		inp.Key = GenerateKey(db.cc.GetTxID(), TraintupleType.String())
+	}
 
 	key, err := createTraintupleInternal(db, inp, true)
 
@@ -221,6 +248,10 @@ func createTraintupleInternal(db *LedgerDB, inp inputTraintuple, checkComputePla
 	if err != nil {
 		return "", err
 	}
+	err = traintuple.SetCondition(db, inp.Condition)
+	if err != nil {
+		return "", err
+	}
 	// Test if the key (ergo the traintuple) already exists
 	tupleExists, err := db.KeyExists(traintuple.Key)
 	if err != nil {
@@ -234,6 +265,14 @@ func createTraintupleInternal(db *LedgerDB, inp inputTraintuple, checkComputePla
 		return "", err
 	}
 
+	err = reserveTupleQuota(db, traintuple.Creator, traintuple.Status, traintuple.ComputePlanKey, traintuple.Key)
+	if err != nil {
+		return "", err
+	}
+	err = reservePrivacyBudget(db, traintuple.Dataset.DataManagerKey, traintuple.Key, traintuple.Epsilon, traintuple.Delta)
+	if err != nil {
+		return "", err
+	}
 	err = traintuple.Save(db, traintuple.Key)
 	if err != nil {
 		return "", err
@@ -265,6 +304,9 @@ func logStartTrain(db *LedgerDB, args []string) (o outputTraintuple, err error)
 	if err = validateTupleOwner(db, traintuple.Dataset.Worker); err != nil {
 		return
 	}
+	if err = checkWorkerConcurrency(db, traintuple.Dataset.Worker); err != nil {
+		return
+	}
 	if err = traintuple.commitStatusUpdate(db, inp.Key, status); err != nil {
 		return
 	}
@@ -308,6 +350,11 @@ func logSuccessTrain(db *LedgerDB, args []string) (o outputTraintuple, err error
 		return
 	}
 
+	err = consumePrivacyBudget(db, traintuple.Dataset.DataManagerKey, traintupleKey, traintuple.Epsilon, traintuple.Delta)
+	if err != nil {
+		return
+	}
+
 	err = TryAddIntermediaryModel(db, traintuple.ComputePlanKey, traintuple.Dataset.Worker, traintupleKey, traintuple.OutModel.Key)
 	if err != nil {
 		return
@@ -343,11 +390,13 @@ func logFailTrain(db *LedgerDB, args []string) (o outputTraintuple, err error) {
 		return
 	}
 
-	traintuple.Log += inp.Log
-
 	if err = validateTupleOwner(db, traintuple.Dataset.Worker); err != nil {
 		return
 	}
+	traintuple.PrivateHash, err = putFailureLog(db, traintuple.Dataset.Worker, inp.Key, inp.Log)
+	if err != nil {
+		return
+	}
 	if err = traintuple.commitStatusUpdate(db, inp.Key, status); err != nil {
 		return
 	}
@@ -533,8 +582,8 @@ func UpdateTraintupleChild(db *LedgerDB, parentTraintupleKey string, childTraint
 
 	// get traintuple new status
 	var newStatus string
-	if traintupleStatus == StatusFailed {
-		newStatus = StatusFailed
+	if stringInSlice(traintupleStatus, []string{StatusFailed, StatusAborted}) {
+		newStatus = traintupleStatus
 	} else if traintupleStatus == StatusDone {
 		ready, _err := childTraintuple.isReady(db, parentTraintupleKey)
 		if _err != nil {
@@ -563,6 +612,16 @@ func UpdateTraintupleChild(db *LedgerDB, parentTraintupleKey string, childTraint
 }
 
 func (traintuple *Traintuple) isReady(db *LedgerDB, newDoneTraintupleKey string) (ready bool, err error) {
+	// a conditional traintuple also waits for the perf of its testtuple
+	if traintuple.Condition != nil {
+		testtuple, err := db.GetTesttuple(traintuple.Condition.TesttupleKey)
+		if err != nil {
+			return false, err
+		}
+		if testtuple.Status != StatusDone {
+			return false, nil
+		}
+	}
 	return IsReady(db, traintuple.InModelKeys, newDoneTraintupleKey)
 }
 
@@ -612,9 +671,19 @@ func (traintuple *Traintuple) commitStatusUpdate(db *LedgerDB, traintupleKey str
 	if err := db.UpdateIndex(indexName, oldAttributes, newAttributes); err != nil {
 		return err
 	}
+	if err := releaseTupleQuota(db, traintuple.Creator, newStatus, traintupleKey); err != nil {
+		return err
+	}
+	if err := releaseTuplePrivacyBudget(db, newStatus, traintupleKey); err != nil {
+		return err
+	}
+	if err := updateSchedulingIndex(db, traintupleKey, oldStatus, newStatus); err != nil {
+		return err
+	}
 	if err := UpdateComputePlanState(db, traintuple.ComputePlanKey, newStatus, traintupleKey, traintuple.Dataset.Worker); err != nil {
 		return err
 	}
+	db.recordTupleStatus(TraintupleType, newStatus)
 	logger.Infof("traintuple %s status updated: %s (from=%s)", traintupleKey, newStatus, oldStatus)
 	return nil
 }
@@ -625,6 +694,8 @@ func UpdateTesttupleChildren(db *LedgerDB, traintupleKey string, traintupleStatu
 	switch {
 	case traintupleStatus == StatusFailed:
 		newStatus = StatusFailed
+	case traintupleStatus == StatusAborted:
+		newStatus = StatusAborted
 	case traintupleStatus == StatusDone:
 		newStatus = StatusTodo
 	default:
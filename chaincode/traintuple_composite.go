@@ -50,6 +50,9 @@ func (traintuple *CompositeTraintuple) SetFromInput(db *LedgerDB, inp inputCompo
 	if !algo.Permissions.CanProcess(algo.Owner, creator) {
 		return errors.Forbidden("not authorized to process algo %s", inp.AlgoKey)
 	}
+	if algo.Deprecated {
+		return errors.BadRequest("algo %s is deprecated, no new tuple can use it", inp.AlgoKey)
+	}
 	traintuple.AlgoKey = inp.AlgoKey
 
 	// check if DataSampleKeys are from the same dataManager and if they are not test only dataSample
@@ -68,6 +71,11 @@ func (traintuple *CompositeTraintuple) SetFromInput(db *LedgerDB, inp inputCompo
 	if !dataManager.Permissions.CanProcess(dataManager.Owner, creator) {
 		return errors.Forbidden("not authorized to process dataManager %s", inp.DataManagerKey)
 	}
+	if err = checkPrivacyBudget(db, dataManager, inp.Epsilon, inp.Delta); err != nil {
+		return err
+	}
+	traintuple.Epsilon = inp.Epsilon
+	traintuple.Delta = inp.Delta
 
 	// fill traintuple.Dataset from dataManager and dataSample
 	traintuple.Dataset = &Dataset{
@@ -75,6 +83,13 @@ func (traintuple *CompositeTraintuple) SetFromInput(db *LedgerDB, inp inputCompo
 		DataSampleKeys: inp.DataSampleKeys,
 	}
 	traintuple.Dataset.Worker, err = getDataManagerOwner(db, traintuple.Dataset.DataManagerKey)
+	if err != nil {
+		return err
+	}
+	traintuple.Resources, err = getTaskResources(db, traintuple.Dataset.Worker, algo.Resources, inp.Resources)
+	if err != nil {
+		return err
+	}
 
 	// permissions (head): worker only where the data belong
 	workerOnly := Permission{
@@ -201,6 +216,9 @@ func (traintuple *CompositeTraintuple) Save(db *LedgerDB, traintupleKey string)
 	if err := db.Add(traintupleKey, traintuple); err != nil {
 		return err
 	}
+	if err := setKeyEndorsers(db, traintupleKey, traintuple.Dataset.Worker); err != nil {
+		return err
+	}
 
 	// create composite keys
 	if err := db.CreateIndex("compositeTraintuple~algo~key", []string{"compositeTraintuple", traintuple.AlgoKey, traintupleKey}); err != nil {
@@ -209,6 +227,10 @@ func (traintuple *CompositeTraintuple) Save(db *LedgerDB, traintupleKey string)
 	if err := db.CreateIndex("compositeTraintuple~worker~status~key", []string{"compositeTraintuple", traintuple.Dataset.Worker, traintuple.Status, traintupleKey}); err != nil {
 		return err
 	}
+	if err := updateSchedulingIndex(db, traintupleKey, "", traintuple.Status); err != nil {
+		return err
+	}
+	db.recordTupleStatus(CompositeTraintupleType, traintuple.Status)
 	// TODO: Do we create an index for head/trunk inModel or do we concider that
 	// they are classic inModels ?
 	if err := db.CreateIndex("tuple~inModel~key", []string{"tuple", traintuple.InHeadModel, traintupleKey}); err != nil {
@@ -245,6 +267,9 @@ func createCompositeTraintuple(db *LedgerDB, args []string) (outputKey, error) {
 	if err != nil {
 		return outputKey{}, err
 	}
+	if inp.Key == "" {
+		inp.Key = GenerateKey(db.cc.GetTxID(), CompositeTraintupleType.String())
+	}
 
 	key, err := createCompositeTraintupleInternal(db, inp, true)
 	if err != nil {
@@ -280,6 +305,14 @@ func createCompositeTraintupleInternal(db *LedgerDB, inp inputCompositeTraintupl
 		return "", err
 	}
 
+	err = reserveTupleQuota(db, traintuple.Creator, traintuple.Status, traintuple.ComputePlanKey, traintuple.Key)
+	if err != nil {
+		return "", err
+	}
+	err = reservePrivacyBudget(db, traintuple.Dataset.DataManagerKey, traintuple.Key, traintuple.Epsilon, traintuple.Delta)
+	if err != nil {
+		return "", err
+	}
 	err = traintuple.Save(db, traintuple.Key)
 	if err != nil {
 		return "", err
@@ -309,6 +342,9 @@ func logStartCompositeTrain(db *LedgerDB, args []string) (o outputCompositeTrain
 	if err = validateTupleOwner(db, compositeTraintuple.Dataset.Worker); err != nil {
 		return
 	}
+	if err = checkWorkerConcurrency(db, compositeTraintuple.Dataset.Worker); err != nil {
+		return
+	}
 	if err = compositeTraintuple.commitStatusUpdate(db, inp.Key, status); err != nil {
 		return
 	}
@@ -360,6 +396,11 @@ func logSuccessCompositeTrain(db *LedgerDB, args []string) (o outputCompositeTra
 		return
 	}
 
+	err = consumePrivacyBudget(db, compositeTraintuple.Dataset.DataManagerKey, compositeTraintupleKey, compositeTraintuple.Epsilon, compositeTraintuple.Delta)
+	if err != nil {
+		return
+	}
+
 	err = TryAddIntermediaryModel(db, compositeTraintuple.ComputePlanKey, compositeTraintuple.Dataset.Worker, compositeTraintupleKey, inp.OutHeadModel.Key)
 	if err != nil {
 		return
@@ -398,11 +439,13 @@ func logFailCompositeTrain(db *LedgerDB, args []string) (o outputCompositeTraint
 		return
 	}
 
-	compositeTraintuple.Log += inp.Log
-
 	if err = validateTupleOwner(db, compositeTraintuple.Dataset.Worker); err != nil {
 		return
 	}
+	compositeTraintuple.PrivateHash, err = putFailureLog(db, compositeTraintuple.Dataset.Worker, inp.Key, inp.Log)
+	if err != nil {
+		return
+	}
 	if err = compositeTraintuple.commitStatusUpdate(db, inp.Key, status); err != nil {
 		return
 	}
@@ -493,8 +536,8 @@ func UpdateCompositeTraintupleChild(db *LedgerDB, parentTraintupleKey string, ch
 
 	// get traintuple new status
 	var newStatus string
-	if traintupleStatus == StatusFailed {
-		newStatus = StatusFailed
+	if stringInSlice(traintupleStatus, []string{StatusFailed, StatusAborted}) {
+		newStatus = traintupleStatus
 	} else if traintupleStatus == StatusDone {
 		ready, _err := childTraintuple.isReady(db, parentTraintupleKey)
 		if _err != nil {
@@ -585,9 +628,19 @@ func (traintuple *CompositeTraintuple) commitStatusUpdate(db *LedgerDB, traintup
 	if err := db.UpdateIndex(indexName, oldAttributes, newAttributes); err != nil {
 		return err
 	}
+	if err := releaseTupleQuota(db, traintuple.Creator, newStatus, traintupleKey); err != nil {
+		return err
+	}
+	if err := releaseTuplePrivacyBudget(db, newStatus, traintupleKey); err != nil {
+		return err
+	}
+	if err := updateSchedulingIndex(db, traintupleKey, oldStatus, newStatus); err != nil {
+		return err
+	}
 	if err := UpdateComputePlanState(db, traintuple.ComputePlanKey, newStatus, traintupleKey, traintuple.Dataset.Worker); err != nil {
 		return err
 	}
+	db.recordTupleStatus(CompositeTraintupleType, newStatus)
 	logger.Infof("compositetraintuple %s status updated: %s (from=%s)", traintupleKey, newStatus, oldStatus)
 	return nil
 }
@@ -20,6 +20,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -135,6 +136,28 @@ func TestTraintupleWithSingleDatasample(t *testing.T) {
 	assert.EqualValues(t, 200, resp.Status, "It should find the traintuple without error ", resp.Message)
 }
 
+func TestTraintupleGeneratedKey(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "algo")
+
+	inpTraintuple := inputTraintuple{}
+	inpTraintuple.createDefault()
+	inpTraintuple.Key = ""
+	resp := mockStub.MockInvokeTxID("generatedKeyTxID", methodAndAssetToByte("createTraintuple", inpTraintuple))
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	out := outputKey{}
+	require.NoError(t, json.Unmarshal(resp.Payload, &out))
+
+	// the key only depends on the transaction, so that all the endorsers generate the same
+	assert.Equal(t, GenerateKey("generatedKeyTxID", "traintuple"), out.Key)
+	key, err := uuid.Parse(out.Key)
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, key.Version())
+	resp = mockStub.MockInvoke([][]byte{[]byte("queryTraintuple"), keyToJSON(out.Key)})
+	assert.EqualValues(t, 200, resp.Status, resp.Message)
+}
+
 func TestTraintupleWithDuplicatedDatasamples(t *testing.T) {
 	scc := new(SubstraChaincode)
 	mockStub := NewMockStubWithRegisterNode("substra", scc)
@@ -0,0 +1,144 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The read/write set limits of a transaction are global settings changed through governance proposals,
+// so that all the endorsers apply the same ones. Unset limits are unlimited.
+const (
+	limitSettingPrefix   = "limit."
+	LimitMaxReads        = "max_reads"
+	LimitMaxWrites       = "max_writes"
+	LimitMaxBytesWritten = "max_bytes_written"
+)
+
+// The write sets counted by countWrite, besides the private data collections
+const (
+	stateWriteSet    = ""
+	metadataWriteSet = "metadata"
+)
+
+// debugTransientKey is the transient data key requesting the transaction stats in the response
+const debugTransientKey = "debug"
+
+// transactionStats counts the accesses to the ledger made during a transaction
+type transactionStats struct {
+	Reads         int `json:"reads"`           // the number of GetState calls
+	Writes        int `json:"writes"`          // the number of keys written by PutState and DelState calls
+	IndexQueries  int `json:"index_queries"`   // the number of composite key iterators
+	IndexKeysRead int `json:"index_keys_read"` // the number of keys returned by the iterators
+	BytesWritten  int `json:"bytes_written"`   // the size of the written keys and of their last value
+}
+
+func (stats transactionStats) String() string {
+	return fmt.Sprintf("reads=%d writes=%d index_queries=%d index_keys_read=%d bytes_written=%d",
+		stats.Reads, stats.Writes, stats.IndexQueries, stats.IndexKeysRead, stats.BytesWritten)
+}
+
+// transactionLimits are the limits of the transaction stats, 0 meaning unlimited
+type transactionLimits map[string]int
+
+// validateLimitSetting checks that the value of a limit setting is a positive integer
+func validateLimitSetting(name string, value string) error {
+	if !strings.HasPrefix(name, limitSettingPrefix) {
+		return nil
+	}
+	limitName := strings.TrimPrefix(name, limitSettingPrefix)
+	if !stringInSlice(limitName, []string{LimitMaxReads, LimitMaxWrites, LimitMaxBytesWritten}) {
+		return errors.BadRequest("unknown limit %s", name)
+	}
+	if limit, err := strconv.Atoi(value); err != nil || limit < 0 {
+		return errors.BadRequest("limit %s must be a positive integer, received: %s", name, value)
+	}
+	return nil
+}
+
+// loadLimits reads the limits of the transaction stats from the governance settings
+func (db *LedgerDB) loadLimits() error {
+	limits := transactionLimits{}
+	for _, name := range []string{LimitMaxReads, LimitMaxWrites, LimitMaxBytesWritten} {
+		value, ok, err := getSetting(db, limitSettingPrefix+name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if limits[name], err = strconv.Atoi(value); err != nil {
+			return errors.Internal("invalid value %s for setting %s", value, limitSettingPrefix+name)
+		}
+	}
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	db.limits = limits
+	return nil
+}
+
+// checkLimit fails when a transaction stat goes beyond its limit
+func (db *LedgerDB) checkLimit(name string, value int) error {
+	if limit := db.limits[name]; limit > 0 && value > limit {
+		return errors.BadRequest("the transaction exceeds the limit of %d for %s (setting %s), try splitting it",
+			limit, name, limitSettingPrefix+name)
+	}
+	return nil
+}
+
+// countRead records a GetState call
+func (db *LedgerDB) countRead() error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	db.stats.Reads++
+	return db.checkLimit(LimitMaxReads, db.stats.Reads)
+}
+
+// countWrite records a PutState, DelState, SetStateValidationParameter or PutPrivateData call.
+// As in the write set of Fabric, a key written several times counts once, with its last value.
+// writeSet distinguishes the keys of the state, of their endorsement policies and of each private collection.
+func (db *LedgerDB) countWrite(writeSet string, key string, value []byte) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	size := len(key) + len(value)
+	previousSize, ok := db.writtenKeys[writeSet+"\x00"+key]
+	if !ok {
+		db.stats.Writes++
+	}
+	db.stats.BytesWritten += size - previousSize
+	db.writtenKeys[writeSet+"\x00"+key] = size
+	if err := db.checkLimit(LimitMaxWrites, db.stats.Writes); err != nil {
+		return err
+	}
+	return db.checkLimit(LimitMaxBytesWritten, db.stats.BytesWritten)
+}
+
+// countIndexQuery records a composite key iterator and the number of keys it returned
+func (db *LedgerDB) countIndexQuery(keysRead int) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	db.stats.IndexQueries++
+	db.stats.IndexKeysRead += keysRead
+}
+
+// Stats returns the accesses to the ledger made so far during the transaction
+func (db *LedgerDB) Stats() transactionStats {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	return db.stats
+}
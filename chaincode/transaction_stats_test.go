@@ -0,0 +1,100 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionStats(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	assert.NoError(t, db.Put("key", "value"))
+	assert.NoError(t, db.CreateIndex("test~key", []string{"test", "key"}))
+	var value string
+	assert.NoError(t, db.Get("key", &value))
+	assert.Error(t, db.Get("missing", &value))
+	keys, err := db.GetIndexKeys("test~key", []string{"test"})
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	// The objects written during the transaction are read from the transaction state
+	assert.Equal(t, transactionStats{
+		Reads:         1,
+		Writes:        2,
+		IndexQueries:  1,
+		IndexKeysRead: 1,
+		BytesWritten:  len("key") + len(`"value"`) + len("\x00test~key\x00test\x00key\x00") + 1,
+	}, db.Stats())
+
+	// A key written again counts once, with its last value
+	assert.NoError(t, db.Put("key", "new value"))
+	assert.Equal(t, 2, db.Stats().Writes)
+	assert.Equal(t, len("key")+len(`"new value"`)+len("\x00test~key\x00test\x00key\x00")+1, db.Stats().BytesWritten)
+}
+
+func TestTransactionLimits(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	initGovernanceWithSettings(t, mockStub, map[string]string{"limit.max_writes": "3"})
+
+	inpDataManager := inputDataManager{}
+	resp := mockStub.MockInvoke(inpDataManager.createDefault())
+	assert.EqualValues(t, 400, resp.Status)
+	assert.Contains(t, resp.Message, "limit.max_writes")
+
+	assert.NoError(t, validateLimitSetting("limit.max_reads", "10"))
+	assert.Error(t, validateLimitSetting("limit.max_reads", "-1"))
+	err := validateLimitSetting("limit.max_gas", "10")
+	assert.Error(t, err)
+	assert.Equal(t, 400, errors.Wrap(err).HTTPStatusCode())
+}
+
+func TestTransactionLimitsReadOnly(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "dataManager")
+	initGovernanceWithSettings(t, mockStub, map[string]string{"limit.max_reads": "1"})
+
+	// The queries are never submitted to the orderer, they are not limited
+	resp := mockStub.MockInvoke([][]byte{[]byte("queryDataManagers")})
+	assert.EqualValues(t, 200, resp.Status, resp.Message)
+}
+
+func TestTransactionStatsDebug(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "dataManager")
+
+	mockStub.Transient = map[string][]byte{debugTransientKey: []byte("true")}
+	resp := mockStub.MockInvoke([][]byte{[]byte("queryDataManagers")})
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	payload := struct {
+		Results []outputDataManager `json:"results"`
+		Debug   transactionStats    `json:"debug"`
+	}{}
+	require.NoError(t, json.Unmarshal(resp.Payload, &payload))
+	assert.Len(t, payload.Results, 1)
+	assert.Equal(t, 1, payload.Debug.IndexQueries)
+	assert.NotZero(t, payload.Debug.Reads)
+}
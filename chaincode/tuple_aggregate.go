@@ -48,6 +48,9 @@ func (tuple *Aggregatetuple) SetFromInput(db *LedgerDB, inp inputAggregatetuple)
 	if !algo.Permissions.CanProcess(algo.Owner, creator) {
 		return errors.Forbidden("not authorized to process algo %s", inp.AlgoKey)
 	}
+	if algo.Deprecated {
+		return errors.BadRequest("algo %s is deprecated, no new tuple can use it", inp.AlgoKey)
+	}
 	tuple.AlgoKey = inp.AlgoKey
 	// Check if worker is a valid node
 	_, err = db.GetNode(inp.Worker)
@@ -55,7 +58,8 @@ func (tuple *Aggregatetuple) SetFromInput(db *LedgerDB, inp inputAggregatetuple)
 		return errors.BadRequest(err, "could not retrieve worker %s", inp.Worker)
 	}
 	tuple.Worker = inp.Worker
-	return nil
+	tuple.Resources, err = getTaskResources(db, tuple.Worker, algo.Resources, inp.Resources)
+	return err
 }
 
 // SetFromParents set the status of the aggregate tuple depending on its "parents",
@@ -171,6 +175,9 @@ func (tuple *Aggregatetuple) Save(db *LedgerDB, aggregatetupleKey string) error
 	if err := db.Add(aggregatetupleKey, tuple); err != nil {
 		return err
 	}
+	if err := setKeyEndorsers(db, aggregatetupleKey, tuple.Worker); err != nil {
+		return err
+	}
 
 	// create composite keys
 	if err := db.CreateIndex("aggregatetuple~algo~key", []string{"aggregatetuple", tuple.AlgoKey, aggregatetupleKey}); err != nil {
@@ -179,6 +186,10 @@ func (tuple *Aggregatetuple) Save(db *LedgerDB, aggregatetupleKey string) error
 	if err := db.CreateIndex("aggregatetuple~worker~status~key", []string{"aggregatetuple", tuple.Worker, tuple.Status, aggregatetupleKey}); err != nil {
 		return err
 	}
+	if err := updateSchedulingIndex(db, aggregatetupleKey, "", tuple.Status); err != nil {
+		return err
+	}
+	db.recordTupleStatus(AggregatetupleType, tuple.Status)
 	for _, inModelKey := range tuple.InModelKeys {
 		if err := db.CreateIndex("tuple~inModel~key", []string{"tuple", inModelKey, aggregatetupleKey}); err != nil {
 			return err
@@ -211,6 +222,9 @@ func createAggregatetuple(db *LedgerDB, args []string) (outputKey, error) {
 	if err != nil {
 		return outputKey{}, err
 	}
+	if inp.Key == "" {
+		inp.Key = GenerateKey(db.cc.GetTxID(), AggregatetupleType.String())
+	}
 
 	key, err := createAggregatetupleInternal(db, inp, true)
 	if err != nil {
@@ -245,6 +259,10 @@ func createAggregatetupleInternal(db *LedgerDB, inp inputAggregatetuple, checkCo
 	if err != nil {
 		return "", err
 	}
+	err = reserveTupleQuota(db, aggregatetuple.Creator, aggregatetuple.Status, aggregatetuple.ComputePlanKey, aggregatetuple.Key)
+	if err != nil {
+		return "", err
+	}
 	err = aggregatetuple.Save(db, aggregatetuple.Key)
 	if err != nil {
 		return "", err
@@ -276,6 +294,9 @@ func logStartAggregate(db *LedgerDB, args []string) (o outputAggregatetuple, err
 	if err = validateTupleOwner(db, aggregatetuple.Worker); err != nil {
 		return
 	}
+	if err = checkWorkerConcurrency(db, aggregatetuple.Worker); err != nil {
+		return
+	}
 	if err = aggregatetuple.commitStatusUpdate(db, inp.Key, status); err != nil {
 		return
 	}
@@ -298,11 +319,13 @@ func logFailAggregate(db *LedgerDB, args []string) (o outputAggregatetuple, err
 		return
 	}
 
-	aggregatetuple.Log += inp.Log
-
 	if err = validateTupleOwner(db, aggregatetuple.Worker); err != nil {
 		return
 	}
+	aggregatetuple.PrivateHash, err = putFailureLog(db, aggregatetuple.Worker, inp.Key, inp.Log)
+	if err != nil {
+		return
+	}
 	if err = aggregatetuple.commitStatusUpdate(db, inp.Key, status); err != nil {
 		return
 	}
@@ -454,8 +477,8 @@ func UpdateAggregatetupleChild(db *LedgerDB, parentAggregatetupleKey string, chi
 
 	// get traintuple new status
 	var newStatus string
-	if aggregatetupleStatus == StatusFailed {
-		newStatus = StatusFailed
+	if stringInSlice(aggregatetupleStatus, []string{StatusFailed, StatusAborted}) {
+		newStatus = aggregatetupleStatus
 	} else if aggregatetupleStatus == StatusDone {
 		ready, _err := childAggregatetuple.isReady(db, parentAggregatetupleKey)
 		if _err != nil {
@@ -527,9 +550,16 @@ func (tuple *Aggregatetuple) commitStatusUpdate(db *LedgerDB, aggregatetupleKey
 	if err := db.UpdateIndex(indexName, oldAttributes, newAttributes); err != nil {
 		return err
 	}
+	if err := releaseTupleQuota(db, tuple.Creator, newStatus, aggregatetupleKey); err != nil {
+		return err
+	}
+	if err := updateSchedulingIndex(db, aggregatetupleKey, oldStatus, newStatus); err != nil {
+		return err
+	}
 	if err := UpdateComputePlanState(db, tuple.ComputePlanKey, newStatus, aggregatetupleKey, tuple.Worker); err != nil {
 		return err
 	}
+	db.recordTupleStatus(AggregatetupleType, newStatus)
 	logger.Infof("aggregatetuple %s status updated: %s (from=%s)", aggregatetupleKey, newStatus, oldStatus)
 	return nil
 }
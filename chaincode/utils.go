@@ -19,6 +19,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"gopkg.in/go-playground/validator.v9"
 
@@ -50,6 +53,16 @@ func typeInSlice(a AssetType, list []AssetType) bool {
 
 // AssetFromJSON unmarshal a stringify json into the passed interface
 func AssetFromJSON(args []string, asset interface{}) error {
+	return assetFromJSONWithValidator(args, asset, newInputValidator())
+}
+
+// structValidator checks the tags of an input struct
+type structValidator interface {
+	Struct(s interface{}) error
+}
+
+// assetFromJSONWithValidator unmarshals the json string of args into asset and checks it with v
+func assetFromJSONWithValidator(args []string, asset interface{}, v structValidator) error {
 	if len(args) != 1 {
 		return errors.BadRequest("arguments should only contains 1 json string, received: %s", args)
 	}
@@ -58,7 +71,6 @@ func AssetFromJSON(args []string, asset interface{}) error {
 	if err != nil {
 		return errors.BadRequest(err, "problem when reading json arg: %s, error is:", arg)
 	}
-	v := validator.New()
 	err = v.Struct(asset)
 	if err != nil {
 		return errors.BadRequest(err, "inputs validation failed: %s, error is:", arg)
@@ -66,6 +78,111 @@ func AssetFromJSON(args []string, asset interface{}) error {
 	return nil
 }
 
+// newInputValidator returns the validator checking the tags of the input structs
+func newInputValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterValidation("semver", isSemver)
+	return v
+}
+
+var semverRegexp = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(?:-([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?(?:\+[0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*)?$`)
+
+// isSemver is a custom validator checking that a field is a semantic version (MAJOR.MINOR.PATCH)
+func isSemver(fl validator.FieldLevel) bool {
+	return semverRegexp.MatchString(fl.Field().String())
+}
+
+// compareSemver compares two semantic versions and returns -1, 0 or 1
+// A pre-release version has a lower precedence than the associated normal version,
+// pre-releases are compared identifier by identifier and build metadata are ignored.
+// It fails if a version is not a semantic version or has a number too large to be compared.
+func compareSemver(a, b string) (int, error) {
+	partsA := semverRegexp.FindStringSubmatch(a)
+	partsB := semverRegexp.FindStringSubmatch(b)
+	if partsA == nil || partsB == nil {
+		return 0, errors.BadRequest("cannot compare versions %s and %s, they must be semantic versions", a, b)
+	}
+	for i := 1; i <= 3; i++ {
+		numA, err := strconv.Atoi(partsA[i])
+		if err != nil {
+			return 0, errors.BadRequest("cannot compare version %s: %s", a, err.Error())
+		}
+		numB, err := strconv.Atoi(partsB[i])
+		if err != nil {
+			return 0, errors.BadRequest("cannot compare version %s: %s", b, err.Error())
+		}
+		if numA != numB {
+			if numA < numB {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+	preA, preB := partsA[4], partsB[4]
+	switch {
+	case preA == preB:
+		return 0, nil
+	case preA == "":
+		return 1, nil
+	case preB == "":
+		return -1, nil
+	default:
+		return comparePrerelease(preA, preB), nil
+	}
+}
+
+// comparePrerelease compares two pre-release versions and returns -1, 0 or 1
+// Numeric identifiers are compared numerically and have a lower precedence than
+// alphanumeric ones, which are compared lexically. A larger set of identifiers has
+// a higher precedence when all the preceding identifiers are equal.
+func comparePrerelease(a, b string) int {
+	idsA, idsB := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(idsA) && i < len(idsB); i++ {
+		if c := comparePrereleaseIdentifier(idsA[i], idsB[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(idsA) < len(idsB):
+		return -1
+	case len(idsA) > len(idsB):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// comparePrereleaseIdentifier compares two pre-release identifiers and returns -1, 0 or 1
+func comparePrereleaseIdentifier(a, b string) int {
+	numericA, numericB := isNumericIdentifier(a), isNumericIdentifier(b)
+	switch {
+	case numericA && !numericB:
+		return -1
+	case !numericA && numericB:
+		return 1
+	case numericA && numericB:
+		// numbers are compared by length first so that they cannot overflow
+		a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+		if len(a) != len(b) {
+			if len(a) < len(b) {
+				return -1
+			}
+			return 1
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+// isNumericIdentifier returns whether a pre-release identifier only contains digits
+func isNumericIdentifier(id string) bool {
+	for _, c := range id {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return id != ""
+}
+
 // GetTxCreator returns the transaction creator
 func GetTxCreator(stub shim.ChaincodeStubInterface) (string, error) {
 	creator, err := stub.GetCreator()
@@ -108,6 +225,10 @@ func (assetType AssetType) String() string {
 		return "testtuple"
 	case ComputePlanType:
 		return "compute_plan"
+	case ProposalType:
+		return "proposal"
+	case ComputePlanTemplateType:
+		return "compute_plan_template"
 	default:
 		return fmt.Sprintf("(unknown asset type: %d)", assetType)
 	}
@@ -133,6 +254,16 @@ func GetNewUUID() (string, error) {
 	return a.String(), nil
 }
 
+// keyNamespace is the UUID namespace of the keys generated by the chaincode
+var keyNamespace = uuid.MustParse("5f9b6a52-8c1e-4b0e-9f3d-2a7c1d4e8b60")
+
+// GenerateKey derives the key of an asset created during a transaction from the transaction ID
+// and the name of the asset in this transaction. It is a UUIDv5, so that all the endorsers generate
+// the same key for the same transaction.
+func GenerateKey(txID string, name string) string {
+	return uuid.NewSHA1(keyNamespace, []byte(txID+"/"+name)).String()
+}
+
 func initMapOutput(m map[string]string) map[string]string {
 	if m == nil {
 		return map[string]string{}